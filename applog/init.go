@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/secrets"
 )
 
 // Init logging entry point
@@ -30,14 +32,24 @@ type MultiLineFormatter struct {
 	log.TextFormatter
 }
 
+// Format masks the secret values in the message and the data of the entry
 func (f *MultiLineFormatter) Format(entry *log.Entry) ([]byte, error) {
 	multiline, ok := entry.Data["multiline"]
 	if ok {
 		delete(entry.Data, "multiline")
 	}
+
+	entry.Message = secrets.MaskString(entry.Message)
+	for key, val := range entry.Data {
+		if err, isErr := val.(error); isErr {
+			val = err.Error()
+		}
+		entry.Data[key] = secrets.MaskValue(val)
+	}
+
 	res, err := f.TextFormatter.Format(entry)
 	if multiline, ok := multiline.(string); ok && multiline != "" {
-		res = append(res, []byte(multiline)...)
+		res = append(res, []byte(secrets.MaskString(multiline))...)
 	}
 	return res, err
 }
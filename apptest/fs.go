@@ -130,6 +130,10 @@ func (fmm *FsManagerMock) WriteFile(name, contents string, mode os.FileMode) err
 	return nil
 }
 
+func (fmm *FsManagerMock) WriteFileAtomic(name, contents string, mode os.FileMode) error {
+	return nil
+}
+
 func (fmm *FsManagerMock) ReadFile(filePath string) (content string, err error) {
 	return "", nil
 }
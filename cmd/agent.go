@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/script"
+	"github.com/spf13/cobra"
+)
+
+var (
+	AgentInterval = 30 * time.Minute
+	AgentSplay    = time.Duration(0)
+)
+
+func init() {
+	agentCmd.Flags().DurationVar(&AgentInterval, "interval", AgentInterval, "Interval between the starts of the runs")
+	agentCmd.Flags().DurationVar(&AgentSplay, "splay", AgentSplay, "Maximum random delay of every run")
+	rootCmd.AddCommand(agentCmd)
+}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent [script to apply]",
+	Short: "Applies a script periodically until the process is stopped",
+	Long: `Applies a script periodically until the process is stopped, the script can be a local file
+or a http, https or ftp URL. Every run is delayed by a random duration up to the --splay, takes the run lock
+of the state directory and is saved to the history. A run is skipped if another process holds the run lock
+after waiting --wait-lock.
+The script is read again before every run, so a changed script is applied by the next run.
+The status of the last run is written to agent-status.json in the state directory.
+SIGINT or SIGTERM stops the agent after cancelling the running script.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signalContext()
+		defer stop()
+
+		opts := runOptions(nil)
+		opts.StateDir = StateDir
+		if !NoLock {
+			opts.LockDir = StateDir
+		}
+
+		agent := &script.Agent{
+			Script:   args[0],
+			Options:  opts,
+			Interval: AgentInterval,
+			Splay:    AgentSplay,
+		}
+
+		return agent.Run(ctx)
+	},
+	SilenceErrors: true,
+}
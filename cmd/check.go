@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/realvnc-labs/tacoscript/script"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [script to check]",
+	Short: "Reports the drift of the host from a script without changing anything",
+	Long: `Runs all tasks of the script in dry run mode and prints the tasks which would change the host with their changes
+together with the failed tasks. The exit code is 0 if the host is in the desired state, 2 if a drift was detected
+and 1 on errors. The onlyif, unless and creates conditions of the tasks are still evaluated, the commands
+of cmd.run and cmd.script tasks are not run and they are reported as a drift unless a condition skips them.
+Check runs are not saved to the history.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signalContext()
+		defer stop()
+
+		opts := runOptions(nil)
+		opts.DryRun = true
+
+		result, err := script.ExecuteScript(ctx, args[0], opts)
+		failure := &script.FailureError{}
+		if err != nil && !errors.As(err, &failure) {
+			return err
+		}
+
+		if err = script.WriteResult(os.Stdout, result.Drift()); err != nil {
+			return err
+		}
+
+		return detailedExitCode(result)
+	},
+	SilenceErrors: true,
+}
@@ -1,25 +1,133 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
 
+	tacoio "github.com/realvnc-labs/tacoscript/io"
 	"github.com/realvnc-labs/tacoscript/script"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var DetailedExitCode = false
+
 func init() {
+	// taco <script> runs the script like taco exec, so the flag is local to both commands
+	for _, cmd := range []*cobra.Command{rootCmd, exeCmd} {
+		cmd.Flags().BoolVar(
+			&DetailedExitCode,
+			"detailed-exitcode",
+			false,
+			"Exit with 0 if no changes were made, 2 if changes were applied and 1 on failures",
+		)
+	}
 	rootCmd.AddCommand(exeCmd)
 }
 
 var exeCmd = &cobra.Command{
 	Use:   "exec [script to run]",
 	Short: "Executes a script provided in argument, you can also run taco {{PATH_TO_SCRIPT}}",
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Executes a script provided in argument, the script can be a local file, a http, https or ftp URL
+or '-' to read the script from the standard input. Remote scripts require the --sha256 checksum
+or a signature verified by --verify-key or --trusted-keys-dir. The run takes the exclusive run lock
+of the state directory, it fails if another run holds the lock unless --wait-lock is given.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logrus.Debugf("will execute script %s (abort-on-error=%v)", args[0], AbortOnError)
 
-		return script.RunScript(args[0], AbortOnError, os.Stdout)
+		stream, err := tacoio.NewLineStream(StreamFormat, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signalContext()
+		defer stop()
+
+		opts := runOptions(stream)
+		if !NoHistory {
+			opts.StateDir = StateDir
+		}
+		if !NoLock {
+			opts.LockDir = StateDir
+		}
+
+		if !DetailedExitCode {
+			return script.RunScript(ctx, args[0], opts, os.Stdout)
+		}
+
+		result, err := script.ExecuteScript(ctx, args[0], opts)
+		failure := &script.FailureError{}
+		if err != nil && !errors.As(err, &failure) {
+			return err
+		}
+
+		if err = script.WriteResult(os.Stdout, result); err != nil {
+			return err
+		}
+
+		return detailedExitCode(result)
 	},
 	SilenceErrors: true,
 }
+
+// runOptions gives the run options of the command line flags
+func runOptions(stream tacoio.LineStream) script.RunOptions {
+	return script.RunOptions{
+		AbortOnError:     AbortOnError,
+		Stream:           script.StreamOptions{Stream: stream, All: Stream},
+		Become:           Become,
+		BecomeMethod:     BecomeMethod,
+		PluginPath:       PluginPath,
+		SHA256:           SHA256,
+		VerifyKeys:       VerifyKeys,
+		TrustedKeysDir:   TrustedKeysDir,
+		SignaturePath:    SignaturePath,
+		SecretEnvPattern: SecretEnvPattern,
+		SecretsFile:      SecretsFile,
+		SecretsKeyFile:   ExecSecretsKey,
+		HistoryKeep:      HistoryKeep,
+		LockWait:         WaitLock,
+	}
+}
+
+// detailedExitCode gives the failure of the result, or the exit code 2 if any task changed the host
+// or would change it in a dry run
+func detailedExitCode(result script.Result) error {
+	if err := result.Err(); err != nil {
+		return err
+	}
+
+	if result.Summary.Changes > 0 {
+		return &exitCodeError{code: exitCodeChanges}
+	}
+
+	return nil
+}
+
+// signalContext gives a context which is cancelled on SIGINT or SIGTERM, so the running command is killed
+// and the results of the finished tasks are still printed, a second signal terminates the process immediately
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logrus.Warnf("received signal %s, cancelling the script execution", sig)
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/script"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+func init() {
+	historyCmd.AddCommand(historyListCmd, historyShowCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Shows the runs saved in the history of the state directory",
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the runs in the history, the latest run comes first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		records, err := state.NewHistory(StateDir, HistoryKeep).List()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RUN ID\tSTARTED\tDURATION\tSTATUS\tSCRIPT")
+		for _, record := range records {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%s\t%s\t%s\n",
+				record.ID,
+				record.Started.Local().Format(time.RFC3339),
+				record.Duration().Round(time.Millisecond),
+				record.Status,
+				record.Script,
+			)
+		}
+
+		return w.Flush()
+	},
+	SilenceErrors: true,
+}
+
+// historyRecord is the YAML representation of a history record
+type historyRecord struct {
+	ID             string         `yaml:"ID"`
+	Script         string         `yaml:"Script"`
+	ScriptSHA256   string         `yaml:"ScriptSHA256,omitempty"`
+	Started        string         `yaml:"Started"`
+	Finished       string         `yaml:"Finished"`
+	Duration       time.Duration  `yaml:"Duration"`
+	Status         string         `yaml:"Status"`
+	Error          string         `yaml:"Error,omitempty"`
+	Result         *script.Result `yaml:"Result,omitempty"`
+	RenderedScript string         `yaml:"RenderedScript,omitempty"`
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show [run id]",
+	Short: "Shows the result and the rendered script of a run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		record, err := state.NewHistory(StateDir, HistoryKeep).Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		out := historyRecord{
+			ID:             record.ID,
+			Script:         record.Script,
+			ScriptSHA256:   record.ScriptSHA256,
+			Started:        record.Started.Local().Format(time.RFC3339Nano),
+			Finished:       record.Finished.Local().Format(time.RFC3339Nano),
+			Duration:       record.Duration(),
+			Status:         record.Status,
+			Error:          record.Error,
+			RenderedScript: record.RenderedScript,
+		}
+
+		if len(record.Result) > 0 {
+			out.Result = &script.Result{}
+			if err = json.Unmarshal(record.Result, out.Result); err != nil {
+				return fmt.Errorf("invalid result of the run '%s': %w", record.ID, err)
+			}
+		}
+
+		y, err := yaml.Marshal(out)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(y))
+
+		return nil
+	},
+	SilenceErrors: true,
+}
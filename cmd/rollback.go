@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var RollbackForce = false
+
+func init() {
+	rollbackCmd.Flags().BoolVar(&RollbackForce, "force", false, "Restore the files even if they were modified since the run")
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+// rollbackResult is the YAML output of the rollback command
+type rollbackResult struct {
+	RunID      string                 `yaml:"RunID"`
+	RolledBack []state.RolledBackFile `yaml:"RolledBack"`
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [run id]",
+	Short: "Restores the files modified by a run from the history",
+	Long: `Restores the files modified by the file.managed, file.replace and realvnc_server.config_update tasks
+of a run from the history with their content, mode and ownership before the run. Files created by the run
+are removed. The rollback is refused if any of the files was modified since the run, unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		history := state.NewHistory(StateDir, HistoryKeep)
+		if _, err := history.Get(args[0]); err != nil {
+			return err
+		}
+
+		journal, err := state.LoadJournal(history.Journal(args[0]).Dir)
+		if err != nil {
+			return err
+		}
+
+		rolledBack, rollbackErr := journal.Rollback(RollbackForce)
+		if len(rolledBack) > 0 {
+			y, err := yaml.Marshal(rollbackResult{RunID: args[0], RolledBack: rolledBack})
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(y))
+		}
+
+		return rollbackErr
+	},
+	SilenceErrors: true,
+}
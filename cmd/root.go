@@ -1,18 +1,41 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/realvnc-labs/tacoscript/applog"
+	"github.com/realvnc-labs/tacoscript/exec"
+	tacoio "github.com/realvnc-labs/tacoscript/io"
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/state"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 )
 
 var (
-	Verbose      = false
-	AbortOnError = false
+	Verbose          = false
+	AbortOnError     = false
+	Stream           = false
+	StreamFormat     = tacoio.StreamFormatText
+	Become           = false
+	BecomeMethod     = exec.BecomeMethodSudo
+	PluginPath       = ""
+	SHA256           = ""
+	VerifyKeys       []string
+	TrustedKeysDir   = ""
+	SignaturePath    = ""
+	SecretEnvPattern = ""
+	SecretsFile      = ""
+	ExecSecretsKey   = ""
+	StateDir         = state.DefaultDir()
+	HistoryKeep      = state.DefaultHistoryKeep
+	NoHistory        = false
+	WaitLock         = time.Duration(0)
+	NoLock           = false
 
 	rootCmd = &cobra.Command{
 		Use:           "taco",
@@ -29,6 +52,84 @@ func init() {
 	cobra.OnInitialize(initLog)
 	rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVarP(&AbortOnError, "abort-on-error", "a", false, "Abort on error")
+	rootCmd.PersistentFlags().BoolVar(&Stream, "stream", false, "Stream the output of all commands to stderr while they are running")
+	rootCmd.PersistentFlags().StringVar(
+		&StreamFormat,
+		"stream-format",
+		tacoio.StreamFormatText,
+		"Format of the streamed output lines, 'text' or 'json'",
+	)
+	rootCmd.PersistentFlags().BoolVar(&Become, "become", false, "Run all commands with escalated privileges")
+	rootCmd.PersistentFlags().StringVar(
+		&BecomeMethod,
+		"become-method",
+		exec.BecomeMethodSudo,
+		"Privilege escalation method for --become and the become task option, 'sudo', 'su' or 'doas'",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&PluginPath,
+		"plugin-path",
+		"",
+		"Directories which are searched for taco-plugin-<task type> executables, defaults to TACO_PLUGIN_PATH or PATH",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&SHA256,
+		"sha256",
+		"",
+		"SHA256 checksum of the script, required for scripts downloaded from http, https or ftp URLs without --verify-key",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&VerifyKeys,
+		"verify-key",
+		nil,
+		"Public key file, the script and its local sources are only run with a valid signature of the key or a trusted key",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&TrustedKeysDir,
+		"trusted-keys-dir",
+		"",
+		"Directory of trusted *.pub public keys, enables the signature verification like --verify-key",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&SignaturePath,
+		"signature",
+		"",
+		"Detached signature of the script, defaults to <script>.sig",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&SecretEnvPattern,
+		"secret-env-pattern",
+		secrets.DefaultEnvNamePattern,
+		"Regular expression of the env variable names which values are masked in all output",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&SecretsFile,
+		"secrets-file",
+		"",
+		"Encrypted secrets file, the secrets are available in the script templates as {{ secrets.name }}",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&StateDir,
+		"state-dir",
+		StateDir,
+		fmt.Sprintf("Directory of the run history, defaults to %s if it's set", state.DirEnv),
+	)
+	rootCmd.PersistentFlags().IntVar(&HistoryKeep, "history-keep", HistoryKeep, "Number of runs kept in the history, 0 keeps all")
+	rootCmd.PersistentFlags().BoolVar(&NoHistory, "no-history", false, "Don't save the run to the history")
+	rootCmd.PersistentFlags().DurationVar(
+		&WaitLock,
+		"wait-lock",
+		0,
+		"How long to wait for the run lock held by another process, e.g. 5m, fails immediately by default",
+	)
+	rootCmd.PersistentFlags().BoolVar(&NoLock, "no-lock", false, "Don't take the run lock of the state directory")
+	rootCmd.PersistentFlags().StringVar(
+		&ExecSecretsKey,
+		"secrets-key-file",
+		"",
+		fmt.Sprintf("Key file of the secrets file, defaults to %s, %s is used without a key file",
+			secrets.KeyFileEnv, secrets.PassphraseEnv),
+	)
 }
 
 func initLog() {
@@ -39,11 +140,28 @@ type errorResult struct {
 	Error string `yaml:"Error"`
 }
 
+// exitCodeChanges is the exit code of the --detailed-exitcode flag and the check command if tasks changed the host
+const exitCodeChanges = 2
+
+// exitCodeError exits the process with the code without printing an error
+type exitCodeError struct {
+	code int
+}
+
+func (e *exitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
 func Execute() error {
 	if err := rootCmd.Execute(); err != nil {
+		exitErr := &exitCodeError{}
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
+
 		logrus.Debugf("Execute failed: %v", err)
 
-		y, _ := yaml.Marshal(errorResult{Error: err.Error()})
+		y, _ := yaml.Marshal(errorResult{Error: secrets.MaskString(err.Error())})
 		fmt.Println(string(y))
 
 		os.Exit(1)
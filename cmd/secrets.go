@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	SecretsKeyFile = ""
+	SecretsOutput  = ""
+)
+
+func init() {
+	secretsCmd.PersistentFlags().StringVar(
+		&SecretsKeyFile,
+		"key-file",
+		"",
+		fmt.Sprintf("Key file created by 'taco secrets keygen', defaults to %s, %s is used without a key file",
+			secrets.KeyFileEnv, secrets.PassphraseEnv),
+	)
+	secretsEncryptCmd.Flags().StringVarP(&SecretsOutput, "output", "o", "", "Encrypted file, defaults to <file>.enc")
+	secretsDecryptCmd.Flags().StringVarP(&SecretsOutput, "output", "o", "", "Decrypted file, defaults to stdout")
+
+	secretsCmd.AddCommand(secretsKeygenCmd, secretsEncryptCmd, secretsDecryptCmd, secretsEditCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manages encrypted secrets files, which are used by 'taco exec --secrets-file'",
+}
+
+var secretsKeygenCmd = &cobra.Command{
+	Use:   "keygen [key file]",
+	Short: "Generates a random key file for the encryption of secrets files",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return secrets.GenerateKeyFile(args[0])
+	},
+	SilenceErrors: true,
+}
+
+var secretsEncryptCmd = &cobra.Command{
+	Use:   "encrypt [plain YAML file]",
+	Short: "Encrypts a YAML file of secret names and values",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plaintext, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		if _, err = secrets.ParseValues(plaintext); err != nil {
+			return err
+		}
+
+		encrypted, err := secrets.Encrypt(plaintext, secrets.KeySourceFromEnv(SecretsKeyFile))
+		if err != nil {
+			return err
+		}
+
+		output := SecretsOutput
+		if output == "" {
+			output = args[0] + ".enc"
+		}
+
+		return utils.WriteFileAtomic(output, encrypted, 0600)
+	},
+	SilenceErrors: true,
+}
+
+var secretsDecryptCmd = &cobra.Command{
+	Use:   "decrypt [encrypted file]",
+	Short: "Decrypts a secrets file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plaintext, err := decryptSecretsFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		if SecretsOutput == "" {
+			_, err = os.Stdout.Write(plaintext)
+			return err
+		}
+
+		return utils.WriteFileAtomic(SecretsOutput, plaintext, 0600)
+	},
+	SilenceErrors: true,
+}
+
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit [encrypted file]",
+	Short: "Decrypts a secrets file into a temporary file, opens it in $VISUAL or $EDITOR and encrypts the changes",
+	Long: `Decrypts a secrets file into a temporary file, opens it in $VISUAL or $EDITOR and encrypts the changes,
+a missing secrets file is created.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plaintext := []byte{}
+		if _, err := os.Stat(args[0]); err == nil {
+			plaintext, err = decryptSecretsFile(args[0])
+			if err != nil {
+				return err
+			}
+		}
+
+		edited, err := editInTempFile(plaintext)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(edited, plaintext) {
+			fmt.Println("no changes")
+			return nil
+		}
+
+		if _, err = secrets.ParseValues(edited); err != nil {
+			return err
+		}
+
+		encrypted, err := secrets.Encrypt(edited, secrets.KeySourceFromEnv(SecretsKeyFile))
+		if err != nil {
+			return err
+		}
+
+		return utils.WriteFileAtomic(args[0], encrypted, 0600)
+	},
+	SilenceErrors: true,
+}
+
+func decryptSecretsFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.Decrypt(data, secrets.KeySourceFromEnv(SecretsKeyFile))
+}
+
+// editInTempFile lets the user edit the data in a private temp directory which is removed afterwards
+func editInTempFile(data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "taco-secrets-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "secrets.yaml")
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	editorCmd := exec.Command(editor, path) //nolint:gosec // the editor is chosen by the user
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err = editorCmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor '%s' failed: %w", editor, err)
+	}
+
+	return os.ReadFile(path)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/realvnc-labs/tacoscript/signing"
+	"github.com/spf13/cobra"
+)
+
+var SignKey = ""
+
+func init() {
+	signCmd.Flags().StringVar(&SignKey, "key", "", "Private key file created by 'taco sign keygen'")
+	signCmd.AddCommand(signKeygenCmd)
+	rootCmd.AddCommand(signCmd)
+}
+
+var signCmd = &cobra.Command{
+	Use:   "sign [files to sign]",
+	Short: "Creates detached ed25519 signatures of scripts and source files",
+	Long: `Creates a detached signature <file>.sig for each file with the private key given by --key,
+the signatures are verified by 'taco exec --verify-key'.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if SignKey == "" {
+			return fmt.Errorf("the private key must be given by --key")
+		}
+
+		key, err := signing.ReadPrivateKey(SignKey)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range args {
+			signaturePath, err := signing.SignFile(key, path)
+			if err != nil {
+				return err
+			}
+			fmt.Println(signaturePath)
+		}
+
+		return nil
+	},
+	SilenceErrors: true,
+}
+
+var signKeygenCmd = &cobra.Command{
+	Use:   "keygen [key name]",
+	Short: "Generates an ed25519 key pair, the keys are written to <key name>.key and <key name>.pub",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		privateKeyPath, publicKeyPath, err := signing.GenerateKeyPair(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("private key: %s\npublic key: %s\n", privateKeyPath, publicKeyPath)
+
+		return nil
+	},
+	SilenceErrors: true,
+}
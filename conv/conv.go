@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -30,6 +31,7 @@ var (
 	ErrNotANumber           = errors.New("value is not a number")
 	ErrFileSizeInvalidUnits = errors.New("file size has invalid units")
 	ErrBadBool              = errors.New("failed to parse bool value")
+	ErrInvalidDuration      = errors.New("invalid duration")
 )
 
 func (kv KeyValue) ToEqualSignString() string {
@@ -63,6 +65,28 @@ func ConvertToKeyValues(val interface{}, path string) (KeyValues, error) {
 	return res, nil
 }
 
+// ConvertMapToKeyValues accepts both a yaml map and a list of single key maps and keeps the order of the keys
+func ConvertMapToKeyValues(val interface{}, path string) (KeyValues, error) {
+	rawMap, ok := val.(yaml.MapSlice)
+	if !ok {
+		return ConvertToKeyValues(val, path)
+	}
+
+	res := make([]KeyValue, 0, len(rawMap))
+	for _, item := range rawMap {
+		value := ""
+		if item.Value != nil {
+			value = fmt.Sprint(item.Value)
+		}
+		res = append(res, KeyValue{
+			Key:   fmt.Sprint(item.Key),
+			Value: value,
+		})
+	}
+
+	return res, nil
+}
+
 func ConvertToValues(val interface{}) ([]string, error) {
 	rawValues, ok := val.([]interface{})
 	if !ok {
@@ -183,6 +207,28 @@ func getMultiplierFromUnits(units string) (multiplier int, err error) {
 	return multiplier, nil
 }
 
+// ConvertToDuration accepts a number of seconds or a duration string like "1m30s"
+func ConvertToDuration(val interface{}) (time.Duration, error) {
+	switch typedVal := val.(type) {
+	case int:
+		return time.Duration(typedVal) * time.Second, nil
+	case float64:
+		return time.Duration(typedVal * float64(time.Second)), nil
+	}
+
+	valStr := strings.TrimSpace(fmt.Sprint(val))
+	if seconds, err := strconv.ParseFloat(valStr, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	duration, err := time.ParseDuration(valStr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: '%s'", ErrInvalidDuration, valStr)
+	}
+
+	return duration, nil
+}
+
 func isNumber(str string) bool {
 	_, err := strconv.Atoi(str)
 	return err == nil
@@ -2,6 +2,7 @@ package conv_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/realvnc-labs/tacoscript/conv"
 	"github.com/stretchr/testify/assert"
@@ -96,3 +97,49 @@ func TestShouldConvertFileSizeToUInt64(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldConvertToDuration(t *testing.T) {
+	cases := []struct {
+		name          string
+		input         interface{}
+		expectedValue time.Duration
+		expectedErr   error
+	}{
+		{
+			name:          "int seconds",
+			input:         30,
+			expectedValue: 30 * time.Second,
+		},
+		{
+			name:          "float seconds",
+			input:         0.5,
+			expectedValue: 500 * time.Millisecond,
+		},
+		{
+			name:          "string seconds",
+			input:         "10",
+			expectedValue: 10 * time.Second,
+		},
+		{
+			name:          "duration string",
+			input:         "1m30s",
+			expectedValue: 90 * time.Second,
+		},
+		{
+			name:        "invalid duration",
+			input:       "soon",
+			expectedErr: conv.ErrInvalidDuration,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			val, err := conv.ConvertToDuration(tc.input)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedValue, val)
+			}
+		})
+	}
+}
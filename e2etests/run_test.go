@@ -2,6 +2,7 @@ package e2etests
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -132,7 +133,7 @@ func TestTacoScript(t *testing.T) {
 			// Run the tacoscript and capture the output
 			t.Logf("Running tacoscript %s", inFile)
 			var output bytes.Buffer
-			err = script.RunScript(tacoTempFile, false, &output)
+			err = script.RunScript(context.Background(), tacoTempFile, script.RunOptions{}, &output)
 			require.NoError(t, err)
 
 			// Execute a command after running the tacoscript
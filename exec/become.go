@@ -0,0 +1,281 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	BecomeMethodSudo = "sudo"
+	BecomeMethodSu   = "su"
+	BecomeMethodDoas = "doas"
+
+	defaultBecomeUser = "root"
+)
+
+// ValidateBecomeMethod checks if the privilege escalation method is supported
+func ValidateBecomeMethod(method string) error {
+	switch method {
+	case "", BecomeMethodSudo, BecomeMethodSu, BecomeMethodDoas:
+		return nil
+	default:
+		return fmt.Errorf(
+			"unknown become method '%s', supported methods are '%s', '%s' and '%s'",
+			method,
+			BecomeMethodSudo,
+			BecomeMethodSu,
+			BecomeMethodDoas,
+		)
+	}
+}
+
+// BecomeError is returned when the privileges cannot be escalated before running a command
+type BecomeError struct {
+	Method string
+	User   string
+	Reason string
+}
+
+func (be BecomeError) Error() string {
+	return fmt.Sprintf("cannot become user '%s' with %s: %s", be.User, be.Method, be.Reason)
+}
+
+// BecomeChecks caches the results of the escalation checks, so the escalation to a user with a method is checked
+// once instead of before every command, condition and retry
+type BecomeChecks struct {
+	mu      sync.Mutex
+	results map[becomeSpec]error
+}
+
+// check gives the cached result of the escalation check or runs the check, a check which was interrupted
+// by the context is not cached
+func (bc *BecomeChecks) check(ctx context.Context, become *becomeSpec, checkFn func() error) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	key := becomeSpec{method: become.method, user: become.user}
+	if err, ok := bc.results[key]; ok {
+		return err
+	}
+
+	err := checkFn()
+	if ctx.Err() != nil {
+		return err
+	}
+
+	if bc.results == nil {
+		bc.results = map[becomeSpec]error{}
+	}
+	bc.results[key] = err
+
+	return err
+}
+
+// becomeSpec defines how the privileges of the commands are escalated
+type becomeSpec struct {
+	method string
+	user   string
+	// the file with the env variables of the command, see writeEnvFile
+	envFile string
+}
+
+// becomeSettings gives the escalation settings if the commands of the context should run with escalated privileges,
+// the task settings take precedence over the settings of the runner
+func (sr SystemRunner) becomeSettings(execContext *Context) *becomeSpec {
+	if !execContext.Become && !sr.Become {
+		return nil
+	}
+
+	method := execContext.BecomeMethod
+	if method == "" {
+		method = sr.BecomeMethod
+	}
+	if method == "" {
+		method = BecomeMethodSudo
+	}
+
+	becomeUser := execContext.User
+	if becomeUser == "" {
+		becomeUser = defaultBecomeUser
+	}
+
+	// nothing to escalate if we already are the target user
+	if currentUser, err := user.Current(); err == nil && currentUser.Username == becomeUser {
+		return nil
+	}
+
+	return &becomeSpec{method: method, user: becomeUser}
+}
+
+// checkBecome finds out before running a command if the privileges can be escalated without a password prompt,
+// the result is cached in the BecomeChecks of the runner
+func (sr SystemRunner) checkBecome(ctx context.Context, become *becomeSpec) error {
+	if sr.BecomeChecks == nil {
+		return sr.runBecomeCheck(ctx, become)
+	}
+
+	return sr.BecomeChecks.check(ctx, become, func() error {
+		return sr.runBecomeCheck(ctx, become)
+	})
+}
+
+// runBecomeCheck runs the escalation tool in the non-interactive mode
+func (sr SystemRunner) runBecomeCheck(ctx context.Context, become *becomeSpec) error {
+	method, becomeUser := become.method, become.user
+
+	if runtime.GOOS == "windows" {
+		return BecomeError{Method: method, User: becomeUser, Reason: "privilege escalation is not supported on Windows"}
+	}
+
+	if method == BecomeMethodSu {
+		if os.Geteuid() != 0 {
+			return BecomeError{
+				Method: method,
+				User:   becomeUser,
+				Reason: "su asks for a password when tacoscript doesn't run as root, use sudo or doas instead",
+			}
+		}
+		return nil
+	}
+
+	// the non-interactive mode fails instead of waiting for a password
+	name, args := wrapWithBecome(method, becomeUser, "true", nil)
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := sr.SystemAPI.Run(ctx, cmd)
+	if errors.Is(err, exec.ErrNotFound) {
+		return BecomeError{Method: method, User: becomeUser, Reason: method + " is not installed"}
+	}
+	if err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return BecomeError{
+			Method: method,
+			User:   becomeUser,
+			Reason: fmt.Sprintf("%s, make sure %s is allowed without a password for the current user", reason, method),
+		}
+	}
+
+	return nil
+}
+
+// writeScriptAsTargetUser lets the target user write the script contents from the standard input to a new temp file,
+// so the file is only readable by the target user. The file is removed by the target user as well.
+func (sr SystemRunner) writeScriptAsTargetUser(
+	ctx context.Context,
+	contents string,
+	become *becomeSpec,
+) (path string, remove func(), err error) {
+	randomName := make([]byte, 8)
+	if _, err = rand.Read(randomName); err != nil {
+		return "", nil, err
+	}
+	path = filepath.Join(os.TempDir(), fmt.Sprintf("taco-%x", randomName))
+
+	// the noclobber option fails if the file exists, e.g. if another user has created it in the shared temp dir
+	name, args := become.wrap("sh", []string{"-c", `umask 077 && set -C && cat > "$1"`, "sh", path})
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(contents)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err = sr.SystemAPI.Run(ctx, cmd); err != nil {
+		reason := strings.TrimSpace(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return "", nil, BecomeError{
+			Method: become.method,
+			User:   become.user,
+			Reason: fmt.Sprintf("cannot write the script file '%s': %s", path, reason),
+		}
+	}
+
+	logrus.Debugf("wrote the script file '%s' as user '%s'", path, become.user)
+
+	remove = func() {
+		name, args := become.wrap("rm", []string{"-f", path})
+		if err := sr.SystemAPI.Run(context.Background(), exec.Command(name, args...)); err != nil {
+			logrus.Warnf("cannot remove the script file '%s' as user '%s': %v", path, become.user, err)
+		}
+	}
+
+	return path, remove, nil
+}
+
+// writeEnvFile writes the env variables of the context as export lines to a file which is only readable by the target
+// user, as sudo and doas reset the environment and the values shouldn't be visible in the process list.
+// The returned spec runs the commands with the variables of the file.
+func (sr SystemRunner) writeEnvFile(
+	ctx context.Context,
+	execContext *Context,
+	become *becomeSpec,
+) (envBecome *becomeSpec, remove func(), err error) {
+	if become == nil || len(execContext.Envs) == 0 {
+		return become, func() {}, nil
+	}
+
+	lines := make([]string, 0, len(execContext.Envs))
+	for _, env := range execContext.Envs {
+		lines = append(lines, fmt.Sprintf("export %s=%s", env.Key, quoteForShell(env.Value)))
+	}
+
+	path, remove, err := sr.writeScript(ctx, strings.Join(lines, "\n")+"\n", "taco-*", become)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	envBecome = &becomeSpec{method: become.method, user: become.user, envFile: path}
+
+	return envBecome, remove, nil
+}
+
+// wrap prefixes the command with the escalation tool, if the spec has an env file, the target command is started
+// by sh after it has loaded the env variables from the file
+func (b *becomeSpec) wrap(name string, args []string) (wrappedName string, wrappedArgs []string) {
+	if b == nil {
+		return name, args
+	}
+
+	if b.envFile != "" {
+		loaderArgs := make([]string, 0, len(args)+5)
+		loaderArgs = append(loaderArgs, "-c", `. "$1" && shift && exec "$@"`, "sh", b.envFile, name)
+		name, args = "sh", append(loaderArgs, args...)
+	}
+
+	return wrapWithBecome(b.method, b.user, name, args)
+}
+
+func wrapWithBecome(method, becomeUser, name string, args []string) (wrappedName string, wrappedArgs []string) {
+	switch method {
+	case BecomeMethodSu:
+		quoted := make([]string, 0, len(args)+1)
+		for _, arg := range append([]string{name}, args...) {
+			quoted = append(quoted, quoteForShell(arg))
+		}
+		return "su", []string{becomeUser, "-c", strings.Join(quoted, " ")}
+	default:
+		wrappedArgs = []string{"-n", "-u", becomeUser, "--", name}
+		return method, append(wrappedArgs, args...)
+	}
+}
+
+func quoteForShell(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
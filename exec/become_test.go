@@ -0,0 +1,229 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerWithBecome(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("privilege escalation is not supported on Windows")
+	}
+
+	testCases := []struct {
+		name          string
+		runner        SystemRunner
+		execContext   *Context
+		expectedCmds  []string
+		checkErr      string
+		expectedError string
+	}{
+		{
+			name:   "sudo as a different user",
+			runner: SystemRunner{Become: true},
+			execContext: &Context{
+				Cmds: []string{"whoami"},
+				User: "nobody",
+			},
+			expectedCmds: []string{
+				"sudo -n -u nobody -- true",
+				`sudo -n -u nobody -- sh -c umask 077 && set -C && cat > "$1" sh `,
+				"sudo -n -u nobody -- sh ",
+				"sudo -n -u nobody -- rm -f ",
+			},
+		},
+		{
+			name:   "task level doas without a shell",
+			runner: SystemRunner{BecomeMethod: BecomeMethodSudo},
+			execContext: &Context{
+				Cmds:         []string{"echo 'a b' | wc -c"},
+				User:         "nobody",
+				Shell:        NoShell,
+				Become:       true,
+				BecomeMethod: BecomeMethodDoas,
+			},
+			expectedCmds: []string{
+				"doas -n -u nobody -- true",
+				"doas -n -u nobody -- echo a b",
+				"doas -n -u nobody -- wc -c",
+			},
+		},
+		{
+			name:   "no escalation",
+			runner: SystemRunner{},
+			execContext: &Context{
+				Cmds:  []string{"echo 123"},
+				Shell: NoShell,
+			},
+			expectedCmds: []string{"echo 123"},
+		},
+		{
+			name:   "escalation is not possible",
+			runner: SystemRunner{Become: true},
+			execContext: &Context{
+				Cmds: []string{"whoami"},
+				User: "nobody",
+			},
+			checkErr:     "sudo: a password is required",
+			expectedCmds: []string{"sudo -n -u nobody -- true"},
+			expectedError: "cannot become user 'nobody' with sudo: sudo: a password is required, " +
+				"make sure sudo is allowed without a password for the current user",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			systemAPI := &SystemAPIMock{}
+			if tc.checkErr != "" {
+				systemAPI.Callback = func(cmd *exec.Cmd) error {
+					_, err := cmd.Stderr.Write([]byte(tc.checkErr + "\n"))
+					require.NoError(t, err)
+					return errors.New("exit status 1")
+				}
+			}
+
+			systemRunner := tc.runner
+			systemRunner.SystemAPI = systemAPI
+
+			execContext := tc.execContext
+			execContext.StdoutWriter = &bytes.Buffer{}
+			execContext.StderrWriter = &bytes.Buffer{}
+
+			err := systemRunner.Run(execContext)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				assert.ErrorAs(t, err, &BecomeError{})
+			}
+
+			actualCmds := make([]string, 0, len(systemAPI.Cmds))
+			for _, cmd := range systemAPI.Cmds {
+				actualCmds = append(actualCmds, strings.Join(cmd.Args, " "))
+			}
+
+			require.Len(t, actualCmds, len(tc.expectedCmds))
+			for i, expectedCmd := range tc.expectedCmds {
+				assert.Truef(t, strings.HasPrefix(actualCmds[i], expectedCmd), "expected '%s' to start with '%s'", actualCmds[i], expectedCmd)
+			}
+		})
+	}
+}
+
+func TestRunnerWithBecomePassesEnvs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("privilege escalation is not supported on Windows")
+	}
+
+	envs := conv.KeyValues{{Key: "APP_ENV", Value: "prod"}, {Key: "GREETING", Value: "hello world"}}
+
+	systemAPI := &SystemAPIMock{}
+	systemRunner := SystemRunner{SystemAPI: systemAPI, Become: true, BecomeMethod: BecomeMethodDoas}
+	err := systemRunner.Run(&Context{
+		Cmds:         []string{"printenv GREETING"},
+		User:         "nobody",
+		Envs:         envs,
+		StdoutWriter: &bytes.Buffer{},
+		StderrWriter: &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+
+	// the target user writes the env file and the script file from the standard input
+	require.Len(t, systemAPI.Cmds, 6)
+	envContents, err := io.ReadAll(systemAPI.Cmds[1].Stdin)
+	require.NoError(t, err)
+	assert.Equal(t, "export APP_ENV='prod'\nexport GREETING='hello world'\n", string(envContents))
+	envPath := systemAPI.Cmds[1].Args[len(systemAPI.Cmds[1].Args)-1]
+
+	scriptContents, err := io.ReadAll(systemAPI.Cmds[2].Stdin)
+	require.NoError(t, err)
+	assert.Equal(t, "printenv GREETING", string(scriptContents))
+	scriptPath := systemAPI.Cmds[2].Args[len(systemAPI.Cmds[2].Args)-1]
+
+	assert.Equal(
+		t,
+		[]string{"doas", "-n", "-u", "nobody", "--", "sh", "-c", `. "$1" && shift && exec "$@"`, "sh", envPath, "sh", scriptPath},
+		systemAPI.Cmds[3].Args,
+	)
+	assert.Equal(t, []string{"doas", "-n", "-u", "nobody", "--", "rm", "-f", scriptPath}, systemAPI.Cmds[4].Args)
+	assert.Equal(t, []string{"doas", "-n", "-u", "nobody", "--", "rm", "-f", envPath}, systemAPI.Cmds[5].Args)
+
+	// the values are never passed on the command line
+	for _, cmd := range systemAPI.Cmds {
+		for _, arg := range cmd.Args {
+			assert.NotContains(t, arg, "prod")
+			assert.NotContains(t, arg, "hello world")
+		}
+	}
+
+	name, args := (&becomeSpec{method: BecomeMethodSu, user: "deploy", envFile: "/tmp/taco-env"}).wrap("sh", []string{"script"})
+	assert.Equal(t, "su", name)
+	assert.Equal(t, []string{"deploy", "-c", `'sh' '-c' '. "$1" && shift && exec "$@"' 'sh' '/tmp/taco-env' 'sh' 'script'`}, args)
+}
+
+func TestRunnerCachesBecomeChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("privilege escalation is not supported on Windows")
+	}
+
+	systemAPI := &SystemAPIMock{}
+	systemRunner := SystemRunner{SystemAPI: systemAPI, Become: true, BecomeChecks: &BecomeChecks{}}
+
+	countChecks := func(method, becomeUser string) int {
+		checks := 0
+		for _, cmd := range systemAPI.Cmds {
+			if strings.Join(cmd.Args, " ") == method+" -n -u "+becomeUser+" -- true" {
+				checks++
+			}
+		}
+		return checks
+	}
+
+	for i := 0; i < 3; i++ {
+		err := systemRunner.Run(&Context{
+			Cmds:         []string{"whoami"},
+			User:         "nobody",
+			StdoutWriter: &bytes.Buffer{},
+			StderrWriter: &bytes.Buffer{},
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, countChecks(BecomeMethodSudo, "nobody"))
+
+	err := systemRunner.Run(&Context{
+		Cmds:         []string{"whoami"},
+		User:         "nobody",
+		BecomeMethod: BecomeMethodDoas,
+		StdoutWriter: &bytes.Buffer{},
+		StderrWriter: &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, countChecks(BecomeMethodDoas, "nobody"))
+
+	// a failed check is cached as well
+	systemAPI.ErrToGive = errors.New("exit status 1")
+	for i := 0; i < 2; i++ {
+		err = systemRunner.Run(&Context{
+			Cmds:         []string{"whoami"},
+			User:         "daemon",
+			StdoutWriter: &bytes.Buffer{},
+			StderrWriter: &bytes.Buffer{},
+		})
+		assert.ErrorAs(t, err, &BecomeError{})
+	}
+	assert.Equal(t, 1, countChecks(BecomeMethodSudo, "daemon"))
+}
+
+func TestWrapWithSu(t *testing.T) {
+	name, args := wrapWithBecome(BecomeMethodSu, "deploy", "echo", []string{"it's", "a b"})
+	assert.Equal(t, "su", name)
+	assert.Equal(t, []string{"deploy", "-c", `'echo' 'it'\''s' 'a b'`}, args)
+}
@@ -0,0 +1,227 @@
+package exec
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	opPipe         = "|"
+	opAnd          = "&&"
+	opOr           = "||"
+	opSeq          = ";"
+	opBackground   = "&"
+	opStdin        = "<"
+	opStdout       = ">"
+	opStdoutAppend = ">>"
+	opStderr       = "2>"
+	opStderrAppend = "2>>"
+	opStderrToOut  = "2>&1"
+)
+
+// the characters which can be escaped by a backslash outside of quotes, other backslashes are kept as is,
+// so Windows paths can be written without escaping
+const escapableChars = " \t\\'\"|&<>;"
+
+// Redirect redirects the standard input or output of a command from or to a file
+type Redirect struct {
+	Op     string
+	Target string
+}
+
+// NativeCommand is a single program call of a command line
+type NativeCommand struct {
+	Args      []string
+	Redirects []Redirect
+}
+
+// Pipeline is a list of commands connected by pipes
+type Pipeline []NativeCommand
+
+// CommandLine is a list of pipelines chained by the &&, || and ; operators,
+// Operators[i] is placed between Pipelines[i] and Pipelines[i+1]
+type CommandLine struct {
+	Pipelines []Pipeline
+	Operators []string
+}
+
+type cmdToken struct {
+	value string
+	isOp  bool
+}
+
+// ParseCommandLine splits the command line to the program calls which can be executed without a shell,
+// single and double quotes, pipes, redirections and the &&, || and ; operators are supported,
+// variables, globs and subshells are not expanded
+func ParseCommandLine(rawCmd string) (CommandLine, error) {
+	tokens, err := tokenizeCommandLine(rawCmd)
+	if err != nil {
+		return CommandLine{}, err
+	}
+
+	cmdLine := CommandLine{}
+	pipeline := Pipeline{}
+	cmd := NativeCommand{}
+
+	finishCommand := func(op string) error {
+		if len(cmd.Args) == 0 {
+			return fmt.Errorf("missing command before '%s' in '%s'", op, rawCmd)
+		}
+		pipeline = append(pipeline, cmd)
+		cmd = NativeCommand{}
+		return nil
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if !token.isOp {
+			cmd.Args = append(cmd.Args, token.value)
+			continue
+		}
+
+		switch token.value {
+		case opPipe:
+			err = finishCommand(token.value)
+			if err != nil {
+				return CommandLine{}, err
+			}
+		case opAnd, opOr, opSeq:
+			err = finishCommand(token.value)
+			if err != nil {
+				return CommandLine{}, err
+			}
+			cmdLine.Pipelines = append(cmdLine.Pipelines, pipeline)
+			cmdLine.Operators = append(cmdLine.Operators, token.value)
+			pipeline = Pipeline{}
+		case opStderrToOut:
+			cmd.Redirects = append(cmd.Redirects, Redirect{Op: token.value})
+		case opStdin, opStdout, opStdoutAppend, opStderr, opStderrAppend:
+			if i+1 >= len(tokens) || tokens[i+1].isOp {
+				return CommandLine{}, fmt.Errorf("missing file name after '%s' in '%s'", token.value, rawCmd)
+			}
+			i++
+			cmd.Redirects = append(cmd.Redirects, Redirect{Op: token.value, Target: tokens[i].value})
+		case opBackground:
+			return CommandLine{}, fmt.Errorf("background execution with '&' is not supported without a shell in '%s'", rawCmd)
+		}
+	}
+
+	if len(cmd.Args) > 0 || len(cmd.Redirects) > 0 {
+		err = finishCommand("end of line")
+		if err != nil {
+			return CommandLine{}, err
+		}
+	}
+
+	if len(pipeline) == 0 {
+		// a trailing ; is allowed like in a shell, other operators need a following command
+		if len(cmdLine.Operators) == 0 {
+			return CommandLine{}, fmt.Errorf("empty command")
+		}
+		lastOp := cmdLine.Operators[len(cmdLine.Operators)-1]
+		if lastOp != opSeq {
+			return CommandLine{}, fmt.Errorf("missing command after '%s' in '%s'", lastOp, rawCmd)
+		}
+		cmdLine.Operators = cmdLine.Operators[:len(cmdLine.Operators)-1]
+		return cmdLine, nil
+	}
+
+	cmdLine.Pipelines = append(cmdLine.Pipelines, pipeline)
+
+	return cmdLine, nil
+}
+
+func tokenizeCommandLine(rawCmd string) ([]cmdToken, error) {
+	tokens := []cmdToken{}
+	word := strings.Builder{}
+	inWord := false
+
+	finishWord := func() {
+		if inWord {
+			tokens = append(tokens, cmdToken{value: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	chars := []rune(rawCmd)
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		next := rune(0)
+		if i+1 < len(chars) {
+			next = chars[i+1]
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			finishWord()
+		case c == '\\':
+			inWord = true
+			if next != 0 && strings.ContainsRune(escapableChars, next) {
+				word.WriteRune(next)
+				i++
+				continue
+			}
+			word.WriteRune(c)
+		case c == '\'':
+			inWord = true
+			end := indexRune(chars, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in '%s'", rawCmd)
+			}
+			word.WriteString(string(chars[i+1 : end]))
+			i = end
+		case c == '"':
+			inWord = true
+			i++
+			for ; i < len(chars) && chars[i] != '"'; i++ {
+				if chars[i] == '\\' && i+1 < len(chars) && strings.ContainsRune(`"\$`+"`", chars[i+1]) {
+					i++
+				}
+				word.WriteRune(chars[i])
+			}
+			if i >= len(chars) {
+				return nil, fmt.Errorf("unterminated double quote in '%s'", rawCmd)
+			}
+		case c == '2' && !inWord && next == '>':
+			rest := string(chars[i:])
+			op := opStderr
+			switch {
+			case strings.HasPrefix(rest, opStderrToOut):
+				op = opStderrToOut
+			case strings.HasPrefix(rest, opStderrAppend):
+				op = opStderrAppend
+			}
+			tokens = append(tokens, cmdToken{value: op, isOp: true})
+			i += len(op) - 1
+		case c == '|' || c == '&' || c == '>':
+			finishWord()
+			op := string(c)
+			if next == c {
+				op += string(next)
+				i++
+			}
+			tokens = append(tokens, cmdToken{value: op, isOp: true})
+		case c == '<' || c == ';':
+			finishWord()
+			tokens = append(tokens, cmdToken{value: string(c), isOp: true})
+		default:
+			inWord = true
+			word.WriteRune(c)
+		}
+	}
+
+	finishWord()
+
+	return tokens, nil
+}
+
+func indexRune(chars []rune, start int, r rune) int {
+	for i := start; i < len(chars); i++ {
+		if chars[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
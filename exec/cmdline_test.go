@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	testCases := []struct {
+		name            string
+		rawCmd          string
+		expectedCmdLine CommandLine
+		expectedErr     string
+	}{
+		{
+			name:   "simple command",
+			rawCmd: "echo  hello   world",
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{{{Args: []string{"echo", "hello", "world"}}}},
+			},
+		},
+		{
+			name:   "quoting",
+			rawCmd: `printf '%s|%s' "a \"b\" \\c" 'd'"e"f\ g "" x\y`,
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{{{Args: []string{"printf", "%s|%s", `a "b" \c`, "def g", "", `x\y`}}}},
+			},
+		},
+		{
+			name:   "windows path",
+			rawCmd: `C:\Tools\app.exe -f C:\data\in.txt`,
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{{{Args: []string{`C:\Tools\app.exe`, "-f", `C:\data\in.txt`}}}},
+			},
+		},
+		{
+			name:   "pipeline with redirects",
+			rawCmd: "sort < in.txt | uniq -c 2>/dev/null | head -n 3 >> out.txt 2>&1",
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{{
+					{Args: []string{"sort"}, Redirects: []Redirect{{Op: "<", Target: "in.txt"}}},
+					{Args: []string{"uniq", "-c"}, Redirects: []Redirect{{Op: "2>", Target: "/dev/null"}}},
+					{Args: []string{"head", "-n", "3"}, Redirects: []Redirect{{Op: ">>", Target: "out.txt"}, {Op: "2>&1"}}},
+				}},
+			},
+		},
+		{
+			name:   "chains",
+			rawCmd: "test -d /tmp && echo yes || echo no; echo done;",
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{
+					{{Args: []string{"test", "-d", "/tmp"}}},
+					{{Args: []string{"echo", "yes"}}},
+					{{Args: []string{"echo", "no"}}},
+					{{Args: []string{"echo", "done"}}},
+				},
+				Operators: []string{"&&", "||", ";"},
+			},
+		},
+		{
+			name:   "operators in words",
+			rawCmd: "echo a2>f 'x|y'",
+			expectedCmdLine: CommandLine{
+				Pipelines: []Pipeline{{{Args: []string{"echo", "a2", "x|y"}, Redirects: []Redirect{{Op: ">", Target: "f"}}}}},
+			},
+		},
+		{
+			name:        "unterminated quote",
+			rawCmd:      `echo "abc`,
+			expectedErr: `unterminated double quote in 'echo "abc'`,
+		},
+		{
+			name:        "missing command",
+			rawCmd:      "| grep a",
+			expectedErr: "missing command before '|' in '| grep a'",
+		},
+		{
+			name:        "missing command after operator",
+			rawCmd:      "echo a &&",
+			expectedErr: "missing command after '&&' in 'echo a &&'",
+		},
+		{
+			name:        "missing redirect target",
+			rawCmd:      "echo a >",
+			expectedErr: "missing file name after '>' in 'echo a >'",
+		},
+		{
+			name:        "background",
+			rawCmd:      "sleep 1 &",
+			expectedErr: "background execution with '&' is not supported without a shell in 'sleep 1 &'",
+		},
+		{
+			name:        "empty",
+			rawCmd:      "  ",
+			expectedErr: "empty command",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			cmdLine, err := ParseCommandLine(tc.rawCmd)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCmdLine, cmdLine)
+		})
+	}
+}
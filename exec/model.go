@@ -2,7 +2,10 @@ package exec
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/realvnc-labs/tacoscript/conv"
 )
@@ -18,19 +21,31 @@ type Context struct {
 	Cmds         []string
 	Pid          int
 	Shell        string
+	// the arguments of the script file which is created from the commands
+	Args []string
+	// log level of the captured command output, "quiet" disables logging of the output
+	OutputLogLevel string
+	// runs the commands with escalated privileges as User or root if User is empty
+	Become bool
+	// sudo, su or doas, sudo is used if it's empty
+	BecomeMethod string
 }
 
 func (c *Context) Copy() Context {
 	return Context{
-		Ctx:          c.Ctx,
-		StdoutWriter: c.StdoutWriter,
-		StderrWriter: c.StderrWriter,
-		WorkingDir:   c.WorkingDir,
-		User:         c.User,
-		Path:         c.Path,
-		Envs:         c.Envs,
-		Cmds:         c.Cmds,
-		Shell:        c.Shell,
+		Ctx:            c.Ctx,
+		StdoutWriter:   c.StdoutWriter,
+		StderrWriter:   c.StderrWriter,
+		WorkingDir:     c.WorkingDir,
+		User:           c.User,
+		Path:           c.Path,
+		Envs:           c.Envs,
+		Cmds:           c.Cmds,
+		Args:           c.Args,
+		Shell:          c.Shell,
+		OutputLogLevel: c.OutputLogLevel,
+		Become:         c.Become,
+		BecomeMethod:   c.BecomeMethod,
 	}
 }
 
@@ -49,3 +64,26 @@ type RunError struct {
 func (re RunError) Error() string {
 	return re.Err.Error()
 }
+
+func (re RunError) Unwrap() error {
+	return re.Err
+}
+
+// ExitCodeOf gives the exit code of a failed command run, zero is returned if the error is not a RunError
+func ExitCodeOf(err error) int {
+	runErr := RunError{}
+	if errors.As(err, &runErr) {
+		return runErr.ExitCode
+	}
+
+	return 0
+}
+
+// TimeoutError is returned when a command was killed because it exceeded its timeout
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (te TimeoutError) Error() string {
+	return fmt.Sprintf("command timed out after %s", te.Timeout)
+}
@@ -0,0 +1,265 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the exit code of a command which cannot be started, the same as in the POSIX shells
+const cmdNotFoundExitCode = 127
+
+const redirectFileMode = 0644
+
+// lockedWriter serializes the writes of the concurrently running commands of a pipeline
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (lw lockedWriter) Write(p []byte) (n int, err error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(p)
+}
+
+func parseCommandLines(rawCmds []string) ([]CommandLine, error) {
+	cmdLines := make([]CommandLine, 0, len(rawCmds))
+	for _, rawCmd := range rawCmds {
+		cmdLine, err := ParseCommandLine(rawCmd)
+		if err != nil {
+			return nil, err
+		}
+		cmdLines = append(cmdLines, cmdLine)
+	}
+
+	return cmdLines, nil
+}
+
+// runNative executes the parsed commands without a shell,
+// like in a shell script all commands are executed and the exit code of the last one is returned
+func (sr SystemRunner) runNative(
+	ctx context.Context,
+	execContext *Context,
+	cmdLines []CommandLine,
+	become *becomeSpec,
+) (pid, exitCode int, err error) {
+	loggedStdout, loggedStderr := sr.loggedWriters(execContext.StdoutWriter, execContext.StderrWriter, execContext.OutputLogLevel)
+	mu := &sync.Mutex{}
+	stdout := lockedWriter{mu: mu, w: loggedStdout}
+	stderr := lockedWriter{mu: mu, w: loggedStderr}
+
+	for _, cmdLine := range cmdLines {
+		pid, exitCode, err = sr.runCommandLine(ctx, execContext, cmdLine, become, stdout, stderr)
+		if ctx.Err() != nil {
+			return pid, exitCode, ctx.Err()
+		}
+	}
+
+	return pid, exitCode, err
+}
+
+func (sr SystemRunner) runCommandLine(
+	ctx context.Context,
+	execContext *Context,
+	cmdLine CommandLine,
+	become *becomeSpec,
+	stdout, stderr io.Writer,
+) (pid, exitCode int, err error) {
+	pid, exitCode, err = sr.runPipeline(ctx, execContext, cmdLine.Pipelines[0], become, stdout, stderr)
+
+	for i, op := range cmdLine.Operators {
+		if ctx.Err() != nil {
+			return pid, exitCode, ctx.Err()
+		}
+
+		if (op == opAnd && exitCode != 0) || (op == opOr && exitCode == 0) {
+			continue
+		}
+
+		pid, exitCode, err = sr.runPipeline(ctx, execContext, cmdLine.Pipelines[i+1], become, stdout, stderr)
+	}
+
+	return pid, exitCode, err
+}
+
+// runPipeline starts all commands of the pipeline connected by pipes and waits for them,
+// the exit code of the pipeline is the exit code of its last command
+func (sr SystemRunner) runPipeline(
+	ctx context.Context,
+	execContext *Context,
+	pipeline Pipeline,
+	become *becomeSpec,
+	stdout, stderr io.Writer,
+) (pid, exitCode int, err error) {
+	cmds := make([]*exec.Cmd, len(pipeline))
+	startErrs := make([]error, len(pipeline))
+	// the files which are inherited by the child processes and should be closed in this process after the start
+	var childFiles []io.Closer
+
+	defer func() {
+		for _, f := range childFiles {
+			_ = f.Close()
+		}
+	}()
+
+	// all commands and pipes are prepared before the first command is started, so nothing has to be cleaned up
+	// in case of an error
+	for i, nativeCmd := range pipeline {
+		cmdName, cmdArgs := become.wrap(nativeCmd.Args[0], nativeCmd.Args[1:])
+		cmd := exec.Command(cmdName, cmdArgs...) //nolint:gosec // running commands is intended
+		cmds[i] = cmd
+
+		sr.setWorkingDir(cmd, execContext)
+		sr.setEnvs(cmd, execContext)
+		if become == nil {
+			if err = sr.setUser(cmd, execContext); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		if i > 0 {
+			pipeReader, pipeWriter, pipeErr := os.Pipe()
+			if pipeErr != nil {
+				return 0, 0, pipeErr
+			}
+			childFiles = append(childFiles, pipeReader, pipeWriter)
+			cmds[i-1].Stdout = pipeWriter
+			cmd.Stdin = pipeReader
+		}
+	}
+
+	for i, cmd := range cmds {
+		redirectFiles, redirectErr := applyRedirects(cmd, pipeline[i].Redirects, execContext.WorkingDir)
+		childFiles = append(childFiles, redirectFiles...)
+		if redirectErr != nil {
+			startErrs[i] = redirectErr
+			continue
+		}
+
+		logrus.Debugf("will run cmd '%s'", cmd.String())
+		startErrs[i] = sr.SystemAPI.Start(cmd)
+	}
+
+	// the pipe ends must be closed here, otherwise the readers wouldn't get EOF when the writing commands exit
+	for _, f := range childFiles {
+		_ = f.Close()
+	}
+	childFiles = nil
+
+	for i, startErr := range startErrs {
+		if startErr != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", pipeline[i].Args[0], startErr)
+		}
+	}
+
+	waitErrs := sr.waitPipeline(ctx, cmds, startErrs)
+
+	lastIndex := len(cmds) - 1
+	if cmds[lastIndex].Process != nil {
+		pid = cmds[lastIndex].Process.Pid
+	}
+
+	if startErrs[lastIndex] != nil {
+		return pid, cmdNotFoundExitCode, startErrs[lastIndex]
+	}
+
+	err = waitErrs[lastIndex]
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return pid, exitCode, err
+}
+
+// waitPipeline waits for all started commands, if the context is done before, the commands are killed
+func (sr SystemRunner) waitPipeline(ctx context.Context, cmds []*exec.Cmd, startErrs []error) []error {
+	waitErrs := make([]error, len(cmds))
+	wg := sync.WaitGroup{}
+	for i, cmd := range cmds {
+		if startErrs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, cmd *exec.Cmd) {
+			defer wg.Done()
+			waitErrs[i] = cmd.Wait()
+		}(i, cmd)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for i, cmd := range cmds {
+			if startErrs[i] != nil {
+				continue
+			}
+			logrus.Debugf("will kill cmd '%s': %v", cmd, ctx.Err())
+			killErr := sr.SystemAPI.Kill(cmd)
+			if killErr != nil && !errors.Is(killErr, os.ErrProcessDone) {
+				logrus.Warnf("failed to kill process %d: %v", cmd.Process.Pid, killErr)
+			}
+		}
+		<-done
+	}
+
+	return waitErrs
+}
+
+// applyRedirects opens the redirect targets in the order of their appearance, so "> file 2>&1" redirects both
+// outputs to the file, the opened files are returned to be closed after the command start
+func applyRedirects(cmd *exec.Cmd, redirects []Redirect, workingDir string) (files []io.Closer, err error) {
+	for _, redirect := range redirects {
+		if redirect.Op == opStderrToOut {
+			cmd.Stderr = cmd.Stdout
+			continue
+		}
+
+		target := redirect.Target
+		if !filepath.IsAbs(target) && workingDir != "" {
+			target = filepath.Join(workingDir, target)
+		}
+
+		var f *os.File
+		switch redirect.Op {
+		case opStdin:
+			f, err = os.Open(target)
+		case opStdout, opStderr:
+			f, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, redirectFileMode)
+		case opStdoutAppend, opStderrAppend:
+			f, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, redirectFileMode)
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, f)
+
+		switch redirect.Op {
+		case opStdin:
+			cmd.Stdin = f
+		case opStdout, opStdoutAppend:
+			cmd.Stdout = f
+		case opStderr, opStderrAppend:
+			cmd.Stderr = f
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,135 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerWithoutShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses unix programs")
+	}
+
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "in.txt"), []byte("b\na\nb\n"), 0600))
+
+	testCases := []struct {
+		name             string
+		cmds             []string
+		expectedStdout   string
+		expectedStderr   string
+		expectedExitCode int
+		expectedFiles    map[string]string
+	}{
+		{
+			name:           "quoted arguments",
+			cmds:           []string{`printf '%s|%s\n' "a b" c`},
+			expectedStdout: "a b|c\n",
+		},
+		{
+			name:           "pipeline",
+			cmds:           []string{"sort < in.txt | uniq | tr a-z A-Z"},
+			expectedStdout: "A\nB\n",
+		},
+		{
+			name:          "redirects",
+			cmds:          []string{"echo one > out.txt", "echo two >> out.txt", "ls missing-file 2> err.txt"},
+			expectedFiles: map[string]string{"out.txt": "one\ntwo\n"},
+			// the exit code of the last command is the result
+			expectedExitCode: 2,
+		},
+		{
+			name:           "stderr to stdout",
+			cmds:           []string{"ls missing-file 2>&1 | wc -l"},
+			expectedStdout: "1\n",
+		},
+		{
+			name:           "and or chain",
+			cmds:           []string{"false && echo skipped || echo fallback; echo done"},
+			expectedStdout: "fallback\ndone\n",
+		},
+		{
+			name:             "unknown program",
+			cmds:             []string{"taco-unknown-program || echo fallback", "taco-unknown-program"},
+			expectedStdout:   "fallback\n",
+			expectedExitCode: 127,
+		},
+		{
+			name:           "early exiting reader",
+			cmds:           []string{"yes | head -n 2"},
+			expectedStdout: "y\ny\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			systemRunner := SystemRunner{
+				SystemAPI: OSApi{},
+			}
+
+			stdout := &bytes.Buffer{}
+			execContext := &Context{
+				Ctx:          context.Background(),
+				StdoutWriter: stdout,
+				StderrWriter: &bytes.Buffer{},
+				WorkingDir:   workDir,
+				Cmds:         tc.cmds,
+				Shell:        NoShell,
+			}
+
+			err := systemRunner.Run(execContext)
+			if tc.expectedExitCode == 0 {
+				assert.NoError(t, err)
+			} else {
+				runErr := RunError{}
+				assert.True(t, errors.As(err, &runErr))
+				assert.Equal(t, tc.expectedExitCode, runErr.ExitCode)
+			}
+
+			assert.Equal(t, tc.expectedStdout, stdout.String())
+
+			for filename, expectedContents := range tc.expectedFiles {
+				actualContents, err := os.ReadFile(filepath.Join(workDir, filename))
+				assert.NoError(t, err)
+				assert.Equal(t, expectedContents, string(actualContents))
+			}
+		})
+	}
+}
+
+func TestRunnerWithoutShellTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses unix programs")
+	}
+
+	systemRunner := SystemRunner{
+		SystemAPI: OSApi{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	execContext := &Context{
+		Ctx:          ctx,
+		StdoutWriter: &bytes.Buffer{},
+		StderrWriter: &bytes.Buffer{},
+		Cmds:         []string{"sleep 10 | cat", "echo never"},
+		Shell:        NoShell,
+	}
+
+	start := time.Now()
+	err := systemRunner.Run(execContext)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, execContext.StdoutWriter.(*bytes.Buffer).String())
+}
@@ -4,7 +4,9 @@
 package exec
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"os/user"
 	"strconv"
@@ -16,8 +18,28 @@ import (
 type OSApi struct {
 }
 
-func (oe OSApi) Run(cmd *exec.Cmd) error {
-	return cmd.Run()
+func (oe OSApi) Run(ctx context.Context, cmd *exec.Cmd) error {
+	return runWithContext(ctx, cmd, oe.Start, oe.Kill)
+}
+
+func (oe OSApi) Start(cmd *exec.Cmd) error {
+	// the command gets its own process group, so it can be killed together with all its child processes
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+
+	return cmd.Start()
+}
+
+// Kill kills the process group of the started command
+func (oe OSApi) Kill(cmd *exec.Cmd) error {
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
 }
 
 func (oe OSApi) SetUser(userName, path string, cmd *exec.Cmd) error {
@@ -31,6 +53,15 @@ func (oe OSApi) SetUser(userName, path string, cmd *exec.Cmd) error {
 		return err
 	}
 
+	// the kernel would reject the credential switch with an opaque EPERM
+	if os.Geteuid() != 0 && int(uid) != os.Geteuid() {
+		return fmt.Errorf(
+			"switching to user '%s' requires root privileges, run tacoscript as root or use the become option, check path '%s'",
+			userName,
+			path+".user",
+		)
+	}
+
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid:    true,
 		Credential: &syscall.Credential{Uid: uid, Gid: gid},
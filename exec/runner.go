@@ -1,6 +1,8 @@
 package exec
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
 	"os/exec"
@@ -11,11 +13,17 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// QuietOutputLogLevel disables the logging of the command output
+const QuietOutputLogLevel = "quiet"
+
 // the default windows shell must be cmd.exe for compatibility with older Windows versions
 const defaultWindowsShell = "cmd.exe"
 
 const defaultUnixShell = "sh"
 
+// NoShell runs the commands directly without a shell, see ParseCommandLine for the supported syntax
+const NoShell = "none"
+
 var powershellShells = []string{"powershell", "powershell.exe", "pwsh", "pwsh.exe"}
 
 func IsPowerShell(shell string) bool {
@@ -28,7 +36,12 @@ func IsPowerShell(shell string) bool {
 }
 
 type SystemAPI interface {
-	Run(cmd *exec.Cmd) error
+	// Run runs the command and kills it with all its child processes when the context is done
+	Run(ctx context.Context, cmd *exec.Cmd) error
+	// Start starts the command without waiting for it
+	Start(cmd *exec.Cmd) error
+	// Kill kills the started command with all its child processes
+	Kill(cmd *exec.Cmd) error
 	SetUser(userName, path string, cmd *exec.Cmd) error
 }
 
@@ -38,6 +51,13 @@ type Runner interface {
 
 type SystemRunner struct {
 	SystemAPI SystemAPI
+
+	// runs all commands with escalated privileges, see Context.Become
+	Become bool
+	// the default escalation method, sudo is used if it's empty
+	BecomeMethod string
+	// caches the escalation checks, the escalation is checked before every command if it's nil
+	BecomeChecks *BecomeChecks
 }
 
 func (sr SystemRunner) Run(execContext *Context) error {
@@ -60,30 +80,68 @@ func (sr SystemRunner) Run(execContext *Context) error {
 			tmpPattern = "taco-*.ps1"
 		}
 	}
-	tmpFile, err := os.CreateTemp(os.TempDir(), tmpPattern)
-	if err != nil {
-		return err
+	ctx := execContext.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	become := sr.becomeSettings(execContext)
+	if become != nil {
+		err := sr.checkBecome(ctx, become)
+		if err != nil {
+			return err
+		}
 	}
-	defer os.Remove(tmpFile.Name())
 
-	cmd, err := sr.createCmd(execContext, tmpFile)
+	cmdBecome, removeEnvFile, err := sr.writeEnvFile(ctx, execContext, become)
 	if err != nil {
 		return err
 	}
+	defer removeEnvFile()
 
 	var exitCode int
-	execContext.Pid, exitCode, err = sr.runCmd(cmd)
+	if execContext.Shell == NoShell {
+		cmdLines, err := parseCommandLines(execContext.Cmds)
+		if err != nil {
+			return err
+		}
+		execContext.Pid, exitCode, err = sr.runNative(ctx, execContext, cmdLines, cmdBecome)
+		return sr.toRunError(execContext, err, exitCode)
+	}
 
+	scriptPath, removeScript, err := sr.writeScript(ctx, sr.scriptContents(execContext), tmpPattern, become)
 	if err != nil {
-		return RunError{Err: err, ExitCode: exitCode}
+		return err
 	}
+	defer removeScript()
 
-	return nil
+	cmd, err := sr.createCmd(execContext, scriptPath, cmdBecome)
+	if err != nil {
+		return err
+	}
+
+	execContext.Pid, exitCode, err = sr.runCmd(ctx, cmd)
+
+	return sr.toRunError(execContext, err, exitCode)
 }
 
-func (sr SystemRunner) runCmd(cmd *exec.Cmd) (pid, exitCode int, err error) {
+// toRunError converts the error of a started command to a RunError with the exit code
+func (sr SystemRunner) toRunError(execContext *Context, err error, exitCode int) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// the killed process has no meaningful exit code
+		exitCode = -1
+	}
+
+	return RunError{Err: err, ExitCode: exitCode}
+}
+
+func (sr SystemRunner) runCmd(ctx context.Context, cmd *exec.Cmd) (pid, exitCode int, err error) {
 	logrus.Debugf("will run cmd '%s'", cmd.String())
-	err = sr.SystemAPI.Run(cmd)
+	err = sr.SystemAPI.Run(ctx, cmd)
 	if cmd.Process != nil {
 		pid = cmd.Process.Pid
 	}
@@ -103,7 +161,8 @@ func (sr SystemRunner) setWorkingDir(cmd *exec.Cmd, execContext *Context) {
 	}
 }
 
-func (sr SystemRunner) createCmd(execContext *Context, tmpFile *os.File) (cmd *exec.Cmd, err error) {
+// scriptContents gives the contents of the script file with the commands of the context
+func (sr SystemRunner) scriptContents(execContext *Context) string {
 	prelude := ""
 	newLine := "\n"
 
@@ -114,34 +173,65 @@ func (sr SystemRunner) createCmd(execContext *Context, tmpFile *os.File) (cmd *e
 		}
 	}
 
-	rawCmds := prelude + strings.Join(execContext.Cmds, newLine)
+	return prelude + strings.Join(execContext.Cmds, newLine)
+}
 
-	if _, err = tmpFile.WriteString(rawCmds); err != nil {
-		return
+// writeScript writes the commands to a temp file which is only readable by its owner, if the commands run as another
+// unprivileged user the file is written by the target user, as it cannot read the files of the current user
+func (sr SystemRunner) writeScript(
+	ctx context.Context,
+	contents, tmpPattern string,
+	become *becomeSpec,
+) (path string, remove func(), err error) {
+	if become != nil && become.user != defaultBecomeUser {
+		return sr.writeScriptAsTargetUser(ctx, contents, become)
 	}
 
-	tmpFile.Close()
+	tmpFile, err := os.CreateTemp(os.TempDir(), tmpPattern)
+	if err != nil {
+		return "", nil, err
+	}
+	remove = func() {
+		_ = os.Remove(tmpFile.Name())
+	}
 
-	logrus.Debugf("WROTE TO FILE:\n%s\n----\n", rawCmds)
+	_, err = tmpFile.WriteString(contents)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return "", nil, err
+	}
 
+	logrus.Debugf("wrote the script file '%s'", tmpFile.Name())
+
+	return tmpFile.Name(), remove, nil
+}
+
+func (sr SystemRunner) createCmd(execContext *Context, scriptPath string, become *becomeSpec) (cmd *exec.Cmd, err error) {
 	shellParam := sr.parseShellParam(execContext.Shell)
 	cmdName, cmdArgs := sr.buildCmdParts(shellParam)
 
 	if runtime.GOOS == "windows" && execContext.Shell == defaultWindowsShell {
-		cmdName = tmpFile.Name()
+		cmdName = scriptPath
 	} else {
-		cmdArgs = append(cmdArgs, tmpFile.Name())
+		cmdArgs = append(cmdArgs, scriptPath)
 	}
+	cmdArgs = append(cmdArgs, execContext.Args...)
 
+	cmdName, cmdArgs = become.wrap(cmdName, cmdArgs)
 	cmd = exec.Command(cmdName, cmdArgs...)
 
 	sr.setWorkingDir(cmd, execContext)
-	if err = sr.setUser(cmd, execContext); err != nil {
-		return
+	if become == nil {
+		if err = sr.setUser(cmd, execContext); err != nil {
+			return
+		}
 	}
 
 	sr.setEnvs(cmd, execContext)
-	sr.setIO(cmd, execContext.StdoutWriter, execContext.StderrWriter)
+	sr.setIO(cmd, execContext.StdoutWriter, execContext.StderrWriter, execContext.OutputLogLevel)
 	return cmd, err
 }
 
@@ -174,22 +264,83 @@ func (sr SystemRunner) buildCmdParts(shellParam ShellParam) (cmdName string, cmd
 	return
 }
 
-func (sr SystemRunner) setIO(cmd *exec.Cmd, stdOutWriter, stdErrWriter io.Writer) {
+func (sr SystemRunner) setIO(cmd *exec.Cmd, stdOutWriter, stdErrWriter io.Writer, outputLogLevel string) {
 	logrus.Debugf("will set stdout and stderr to cmd '%s'", cmd)
+	cmd.Stdout, cmd.Stderr = sr.loggedWriters(stdOutWriter, stdErrWriter, outputLogLevel)
+}
+
+// loggedWriters gives the writers which additionally log the command output with the output log level
+func (sr SystemRunner) loggedWriters(stdOutWriter, stdErrWriter io.Writer, outputLogLevel string) (stdout, stderr io.Writer) {
+	if outputLogLevel == QuietOutputLogLevel {
+		return stdOutWriter, stdErrWriter
+	}
+
+	logLevel, err := logrus.ParseLevel(outputLogLevel)
+	if err != nil {
+		logLevel = logrus.DebugLevel
+	}
+
 	stdOutLoggedWriter := io2.FuncWriter{
 		Callback: func(p []byte) (n int, err error) {
-			logrus.Debugf("stdout capture: %s", string(p))
+			logrus.StandardLogger().Logf(logLevel, "stdout capture: %s", string(p))
 			return len(p), nil
 		},
 	}
 	stdErrLoggedWriter := io2.FuncWriter{
 		Callback: func(p []byte) (n int, err error) {
-			logrus.Debugf("stderr capture: %s", string(p))
+			logrus.StandardLogger().Logf(logLevel, "stderr capture: %s", string(p))
 			return len(p), nil
 		},
 	}
-	cmd.Stdout = io.MultiWriter(stdOutLoggedWriter, stdOutWriter)
-	cmd.Stderr = io.MultiWriter(stdErrLoggedWriter, stdErrWriter)
+
+	return io.MultiWriter(stdOutLoggedWriter, stdOutWriter), io.MultiWriter(stdErrLoggedWriter, stdErrWriter)
+}
+
+// ValidateOutputLogLevel checks if the log level can be used as the output log level of a command
+func ValidateOutputLogLevel(outputLogLevel string) error {
+	if outputLogLevel == "" || outputLogLevel == QuietOutputLogLevel {
+		return nil
+	}
+
+	_, err := logrus.ParseLevel(outputLogLevel)
+
+	return err
+}
+
+// runWithContext starts the command and waits for it, if the context is done before the command finishes,
+// the command is killed by the kill function and the context error is returned. A command is not started
+// if the context is done already.
+func runWithContext(ctx context.Context, cmd *exec.Cmd, start, kill func(cmd *exec.Cmd) error) error {
+	if ctx != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := start(cmd)
+	if err != nil {
+		return err
+	}
+
+	waitErrs := make(chan error, 1)
+	go func() {
+		waitErrs <- cmd.Wait()
+	}()
+
+	if ctx == nil {
+		return <-waitErrs
+	}
+
+	select {
+	case err = <-waitErrs:
+		return err
+	case <-ctx.Done():
+		logrus.Debugf("will kill cmd '%s': %v", cmd, ctx.Err())
+		killErr := kill(cmd)
+		if killErr != nil && !errors.Is(killErr, os.ErrProcessDone) {
+			logrus.Warnf("failed to kill process %d: %v", cmd.Process.Pid, killErr)
+		}
+		<-waitErrs
+		return ctx.Err()
+	}
 }
 
 func (sr SystemRunner) parseShellParam(rawShell string) ShellParam {
@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"context"
 	"io"
 	"os/exec"
 )
@@ -17,7 +18,7 @@ type SystemAPIMock struct {
 	Callback           func(cmd *exec.Cmd) error
 }
 
-func (oem *SystemAPIMock) Run(cmd *exec.Cmd) error {
+func (oem *SystemAPIMock) Run(ctx context.Context, cmd *exec.Cmd) error {
 	oem.Cmds = append(oem.Cmds, cmd)
 
 	if oem.Callback != nil {
@@ -41,6 +42,20 @@ func (oem *SystemAPIMock) Run(cmd *exec.Cmd) error {
 	return oem.ErrToGive
 }
 
+func (oem *SystemAPIMock) Start(cmd *exec.Cmd) error {
+	oem.Cmds = append(oem.Cmds, cmd)
+
+	if oem.Callback != nil {
+		return oem.Callback(cmd)
+	}
+
+	return oem.ErrToGive
+}
+
+func (oem *SystemAPIMock) Kill(cmd *exec.Cmd) error {
+	return nil
+}
+
 func (oem *SystemAPIMock) SetUser(userName, path string, cmd *exec.Cmd) error {
 	oem.UserNameInput = userName
 	oem.UserNamePathInput = path
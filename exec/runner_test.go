@@ -2,10 +2,12 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"os/exec"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/realvnc-labs/tacoscript/conv"
 
@@ -28,7 +30,7 @@ func TestOSApi(t *testing.T) {
 	cmd.Stdout = &outBuf
 
 	cmdRunner := OSApi{}
-	err := cmdRunner.Run(cmd)
+	err := cmdRunner.Run(context.Background(), cmd)
 	assert.NoError(t, err)
 	if err != nil {
 		return
@@ -200,3 +202,105 @@ func TestRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestRunnerTimeoutKillsChildProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses unix shell syntax")
+	}
+
+	systemRunner := SystemRunner{
+		SystemAPI: OSApi{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	stdout := &bytes.Buffer{}
+	execContext := &Context{
+		Ctx:          ctx,
+		StdoutWriter: stdout,
+		StderrWriter: &bytes.Buffer{},
+		// the background process keeps stdout open, so the run would block if it wasn't killed
+		Cmds: []string{"(sleep 10; echo child) &", "echo started", "sleep 10"},
+	}
+
+	start := time.Now()
+	err := systemRunner.Run(execContext)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	runErr := RunError{}
+	assert.True(t, errors.As(err, &runErr))
+	assert.Equal(t, -1, runErr.ExitCode)
+	assert.Equal(t, "started\n", stdout.String())
+}
+
+func TestRunnerCancellation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses unix shell syntax")
+	}
+
+	systemRunner := SystemRunner{
+		SystemAPI: OSApi{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	execContext := &Context{
+		Ctx:          ctx,
+		StdoutWriter: &bytes.Buffer{},
+		StderrWriter: &bytes.Buffer{},
+		Cmds:         []string{"sleep 10"},
+	}
+
+	start := time.Now()
+	err := systemRunner.Run(execContext)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	runErr := RunError{}
+	assert.True(t, errors.As(err, &runErr))
+	assert.Equal(t, -1, runErr.ExitCode)
+}
+
+func TestRunnerScriptArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses unix shell syntax")
+	}
+
+	systemRunner := SystemRunner{
+		SystemAPI: OSApi{},
+	}
+
+	stdout := &bytes.Buffer{}
+	execContext := &Context{
+		StdoutWriter: stdout,
+		StderrWriter: &bytes.Buffer{},
+		Cmds:         []string{`echo "$#: $1, $2"`},
+		Args:         []string{"one", "two words"},
+	}
+
+	err := systemRunner.Run(execContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "2: one, two words\n", stdout.String())
+}
+
+func TestRunWithContextDoneBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	started := false
+	start := func(cmd *exec.Cmd) error {
+		started = true
+		return nil
+	}
+	kill := func(cmd *exec.Cmd) error {
+		return nil
+	}
+
+	err := runWithContext(ctx, exec.Command("true"), start, kill)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, started)
+}
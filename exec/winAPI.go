@@ -4,7 +4,9 @@
 package exec
 
 import (
+	"context"
 	"os/exec"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,8 +14,22 @@ import (
 type OSApi struct {
 }
 
-func (oe OSApi) Run(cmd *exec.Cmd) error {
-	return cmd.Run()
+func (oe OSApi) Run(ctx context.Context, cmd *exec.Cmd) error {
+	return runWithContext(ctx, cmd, oe.Start, oe.Kill)
+}
+
+func (oe OSApi) Start(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// Kill kills the process with all its child processes as Windows has no process groups like Unix
+func (oe OSApi) Kill(cmd *exec.Cmd) error {
+	err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	if err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
 }
 
 func (oe OSApi) SetUser(userName, path string, cmd *exec.Cmd) error {
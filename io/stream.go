@@ -0,0 +1,121 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/secrets"
+)
+
+const (
+	StreamFormatText = "text"
+	StreamFormatJSON = "json"
+)
+
+// LineStream receives the output lines of the running commands
+type LineStream interface {
+	WriteLine(taskPath, streamName, line string)
+}
+
+// NewLineStream gives a line stream of the given format writing to w
+func NewLineStream(format string, w io.Writer) (LineStream, error) {
+	switch format {
+	case "", StreamFormatText:
+		return &TextLineStream{Writer: w}, nil
+	case StreamFormatJSON:
+		return &JSONLineStream{Writer: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown stream format '%s', supported formats are '%s' and '%s'", format, StreamFormatText, StreamFormatJSON)
+	}
+}
+
+// TextLineStream writes each line prefixed with the task path
+type TextLineStream struct {
+	Writer io.Writer
+	mu     sync.Mutex
+}
+
+func (s *TextLineStream) WriteLine(taskPath, streamName, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := taskPath
+	if streamName != "" && streamName != "stdout" {
+		prefix += " " + streamName
+	}
+
+	fmt.Fprintf(s.Writer, "[%s] %s\n", prefix, secrets.MaskString(line))
+}
+
+type lineEvent struct {
+	Time   string `json:"time"`
+	Task   string `json:"task"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// JSONLineStream writes each line as a JSON object on a separate line
+type JSONLineStream struct {
+	Writer io.Writer
+	mu     sync.Mutex
+}
+
+func (s *JSONLineStream) WriteLine(taskPath, streamName, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, err := json.Marshal(lineEvent{
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Task:   taskPath,
+		Stream: streamName,
+		Line:   secrets.MaskString(line),
+	})
+	if err != nil {
+		return
+	}
+
+	event = append(event, '\n')
+	_, _ = s.Writer.Write(event)
+}
+
+// NewLineWriter gives a writer which passes every complete line written to it to the line stream,
+// the returned flush function passes the remaining incomplete line
+func NewLineWriter(stream LineStream, taskPath, streamName string) (w FuncWriter, flush func()) {
+	var mu sync.Mutex
+	pending := []byte{}
+
+	w = FuncWriter{
+		Callback: func(p []byte) (n int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			pending = append(pending, p...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				stream.WriteLine(taskPath, streamName, string(bytes.TrimSuffix(pending[:i], []byte("\r"))))
+				pending = pending[i+1:]
+			}
+
+			return len(p), nil
+		},
+	}
+
+	flush = func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(pending) > 0 {
+			stream.WriteLine(taskPath, streamName, string(pending))
+			pending = pending[:0]
+		}
+	}
+
+	return w, flush
+}
@@ -0,0 +1,68 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/realvnc-labs/tacoscript/secrets"
+)
+
+func TestTextLineStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	stream, err := NewLineStream(StreamFormatText, buf)
+	assert.NoError(t, err)
+
+	w, flush := NewLineWriter(stream, "task1.cmd.run[1]", "stdout")
+	_, err = w.Write([]byte("line1\r\nline"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("2\nrest"))
+	assert.NoError(t, err)
+	stream.WriteLine("task1.cmd.run[1]", "stderr", "some error")
+	flush()
+	flush()
+
+	assert.Equal(
+		t,
+		"[task1.cmd.run[1]] line1\n[task1.cmd.run[1]] line2\n[task1.cmd.run[1] stderr] some error\n[task1.cmd.run[1]] rest\n",
+		buf.String(),
+	)
+}
+
+func TestJSONLineStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	stream, err := NewLineStream(StreamFormatJSON, buf)
+	assert.NoError(t, err)
+
+	stream.WriteLine("task1.cmd.run[1]", "stderr", "some error")
+
+	event := map[string]string{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "task1.cmd.run[1]", event["task"])
+	assert.Equal(t, "stderr", event["stream"])
+	assert.Equal(t, "some error", event["line"])
+	assert.NotEmpty(t, event["time"])
+}
+
+func TestLineStreamMasksSecrets(t *testing.T) {
+	masker := &secrets.Masker{}
+	masker.Register("stream-secret")
+	defer secrets.Activate(masker)()
+
+	buf := new(bytes.Buffer)
+	stream, err := NewLineStream(StreamFormatText, buf)
+	assert.NoError(t, err)
+
+	stream.WriteLine("task1.cmd.run[1]", "stdout", "token is stream-secret")
+
+	assert.Equal(t, "[task1.cmd.run[1]] token is *****\n", buf.String())
+}
+
+func TestUnknownLineStreamFormat(t *testing.T) {
+	_, err := NewLineStream("xml", new(bytes.Buffer))
+	assert.EqualError(t, err, "unknown stream format 'xml', supported formats are 'text' and 'json'")
+}
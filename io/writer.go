@@ -1,5 +1,7 @@
 package io
 
+import "io"
+
 type FuncWriter struct {
 	Callback func(p []byte) (n int, err error)
 }
@@ -7,3 +9,35 @@ type FuncWriter struct {
 func (fw FuncWriter) Write(p []byte) (n int, err error) {
 	return fw.Callback(p)
 }
+
+// LimitedWriter writes at most Limit bytes to the underlying writer, the remaining data is counted as discarded
+// but reported as written, so the producer of the data is not interrupted
+type LimitedWriter struct {
+	Writer    io.Writer
+	Limit     int64
+	Written   int64
+	Discarded int64
+}
+
+func (lw *LimitedWriter) Write(p []byte) (n int, err error) {
+	remaining := lw.Limit - lw.Written
+	if remaining <= 0 {
+		lw.Discarded += int64(len(p))
+		return len(p), nil
+	}
+
+	toWrite := p
+	if int64(len(p)) > remaining {
+		toWrite = p[:remaining]
+	}
+
+	n, err = lw.Writer.Write(toWrite)
+	lw.Written += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	lw.Discarded += int64(len(p) - n)
+
+	return len(p), nil
+}
@@ -33,3 +33,22 @@ func TestFuncWriter(t *testing.T) {
 	_, err2 := tf2.Write(dataToWrite)
 	assert.EqualError(t, err2, "some error")
 }
+
+func TestLimitedWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	lw := &LimitedWriter{
+		Writer: buf,
+		Limit:  8,
+	}
+
+	for _, chunk := range []string{"some ", "data ", "to write"} {
+		n, err := lw.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+
+	assert.Equal(t, "some dat", buf.String())
+	assert.Equal(t, int64(8), lw.Written)
+	assert.Equal(t, int64(10), lw.Discarded)
+}
@@ -0,0 +1,191 @@
+package script
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/state"
+)
+
+// Agent applies a script periodically like taco exec, every run is saved to the history and takes the run lock
+// of the LockDir if it's set. The script is read again before every run, so a changed script is applied
+// by the next run. The status of the last run is written to the agent status file in the state directory.
+type Agent struct {
+	// a local script file or a http, https or ftp URL
+	Script string
+	// the StateDir is required
+	Options  RunOptions
+	Interval time.Duration
+	// every run is delayed by a random duration up to the splay,
+	// so the agents of many hosts don't run the script at the same time
+	Splay time.Duration
+
+	random *rand.Rand
+	status state.AgentStatus
+}
+
+// hashingDataProvider keeps the SHA256 checksum of the script read by the data provider
+type hashingDataProvider struct {
+	DataProvider RawDataProvider
+	sha256       string
+}
+
+func (hdp *hashingDataProvider) Read() ([]byte, error) {
+	data, err := hdp.DataProvider.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	hdp.sha256 = fmt.Sprintf("%x", sha256.Sum256(data))
+
+	return data, nil
+}
+
+// Run applies the script periodically until the context is cancelled, cancelling the context stops the running
+// script like in RunScript. The error is returned only if the agent cannot be started.
+func (a *Agent) Run(ctx context.Context) error {
+	switch {
+	case a.Script == StdinScriptName:
+		return errors.New("the agent cannot read the script from the standard input")
+	case a.Options.StateDir == "":
+		return errors.New("the agent requires the state directory")
+	case a.Interval <= 0:
+		return fmt.Errorf("invalid interval %v, it must be positive", a.Interval)
+	case a.Splay < 0:
+		return fmt.Errorf("invalid splay %v, it must not be negative", a.Splay)
+	}
+
+	engine, err := newEngine(a.Options)
+	if err != nil {
+		return err
+	}
+
+	//nolint:gosec // the splay doesn't need a secure random source
+	a.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	a.status = state.AgentStatus{
+		PID:      os.Getpid(),
+		Script:   a.Script,
+		Interval: a.Interval.String(),
+		Splay:    a.Splay.String(),
+	}
+
+	logrus.Infof("agent started, applying '%s' every %v with a splay of %v", a.Script, a.Interval, a.Splay)
+
+	nextRun := time.Now().Add(a.splay())
+	for {
+		a.status.NextRun = nextRun
+		a.saveStatus()
+
+		timer := time.NewTimer(time.Until(nextRun))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logrus.Info("agent stopped")
+			a.status.Stopped = true
+			a.saveStatus()
+			return nil
+		case <-timer.C:
+		}
+
+		lastRun := a.runOnce(ctx, engine)
+		a.status.LastRun = &lastRun
+
+		// the interval is counted from the start of the run, a run longer than the interval is followed immediately
+		nextRun = lastRun.Started.Add(a.Interval + a.splay())
+	}
+}
+
+// runOnce runs the script, the run is skipped if another process holds the run lock after the lock wait
+func (a *Agent) runOnce(ctx context.Context, engine *Engine) state.AgentRun {
+	run := state.AgentRun{Started: time.Now()}
+
+	release, err := takeRunLock(ctx, a.Options)
+	if err != nil {
+		logrus.Warnf("skipping the run of '%s': %v", a.Script, err)
+		run.Status = state.RunSkipped
+		run.Error = err.Error()
+		run.Finished = time.Now()
+		return run
+	}
+	defer release()
+
+	result, err := a.execute(ctx, engine)
+	run.Finished = time.Now()
+	run.ID = result.Summary.RunID
+	run.Succeeded = result.Summary.Succeeded
+	run.Failed = result.Summary.Failed
+	run.Changes = result.Summary.Changes
+
+	failure := &FailureError{}
+	switch {
+	case err == nil:
+		run.Status = state.RunSucceeded
+	case errors.As(err, &failure):
+		run.Status = state.RunFailed
+		run.Error = err.Error()
+	default:
+		run.Status = state.RunError
+		run.Error = secrets.MaskString(err.Error())
+	}
+
+	if run.Status == state.RunError {
+		logrus.Errorf("run of '%s' failed: %v", a.Script, err)
+		return run
+	}
+
+	logrus.Infof(
+		"run %s of '%s' %s: %d succeeded, %d failed, %d changes",
+		run.ID,
+		a.Script,
+		run.Status,
+		run.Succeeded,
+		run.Failed,
+		run.Changes,
+	)
+
+	return run
+}
+
+// execute reads the script and runs it, a changed checksum of the script is logged
+func (a *Agent) execute(ctx context.Context, engine *Engine) (Result, error) {
+	scriptName, dataProvider, baseURL, err := scriptSource(ctx, engine, a.Script, a.Options)
+	if err != nil {
+		return Result{}, err
+	}
+
+	hashing := &hashingDataProvider{DataProvider: dataProvider}
+	result, err := engine.run(ctx, scriptName, hashing, baseURL)
+
+	if hashing.sha256 != "" && hashing.sha256 != a.status.ScriptSHA256 {
+		if a.status.ScriptSHA256 != "" {
+			logrus.Infof("the script '%s' changed, applied the new version %s", a.Script, hashing.sha256)
+		}
+		a.status.ScriptSHA256 = hashing.sha256
+	}
+
+	return result, err
+}
+
+// splay gives a random delay up to the splay
+func (a *Agent) splay() time.Duration {
+	if a.Splay <= 0 {
+		return 0
+	}
+
+	return time.Duration(a.random.Int63n(int64(a.Splay)))
+}
+
+// saveStatus writes the agent status, a failure is logged as the runs are not affected
+func (a *Agent) saveStatus() {
+	if err := state.SaveAgentStatus(a.Options.StateDir, a.status); err != nil {
+		logrus.Warnf("cannot write the agent status: %v", err)
+	}
+}
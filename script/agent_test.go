@@ -0,0 +1,174 @@
+package script
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/realvnc-labs/tacoscript/state"
+)
+
+func agentTestScript(targetPath, contents string) string {
+	return fmt.Sprintf(`
+motd:
+  file.managed:
+    - name: %s
+    - contents: %s
+    - skip_verify: true
+`, filepath.ToSlash(targetPath), contents)
+}
+
+// waitForAgentRun waits until the agent status has a finished run which matches the condition
+func waitForAgentRun(t *testing.T, stateDir string, condition func(status state.AgentStatus) bool) state.AgentStatus {
+	t.Helper()
+
+	var status state.AgentStatus
+	require.Eventually(t, func() bool {
+		var err error
+		status, err = state.LoadAgentStatus(stateDir)
+		return err == nil && status.LastRun != nil && condition(status)
+	}, 10*time.Second, 10*time.Millisecond)
+
+	return status
+}
+
+func TestAgentRun(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, "state")
+	scriptPath := filepath.Join(dir, "script.yaml")
+	targetPath := filepath.Join(dir, "motd")
+
+	script := agentTestScript(targetPath, "one")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0600))
+
+	agent := &Agent{
+		Script:   scriptPath,
+		Options:  RunOptions{StateDir: stateDir, LockDir: stateDir},
+		Interval: 20 * time.Millisecond,
+		Splay:    5 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	status := waitForAgentRun(t, stateDir, func(status state.AgentStatus) bool {
+		return status.LastRun.Status == state.RunSucceeded
+	})
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte(script))), status.ScriptSHA256)
+	assert.Equal(t, os.Getpid(), status.PID)
+	assert.Equal(t, "20ms", status.Interval)
+
+	contents, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(contents))
+
+	changedScript := agentTestScript(targetPath, "two")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(changedScript), 0600))
+
+	status = waitForAgentRun(t, stateDir, func(status state.AgentStatus) bool {
+		return status.ScriptSHA256 == fmt.Sprintf("%x", sha256.Sum256([]byte(changedScript)))
+	})
+	assert.Equal(t, state.RunSucceeded, status.LastRun.Status)
+
+	contents, err = os.ReadFile(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(contents))
+
+	cancel()
+	require.NoError(t, <-done)
+
+	status, err = state.LoadAgentStatus(stateDir)
+	require.NoError(t, err)
+	assert.True(t, status.Stopped)
+
+	records, err := state.NewHistory(stateDir, 0).List()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(records), 2)
+	assert.Equal(t, status.LastRun.ID, records[0].ID)
+
+	lock, err := state.AcquireLock(stateDir)
+	require.NoError(t, err, "the agent releases the run lock")
+	require.NoError(t, lock.Release())
+}
+
+func TestAgentSkipsLockedRun(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "script.yaml")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(agentTestScript(filepath.Join(dir, "motd"), "one")), 0600))
+
+	lock, err := state.AcquireLock(dir)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, lock.Release())
+	}()
+
+	agent := &Agent{
+		Script:   scriptPath,
+		Options:  RunOptions{StateDir: dir, LockDir: dir},
+		Interval: time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- agent.Run(ctx)
+	}()
+
+	status := waitForAgentRun(t, dir, func(status state.AgentStatus) bool {
+		return true
+	})
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, state.RunSkipped, status.LastRun.Status)
+	assert.Contains(t, status.LastRun.Error, fmt.Sprintf("is held by the process %d", os.Getpid()))
+	assert.NoFileExists(t, filepath.Join(dir, "motd"))
+
+	records, err := state.NewHistory(dir, 0).List()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestAgentValidation(t *testing.T) {
+	testCases := []struct {
+		agent         Agent
+		expectedError string
+	}{
+		{
+			agent:         Agent{Script: "-", Options: RunOptions{StateDir: "state"}, Interval: time.Minute},
+			expectedError: "the agent cannot read the script from the standard input",
+		},
+		{
+			agent:         Agent{Script: "script.yaml", Interval: time.Minute},
+			expectedError: "the agent requires the state directory",
+		},
+		{
+			agent:         Agent{Script: "script.yaml", Options: RunOptions{StateDir: "state"}},
+			expectedError: "invalid interval 0s, it must be positive",
+		},
+		{
+			agent:         Agent{Script: "script.yaml", Options: RunOptions{StateDir: "state"}, Interval: time.Minute, Splay: -time.Second},
+			expectedError: "invalid splay -1s, it must not be negative",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(strings.TrimPrefix(tc.expectedError, "invalid "), func(t *testing.T) {
+			assert.EqualError(t, tc.agent.Run(context.Background()), tc.expectedError)
+		})
+	}
+}
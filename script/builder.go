@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"runtime"
 	"text/template"
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/signing"
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
 	"github.com/realvnc-labs/tacoscript/utils"
@@ -23,6 +28,15 @@ func (fdp FileDataProvider) Read() ([]byte, error) {
 	return os.ReadFile(fdp.Path)
 }
 
+// ReaderDataProvider reads the script from a reader, e.g. the standard input
+type ReaderDataProvider struct {
+	Reader io.Reader
+}
+
+func (rdp ReaderDataProvider) Read() ([]byte, error) {
+	return io.ReadAll(rdp.Reader)
+}
+
 type RawDataProvider interface {
 	Read() ([]byte, error)
 }
@@ -35,36 +49,71 @@ type Builder struct {
 	DataProvider              RawDataProvider
 	TaskBuilder               builder.Builder
 	TemplateVariablesProvider TemplateVariablesProvider
+	// the URL of a remote script, the relative sources of the tasks are resolved against it
+	BaseURL *url.URL
+	// verifies the detached signatures of the local sources of the tasks if it's set
+	Verifier *signing.Verifier
+	// the values of the env variables with matching names are masked in all output, it's optional
+	EnvNameMatcher *secrets.EnvNameMatcher
+	// the decrypted secrets which are available in the templates as {{ secrets.name }}, nil if no secrets file is given
+	Secrets map[string]string
+	// registers the secret values of the run, nil if no values should be masked
+	Masker *secrets.Masker
 }
 
 func (p Builder) BuildScripts() (tasks.Scripts, error) {
-	yamlTemplate, err := p.DataProvider.Read()
+	_, yamlBody, err := p.Render()
 	if err != nil {
 		return tasks.Scripts{}, err
 	}
 
-	templateVariables, err := p.TemplateVariablesProvider.GetTemplateVariables()
+	return p.Build(yamlBody)
+}
+
+// Render reads the script template and renders it with the template variables and secrets
+func (p Builder) Render() (yamlTemplate, yamlBody []byte, err error) {
+	yamlTemplate, err = p.DataProvider.Read()
 	if err != nil {
-		return tasks.Scripts{}, err
+		return nil, nil, err
 	}
-	yamlBody, err := p.render(yamlTemplate, templateVariables)
+
+	templateVariables, err := p.TemplateVariablesProvider.GetTemplateVariables()
 	if err != nil {
-		return tasks.Scripts{}, err
+		return yamlTemplate, nil, err
 	}
+
+	yamlBody, err = p.render(yamlTemplate, templateVariables)
+
+	return yamlTemplate, yamlBody, err
+}
+
+// Build builds the scripts of a rendered script
+func (p Builder) Build(yamlBody []byte) (tasks.Scripts, error) {
 	if len(yamlBody) == 0 {
 		return tasks.Scripts{}, errors.New("empty script provided: nothing to execute")
 	}
 
 	rawScripts := yaml.MapSlice{}
-	err = yaml.Unmarshal(yamlBody, &rawScripts)
+	err := yaml.Unmarshal(yamlBody, &rawScripts)
 	if err != nil {
 		return tasks.Scripts{}, fmt.Errorf("invalid script provided: %w", err)
 	}
 
 	scripts := make(tasks.Scripts, 0, len(rawScripts))
 	errs := utils.Errors{}
+	failHard := false
 	for _, rawTask := range rawScripts {
 		scriptID := rawTask.Key.(string)
+
+		// the failhard option applies to the whole file, a task with the same id has a map value
+		if _, isTask := rawTask.Value.(yaml.MapSlice); scriptID == tasks.FailHardField && !isTask {
+			failHard, err = conv.ConvertToBool(rawTask.Value)
+			if err != nil {
+				errs.Add(fmt.Errorf("invalid '%s' value '%v': %w", tasks.FailHardField, rawTask.Value, err))
+			}
+			continue
+		}
+
 		script := tasks.Script{
 			ID:    scriptID,
 			Tasks: []tasks.CoreTask{},
@@ -81,6 +130,11 @@ func (p Builder) BuildScripts() (tasks.Scripts, error) {
 					return tasks.Scripts{}, err
 				}
 
+				if sourceTask, ok := task.(tasks.TaskWithSource); ok {
+					errs.Add(p.prepareSource(sourceTask.GetSource()))
+				}
+				p.registerSecrets(task)
+
 				err = task.Validate(runtime.GOOS)
 				if err != nil {
 					errs.Add(err)
@@ -93,16 +147,68 @@ func (p Builder) BuildScripts() (tasks.Scripts, error) {
 		}
 		scripts = append(scripts, script)
 	}
+
+	for i := range scripts {
+		scripts[i].FailHard = failHard
+	}
 	err = ValidateScripts(scripts)
 	errs.Add(err)
 
 	return scripts, errs.ToError()
 }
 
+// prepareSource resolves the relative source of a remote script and verifies the signature of a local source,
+// the verified contents are kept in the source, so the executor doesn't read a file which was changed after the verification
+func (p Builder) prepareSource(source *utils.Location) (err error) {
+	if p.BaseURL != nil {
+		*source = source.ResolveRelative(p.BaseURL)
+	}
+
+	if p.Verifier == nil || source.IsURL || source.LocalPath == "" {
+		return nil
+	}
+
+	source.VerifiedContents, err = p.Verifier.ReadVerifiedFile(source.LocalPath)
+
+	return err
+}
+
+// registerSecrets masks the env values of sensitive tasks and the values of the env variables with secret names
+func (p Builder) registerSecrets(task tasks.CoreTask) {
+	envTask, ok := task.(tasks.TaskWithEnvs)
+	if !ok {
+		return
+	}
+
+	sensitive := false
+	if policyTask, ok := task.(tasks.TaskWithExecutionPolicy); ok {
+		sensitive = policyTask.GetExecutionPolicy().Sensitive
+	}
+
+	for _, env := range envTask.GetEnvs() {
+		if sensitive || (p.EnvNameMatcher != nil && p.EnvNameMatcher.IsSecret(env.Key)) {
+			p.Masker.Register(env.Value)
+		}
+	}
+}
+
+// secretValues is the template function giving the decrypted secrets
+func (p Builder) secretValues() (map[string]string, error) {
+	if p.Secrets == nil {
+		return nil, errors.New("the script uses secrets but no secrets file is given")
+	}
+
+	return p.Secrets, nil
+}
+
 func (p Builder) render(templateData []byte, variables utils.TemplateVarsMap) (result []byte, err error) {
 	templ := template.New("goyaml")
 
-	pageTemplate, err := templ.Option("missingkey=zero").Parse(string(templateData))
+	pageTemplate, err := templ.
+		Funcs(secrets.TemplateFuncs(p.Masker)).
+		Funcs(template.FuncMap{secrets.TemplateNamespace: p.secretValues}).
+		Option("missingkey=zero").
+		Parse(string(templateData))
 	if err != nil {
 		return result, err
 	}
@@ -286,6 +286,39 @@ func TestBuilder(t *testing.T) {
 			ExpectedScripts:        tasks.Scripts{},
 			TemplateVariablesError: errors.New("cannot provide template variables"),
 		},
+		{
+			YamlInput: `
+failhard: true
+cwd:
+  cmd.run:
+    - name: echo 1
+`,
+			ExpectedScripts: tasks.Scripts{
+				tasks.Script{
+					ID: "cwd",
+					Tasks: []tasks.CoreTask{
+						&TaskBuilderTaskMock{
+							TypeName: "cmd.run",
+							Path:     "cwd.cmd.run[1]",
+							Context: []interface{}{
+								yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "echo 1"}},
+							},
+						},
+					},
+					FailHard: true,
+				},
+			},
+		},
+		{
+			YamlInput: `
+failhard: sometimes
+cwd:
+  cmd.run:
+    - name: echo 1
+`,
+			ExpectedErrMsg:  `invalid 'failhard' value 'sometimes': failed to parse bool value`,
+			ExpectedScripts: tasks.Scripts{},
+		},
 		{
 			YamlFileName:    "test10.go.yaml",
 			ExpectedErrMsg:  `template: goyaml:3:6: executing "goyaml" at <eq "RedHat">: error calling eq: missing argument for comparison`,
@@ -0,0 +1,424 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/signing"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+// Engine runs scripts, it's the API for the applications embedding tacoscript, the zero configuration
+// created by New behaves like the taco exec command
+type Engine struct {
+	abortOnError              bool
+	stream                    StreamOptions
+	become                    bool
+	becomeMethod              string
+	pluginPath                string
+	runner                    exec.Runner
+	fsManager                 tasks.FsManager
+	templateVariablesProvider TemplateVariablesProvider
+	eventHandler              func(event TaskEvent)
+	verifier                  *signing.Verifier
+	secretEnvPattern          string
+	secretValues              map[string]string
+	secretsFile               string
+	secretsKey                secrets.KeySource
+	history                   *state.History
+	dryRun                    bool
+}
+
+// Option configures an Engine
+type Option func(e *Engine)
+
+// WithRunOptions applies the settings of the taco exec command line flags
+func WithRunOptions(opts RunOptions) Option {
+	return func(e *Engine) {
+		e.abortOnError = opts.AbortOnError
+		e.stream = opts.Stream
+		e.become = opts.Become
+		e.becomeMethod = opts.BecomeMethod
+		e.pluginPath = opts.PluginPath
+		e.secretEnvPattern = opts.SecretEnvPattern
+		if opts.SecretsFile != "" {
+			e.secretsFile = opts.SecretsFile
+			e.secretsKey = secrets.KeySourceFromEnv(opts.SecretsKeyFile)
+		}
+		if opts.StateDir != "" {
+			e.history = state.NewHistory(opts.StateDir, opts.HistoryKeep)
+		}
+		e.dryRun = opts.DryRun
+	}
+}
+
+// WithAbortOnError stops the execution after the first failed task
+func WithAbortOnError(abortOnError bool) Option {
+	return func(e *Engine) {
+		e.abortOnError = abortOnError
+	}
+}
+
+// WithStream streams the command outputs while the script is running
+func WithStream(stream StreamOptions) Option {
+	return func(e *Engine) {
+		e.stream = stream
+	}
+}
+
+// WithPluginPath sets the directories which are searched for plugin executables
+func WithPluginPath(pluginPath string) Option {
+	return func(e *Engine) {
+		e.pluginPath = pluginPath
+	}
+}
+
+// WithRunner runs the commands of all tasks with the given runner, the become settings are ignored then
+func WithRunner(runner exec.Runner) Option {
+	return func(e *Engine) {
+		e.runner = runner
+	}
+}
+
+// WithFsManager makes all tasks access the file system with the given manager
+func WithFsManager(fsManager tasks.FsManager) Option {
+	return func(e *Engine) {
+		e.fsManager = fsManager
+	}
+}
+
+// WithTemplateVariablesProvider gives the variables for rendering the scripts and the facts for the plugins
+// instead of the ones detected on the host
+func WithTemplateVariablesProvider(provider TemplateVariablesProvider) Option {
+	return func(e *Engine) {
+		e.templateVariablesProvider = provider
+	}
+}
+
+// WithEventHandler calls the handler before and after the execution of each task,
+// the handler is called synchronously and should return quickly
+func WithEventHandler(handler func(event TaskEvent)) Option {
+	return func(e *Engine) {
+		e.eventHandler = handler
+	}
+}
+
+// WithVerifier runs only the scripts and local sources with a valid detached signature of a trusted key,
+// the signature of a script file or URL is read from the name with the signature extension
+func WithVerifier(verifier *signing.Verifier) Option {
+	return func(e *Engine) {
+		e.verifier = verifier
+	}
+}
+
+// WithSecretEnvPattern masks the values of the env variables with names matching the regular expression in all output,
+// secrets.DefaultEnvNamePattern is used by default
+func WithSecretEnvPattern(pattern string) Option {
+	return func(e *Engine) {
+		e.secretEnvPattern = pattern
+	}
+}
+
+// WithSecrets makes the values available in the script templates as {{ secrets.name }},
+// the values are masked in all output
+func WithSecrets(values map[string]string) Option {
+	return func(e *Engine) {
+		e.secretValues = values
+	}
+}
+
+// WithSecretsFile decrypts the secrets file with the key before each run, the secrets are available
+// in the script templates as {{ secrets.name }} and masked in all output
+func WithSecretsFile(path string, key secrets.KeySource) Option {
+	return func(e *Engine) {
+		e.secretsFile = path
+		e.secretsKey = key
+	}
+}
+
+// WithHistory saves every run with its result and rendered script to the history,
+// the file modifications are recorded in the journal of the run for the rollback
+func WithHistory(history *state.History) Option {
+	return func(e *Engine) {
+		e.history = history
+	}
+}
+
+// WithDryRun runs all tasks in dry run mode, the tasks report the changes they would make without making them,
+// a dry run is not saved to the history
+func WithDryRun(dryRun bool) Option {
+	return func(e *Engine) {
+		e.dryRun = dryRun
+	}
+}
+
+// New creates an engine configured by the given options
+func New(opts ...Option) *Engine {
+	e := &Engine{
+		fsManager:                 &utils.FsManager{},
+		templateVariablesProvider: utils.OSDataProvider{},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// RunFile runs the script at the given path
+func (e *Engine) RunFile(ctx context.Context, scriptPath string) (Result, error) {
+	var dataProvider RawDataProvider = FileDataProvider{Path: scriptPath}
+	if e.verifier != nil {
+		dataProvider = e.Signed(dataProvider, FileDataProvider{Path: scriptPath + signing.SignatureExtension})
+	}
+
+	return e.Run(ctx, scriptPath, dataProvider)
+}
+
+// RunReader runs the script read from r, the script name is used in the summary of the result.
+// The engine must not have a verifier, use Run with a SignedDataProvider instead.
+func (e *Engine) RunReader(ctx context.Context, scriptName string, r io.Reader) (Result, error) {
+	return e.Run(ctx, scriptName, ReaderDataProvider{Reader: r})
+}
+
+// RunBytes runs the given script, the script name is used in the summary of the result.
+// The engine must not have a verifier, use Run with a SignedDataProvider instead.
+func (e *Engine) RunBytes(ctx context.Context, scriptName string, script []byte) (Result, error) {
+	return e.Run(ctx, scriptName, ReaderDataProvider{Reader: bytes.NewReader(script)})
+}
+
+// RunURL downloads the script from a http, https or ftp URL and runs it if it matches the SHA256 checksum,
+// the relative sources of the tasks are resolved against the URL
+func (e *Engine) RunURL(ctx context.Context, scriptURL *url.URL, sha256 string) (Result, error) {
+	dataProvider := RemoteDataProvider{
+		Ctx:      ctx,
+		URL:      scriptURL,
+		SHA256:   sha256,
+		Verifier: e.verifier,
+	}
+
+	return e.run(ctx, scriptURL.String(), dataProvider, scriptURL)
+}
+
+// Signed verifies the script of the data provider against the signature with the verifier of the engine
+func (e *Engine) Signed(dataProvider, signatureProvider RawDataProvider) SignedDataProvider {
+	return SignedDataProvider{
+		DataProvider:      dataProvider,
+		SignatureProvider: signatureProvider,
+		Verifier:          e.verifier,
+	}
+}
+
+// Run runs the script given by the data provider, cancelling the context stops the execution after killing
+// the currently running command. The result contains the tasks run so far, if any task failed
+// a FailureError is returned together with the result.
+func (e *Engine) Run(ctx context.Context, scriptName string, dataProvider RawDataProvider) (Result, error) {
+	return e.run(ctx, scriptName, dataProvider, nil)
+}
+
+func (e *Engine) run(ctx context.Context, scriptName string, dataProvider RawDataProvider, baseURL *url.URL) (Result, error) {
+	// the secret values are masked in the output of this run only, the next run registers them again
+	masker := &secrets.Masker{}
+	defer secrets.Activate(masker)()
+	ctx = secrets.WithMasker(ctx, masker)
+
+	if e.history == nil || e.dryRun {
+		result, err := e.execute(ctx, scriptName, dataProvider, baseURL, &state.Record{})
+		return result, masker.MaskError(err)
+	}
+
+	started := time.Now()
+	record := state.Record{
+		ID:      state.NewRunID(started),
+		Script:  scriptName,
+		Started: started,
+	}
+
+	result, err := e.execute(ctx, scriptName, dataProvider, baseURL, &record)
+	e.saveRecord(record, result, err)
+
+	return result, masker.MaskError(err)
+}
+
+// execute builds and runs the script, the record gets the script checksum and the rendered script
+func (e *Engine) execute(
+	ctx context.Context,
+	scriptName string,
+	dataProvider RawDataProvider,
+	baseURL *url.URL,
+	record *state.Record,
+) (Result, error) {
+	if e.verifier != nil && !isVerified(dataProvider) {
+		return Result{}, fmt.Errorf("the signature of the script '%s' cannot be verified", scriptName)
+	}
+
+	runner := e.runner
+	if runner == nil {
+		err := exec.ValidateBecomeMethod(e.becomeMethod)
+		if err != nil {
+			return Result{}, err
+		}
+
+		runner = exec.SystemRunner{
+			SystemAPI:    exec.OSApi{},
+			Become:       e.become,
+			BecomeMethod: e.becomeMethod,
+			BecomeChecks: &exec.BecomeChecks{},
+		}
+	}
+
+	builders, executors, err := e.taskTypes(runner)
+	if err != nil {
+		return Result{}, err
+	}
+
+	envNameMatcher, err := secrets.NewEnvNameMatcher(e.secretEnvPattern)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid secret env pattern '%s': %w", e.secretEnvPattern, err)
+	}
+
+	masker := secrets.MaskerFromContext(ctx)
+	secretValues, err := e.loadSecrets(masker)
+	if err != nil {
+		return Result{}, err
+	}
+
+	parser := Builder{
+		DataProvider:              dataProvider,
+		TaskBuilder:               builder.NewBuilderRouter(builders),
+		TemplateVariablesProvider: e.templateVariablesProvider,
+		BaseURL:                   baseURL,
+		Verifier:                  e.verifier,
+		EnvNameMatcher:            envNameMatcher,
+		Secrets:                   secretValues,
+		Masker:                    masker,
+	}
+
+	yamlTemplate, yamlBody, err := parser.Render()
+	if yamlTemplate != nil {
+		record.ScriptSHA256 = fmt.Sprintf("%x", sha256.Sum256(yamlTemplate))
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	scripts, err := parser.Build(yamlBody)
+	// the build registers the secret env values, the rendered script is masked after it
+	record.RenderedScript = masker.Mask(string(yamlBody))
+	if err != nil {
+		return Result{}, err
+	}
+
+	router := tasks.ExecutorRouter{Executors: executors}
+	err = ValidatePolicies(scripts, router)
+	if err != nil {
+		return Result{}, err
+	}
+
+	scriptRunner := Runner{
+		ExecutorRouter: router,
+		ScriptName:     scriptName,
+		EventHandler:   e.eventHandler,
+	}
+
+	if e.dryRun {
+		ctx = tasks.WithDryRun(ctx)
+	} else if e.history != nil {
+		ctx = state.WithJournal(ctx, e.history.Journal(record.ID))
+	}
+
+	result, err := scriptRunner.Execute(ctx, scripts, e.abortOnError)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Summary.RunID = record.ID
+	result.Summary.DryRun = e.dryRun
+
+	return result, result.Err()
+}
+
+// saveRecord completes the record with the outcome of the run and saves it to the history,
+// a failure is logged as the run itself is not affected
+func (e *Engine) saveRecord(record state.Record, result Result, runErr error) {
+	record.Finished = time.Now()
+
+	failure := &FailureError{}
+	switch {
+	case runErr == nil:
+		record.Status = state.RunSucceeded
+	case errors.As(runErr, &failure):
+		record.Status = state.RunFailed
+		record.Error = runErr.Error()
+	default:
+		record.Status = state.RunError
+		record.Error = secrets.MaskString(runErr.Error())
+	}
+
+	if record.Status != state.RunError {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			logrus.Warnf("cannot save the run %s to the history: %v", record.ID, err)
+			return
+		}
+		record.Result = resultJSON
+	}
+
+	if err := e.history.Save(record); err != nil {
+		logrus.Warnf("cannot save the run %s to the history: %v", record.ID, err)
+	}
+}
+
+// loadSecrets gives the secrets of the secrets file and the ones given by WithSecrets, nil if there are none,
+// all values are registered in the masker of the run
+func (e *Engine) loadSecrets(masker *secrets.Masker) (map[string]string, error) {
+	if e.secretsFile == "" && e.secretValues == nil {
+		return nil, nil
+	}
+
+	values := map[string]string{}
+	if e.secretsFile != "" {
+		fileValues, err := secrets.LoadFile(e.secretsFile, e.secretsKey)
+		if err != nil {
+			return nil, err
+		}
+		values = fileValues
+	}
+
+	for name, val := range e.secretValues {
+		values[name] = val
+	}
+
+	for _, val := range values {
+		masker.Register(val)
+	}
+
+	return values, nil
+}
+
+// isVerified tells if the data provider verifies the script signature
+func isVerified(dataProvider RawDataProvider) bool {
+	switch dp := dataProvider.(type) {
+	case SignedDataProvider:
+		return dp.Verifier != nil
+	case RemoteDataProvider:
+		return dp.Verifier != nil
+	case *hashingDataProvider:
+		return isVerified(dp.DataProvider)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,384 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+type templateVariablesProviderMock struct {
+	variables utils.TemplateVarsMap
+}
+
+func (tvpm templateVariablesProviderMock) GetTemplateVariables() (utils.TemplateVarsMap, error) {
+	return tvpm.variables, nil
+}
+
+func TestEngineRunBytes(t *testing.T) {
+	runner := &exec.RunnerMock{}
+	events := []TaskEvent{}
+
+	engine := New(
+		WithRunner(runner),
+		WithTemplateVariablesProvider(templateVariablesProviderMock{
+			variables: utils.TemplateVarsMap{"app_dir": "/opt/app"},
+		}),
+		WithEventHandler(func(event TaskEvent) {
+			events = append(events, event)
+		}),
+	)
+
+	result, err := engine.RunBytes(context.Background(), "inline", []byte(`
+deploy:
+  cmd.run:
+    - name: ls {{ .app_dir }}
+greet:
+  test.greeting:
+    - name: world
+`))
+	require.NoError(t, err)
+
+	require.Len(t, runner.GivenExecContexts, 1)
+	assert.Equal(t, []string{"ls /opt/app"}, runner.GivenExecContexts[0].Cmds)
+
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, "deploy", result.Results[0].ID)
+	assert.True(t, result.Results[0].Result)
+	assert.Equal(t, "Greeted world", result.Results[1].Comment)
+	assert.Equal(t, "inline", result.Summary.Script)
+	assert.Equal(t, 2, result.Summary.Succeeded)
+
+	require.Len(t, events, 4)
+	assert.Equal(t, TaskEvent{Type: TaskStarted, ScriptID: "deploy", TaskType: "cmd.run", Path: "deploy.cmd.run[1]"}, events[0])
+	assert.Equal(t, TaskFinished, events[1].Type)
+	assert.Equal(t, &result.Results[0], events[1].Result)
+	assert.Equal(t, "greet", events[3].ScriptID)
+}
+
+func TestEngineRunWithFailedTask(t *testing.T) {
+	engine := New(
+		WithRunner(&exec.RunnerMock{ErrToReturn: errors.New("exit status 2")}),
+		WithAbortOnError(true),
+	)
+
+	result, err := engine.RunReader(context.Background(), "-", strings.NewReader(`
+first:
+  cmd.run:
+    - name: "false"
+second:
+  cmd.run:
+    - name: "true"
+`))
+	failure := &FailureError{}
+	require.True(t, errors.As(err, &failure), err)
+	assert.Equal(t, &FailureError{Aborted: 1, Failed: 1}, failure)
+	assert.EqualError(t, err, "1 aborted, 1 failed")
+
+	require.Len(t, result.Results, 1)
+	assert.False(t, result.Results[0].Result)
+	assert.Equal(t, "exit status 2", result.Results[0].Error)
+}
+
+func TestEngineRunWithInvalidScript(t *testing.T) {
+	result, err := New(WithRunner(&exec.RunnerMock{})).RunBytes(context.Background(), "inline", []byte(`
+deploy:
+  cmd.run:
+    - cwd: /tmp
+`))
+	require.Error(t, err)
+
+	failure := &FailureError{}
+	assert.False(t, errors.As(err, &failure))
+	assert.Empty(t, result.Results)
+}
+
+func TestEngineRunWithUnsupportedPolicy(t *testing.T) {
+	runner := &exec.RunnerMock{}
+	_, err := New(WithRunner(runner)).RunBytes(context.Background(), "inline", []byte(`
+deploy:
+  cmd.run:
+    - name: ls
+    - timeout: 10s
+    - retry:
+        attempts: 3
+        until: 0
+hosts:
+  host.present:
+    - name: app.local
+    - ip: 10.0.0.1
+    - timeout: 10s
+    - retry:
+        attempts: 3
+        until: 1
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "'timeout' at path 'hosts.host.present[1]' is not supported by the task type host.present")
+	assert.Contains(t, err.Error(), "'retry.until' at path 'hosts.host.present[1]' is not supported")
+	assert.NotContains(t, err.Error(), "deploy")
+	assert.Empty(t, runner.GivenExecContexts)
+}
+
+func TestFailureErrorCancelled(t *testing.T) {
+	err := Result{Summary: Summary{Cancelled: true, Aborted: 2}}.Err()
+
+	assert.True(t, errors.Is(err, ErrCancelled))
+	assert.EqualError(t, err, "script execution cancelled: 2 aborted, 0 failed")
+	assert.NoError(t, Result{}.Err())
+}
+
+func TestEngineMasksSecrets(t *testing.T) {
+	runner := &exec.RunnerMock{
+		RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+			_, _ = stdOutWriter.Write([]byte("connected with tmpl-secret-1 and env-secret-2 as sensitive-secret-3"))
+		},
+	}
+
+	engine := New(
+		WithRunner(runner),
+		WithTemplateVariablesProvider(templateVariablesProviderMock{
+			variables: utils.TemplateVarsMap{"db_password": "tmpl-secret-1"},
+		}),
+	)
+
+	result, err := engine.RunBytes(context.Background(), "inline", []byte(`
+connect:
+  cmd.run:
+    - name: connect --password {{ secret .db_password }}
+    - env:
+        - DB_TOKEN: env-secret-2
+        - PLAIN: plain-value
+deploy:
+  cmd.run:
+    - name: deploy
+    - sensitive: true
+    - env:
+        - LICENSE: sensitive-secret-3
+`))
+	require.NoError(t, err)
+
+	require.Len(t, runner.GivenExecContexts, 2)
+	assert.Equal(t, []string{"connect --password tmpl-secret-1"}, runner.GivenExecContexts[0].Cmds)
+	assert.Equal(t, "env-secret-2", runner.GivenExecContexts[0].Envs[0].Value)
+	assert.Equal(t, "quiet", runner.GivenExecContexts[1].OutputLogLevel)
+
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, "connect --password *****", result.Results[0].Name)
+	assert.Equal(t, "connected with ***** and ***** as *****", result.Results[0].Changes["stdout"])
+	assert.Equal(t, secrets.Mask, result.Results[1].Changes["stdout"])
+	assert.Equal(t, 0, result.Results[1].Changes["pid"])
+	assert.Equal(t, "plain-value", secrets.MaskString("plain-value"))
+
+	// the secrets belong to the run, they are not masked in the output of the process after it
+	assert.Equal(t, "tmpl-secret-1", secrets.MaskString("tmpl-secret-1"))
+}
+
+func TestEngineSecretsFile(t *testing.T) {
+	keySource := secrets.KeySource{Passphrase: "correct horse"}
+	encrypted, err := secrets.Encrypt([]byte("db_password: file-secret-4\n"), keySource)
+	require.NoError(t, err)
+
+	secretsFile := filepath.Join(t.TempDir(), "secrets.enc")
+	require.NoError(t, os.WriteFile(secretsFile, encrypted, 0600))
+
+	script := []byte(`
+connect:
+  cmd.run:
+    - name: connect --password {{ secrets.db_password }} --user {{ secrets.db_user }}
+`)
+
+	runner := &exec.RunnerMock{}
+	result, err := New(
+		WithRunner(runner),
+		WithSecretsFile(secretsFile, keySource),
+		WithSecrets(map[string]string{"db_user": "given-secret-5"}),
+	).RunBytes(context.Background(), "inline", script)
+	require.NoError(t, err)
+
+	require.Len(t, runner.GivenExecContexts, 1)
+	assert.Equal(t, []string{"connect --password file-secret-4 --user given-secret-5"}, runner.GivenExecContexts[0].Cmds)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "connect --password ***** --user *****", result.Results[0].Name)
+
+	_, err = New(
+		WithRunner(&exec.RunnerMock{}),
+		WithSecretsFile(secretsFile, secrets.KeySource{Passphrase: "wrong"}),
+	).RunBytes(context.Background(), "inline", script)
+	assert.True(t, errors.Is(err, secrets.ErrDecrypt), err)
+
+	_, err = New(WithRunner(&exec.RunnerMock{})).RunBytes(context.Background(), "inline", script)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the script uses secrets but no secrets file is given")
+}
+
+func TestEngineHistory(t *testing.T) {
+	history := state.NewHistory(t.TempDir(), 0)
+
+	engine := New(
+		WithRunner(&exec.RunnerMock{}),
+		WithHistory(history),
+		WithTemplateVariablesProvider(templateVariablesProviderMock{
+			variables: utils.TemplateVarsMap{"token": "history-secret-6"},
+		}),
+	)
+
+	result, err := engine.RunBytes(context.Background(), "inline", []byte(`
+deploy:
+  cmd.run:
+    - name: deploy --token {{ secret .token }}
+`))
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Summary.RunID)
+
+	_, err = engine.RunBytes(context.Background(), "broken", []byte(`
+deploy:
+  cmd.run:
+    - cwd: /tmp
+`))
+	require.Error(t, err)
+
+	records, err := history.List()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	record, err := history.Get(result.Summary.RunID)
+	require.NoError(t, err)
+	assert.Equal(t, "inline", record.Script)
+	assert.Equal(t, state.RunSucceeded, record.Status)
+	assert.Len(t, record.ScriptSHA256, 64)
+	assert.Contains(t, record.RenderedScript, "deploy --token *****")
+	assert.False(t, record.Finished.Before(record.Started))
+
+	savedResult := Result{}
+	require.NoError(t, json.Unmarshal(record.Result, &savedResult))
+	assert.Equal(t, result.Summary, savedResult.Summary)
+	require.Len(t, savedResult.Results, 1)
+	assert.Equal(t, "deploy --token *****", savedResult.Results[0].Name)
+	assert.True(t, time.Time(result.Results[0].Started).Equal(time.Time(savedResult.Results[0].Started)))
+
+	broken := records[0]
+	if broken.Script != "broken" {
+		broken = records[1]
+	}
+	assert.Equal(t, state.RunError, broken.Status)
+	assert.NotEmpty(t, broken.Error)
+	assert.Empty(t, broken.Result)
+}
+
+func TestEngineHistoryMasksEnvSecrets(t *testing.T) {
+	stateDir := t.TempDir()
+	history := state.NewHistory(stateDir, 0)
+
+	result, err := New(WithRunner(&exec.RunnerMock{}), WithHistory(history)).RunBytes(context.Background(), "inline", []byte(`
+connect:
+  cmd.run:
+    - name: connect
+    - env:
+        - DB_PASSWORD: hunter2secret
+deploy:
+  cmd.run:
+    - name: deploy
+    - sensitive: true
+    - env:
+        - FOO: verysecretvalue
+`))
+	require.NoError(t, err)
+
+	record, err := history.Get(result.Summary.RunID)
+	require.NoError(t, err)
+	assert.Contains(t, record.RenderedScript, "DB_PASSWORD: *****")
+	assert.Contains(t, record.RenderedScript, "FOO: *****")
+
+	err = filepath.Walk(stateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2secret", path)
+		assert.NotContains(t, string(data), "verysecretvalue", path)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestEngineDryRun(t *testing.T) {
+	runner := &exec.RunnerMock{}
+	history := state.NewHistory(t.TempDir(), 0)
+	filePath := filepath.Join(t.TempDir(), "motd")
+
+	engine := New(
+		WithRunner(runner),
+		WithHistory(history),
+		WithDryRun(true),
+		WithTemplateVariablesProvider(templateVariablesProviderMock{
+			variables: utils.TemplateVarsMap{"motd": filePath},
+		}),
+	)
+
+	result, err := engine.RunBytes(context.Background(), "inline", []byte(`
+motd:
+  file.managed:
+    - name: {{ .motd }}
+    - contents: welcome
+    - skip_verify: true
+deploy:
+  cmd.run:
+    - name: deploy
+greet:
+  test.greeting:
+    - name: world
+`))
+	failure := &FailureError{}
+	require.ErrorAs(t, err, &failure)
+	assert.Equal(t, 1, failure.Failed)
+
+	assert.NoFileExists(t, filePath)
+	assert.Empty(t, runner.GivenExecContexts)
+
+	assert.True(t, result.Summary.DryRun)
+	assert.Empty(t, result.Summary.RunID)
+	assert.Equal(t, 2, result.Summary.Changes)
+
+	require.Len(t, result.Results, 3)
+	assert.Equal(t, "File would be updated", result.Results[0].Comment)
+	assert.Contains(t, result.Results[0].Changes, "diff")
+	assert.Equal(t, `Command "deploy" would run`, result.Results[1].Comment)
+	assert.Equal(t, map[string]interface{}{"cmd": "deploy"}, result.Results[1].Changes)
+	assert.Equal(t, "the task type doesn't support the dry run: test.greeting", result.Results[2].Error)
+
+	records, err := history.List()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestResultDrift(t *testing.T) {
+	result := Result{
+		Results: []TaskResult{
+			{ID: "unchanged", Result: true},
+			{ID: "changed", Result: true, Changes: map[string]interface{}{"diff": "+one"}},
+			{ID: "failed", Result: false},
+		},
+		Summary: Summary{Script: "inline", Succeeded: 2, Failed: 1, Changes: 1},
+	}
+
+	drift := result.Drift()
+
+	assert.Equal(t, result.Summary, drift.Summary)
+	require.Len(t, drift.Results, 2)
+	assert.Equal(t, "changed", drift.Results[0].ID)
+	assert.Equal(t, "failed", drift.Results[1].ID)
+}
@@ -2,17 +2,39 @@ package script
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 
 	"github.com/realvnc-labs/tacoscript/exec"
+	tacoio "github.com/realvnc-labs/tacoscript/io"
+	"github.com/realvnc-labs/tacoscript/signing"
+	"github.com/realvnc-labs/tacoscript/state"
 	"github.com/realvnc-labs/tacoscript/tasks/cmdrun"
 	"github.com/realvnc-labs/tacoscript/tasks/cmdrun/crtbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/cmdscript"
+	"github.com/realvnc-labs/tacoscript/tasks/cmdscript/cstbuilder"
 	"github.com/realvnc-labs/tacoscript/tasks/filemanaged"
 	"github.com/realvnc-labs/tacoscript/tasks/filemanaged/fmtbuilder"
 	"github.com/realvnc-labs/tacoscript/tasks/filereplace"
 	"github.com/realvnc-labs/tacoscript/tasks/filereplace/frtbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/fileserialize"
+	"github.com/realvnc-labs/tacoscript/tasks/fileserialize/fstbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/host"
+	"github.com/realvnc-labs/tacoscript/tasks/host/htbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/ini"
+	"github.com/realvnc-labs/tacoscript/tasks/ini/initbuilder"
 	"github.com/realvnc-labs/tacoscript/tasks/pkgtask"
 	"github.com/realvnc-labs/tacoscript/tasks/pkgtask/pkgbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/plugin"
+	"github.com/realvnc-labs/tacoscript/tasks/plugin/pltbuilder"
 	"github.com/realvnc-labs/tacoscript/tasks/realvncserver"
 	"github.com/realvnc-labs/tacoscript/tasks/realvncserver/rvstbuilder"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
@@ -24,31 +46,220 @@ import (
 	"github.com/realvnc-labs/tacoscript/tasks"
 )
 
-// RunScript main entry point for the script execution
-func RunScript(scriptPath string, abortOnError bool, output io.Writer) error {
-	fileDataProvider := FileDataProvider{
-		Path: scriptPath,
+// StreamOptions define how the command outputs are streamed while the script is running
+type StreamOptions struct {
+	// receives the output lines, the output is not streamed if it's nil
+	Stream tacoio.LineStream
+	// streams the output of all tasks, otherwise only the tasks with `stream: true` are streamed
+	All bool
+}
+
+// RunOptions are the settings of the script execution which are not defined in the script itself
+type RunOptions struct {
+	// stops the execution after the first failed task
+	AbortOnError bool
+	Stream       StreamOptions
+	// runs all commands with escalated privileges
+	Become bool
+	// sudo, su or doas
+	BecomeMethod string
+	// directories separated by os.PathListSeparator which are searched for plugin executables,
+	// TACO_PLUGIN_PATH or PATH are searched if it's empty
+	PluginPath string
+	// the SHA256 checksum of the script, it's required for remote scripts without signature verification
+	SHA256 string
+	// public key files, the script and the local sources must have a valid detached signature of one of them
+	VerifyKeys []string
+	// all *.pub files in the directory are trusted public keys for the signature verification
+	TrustedKeysDir string
+	// the detached signature of the script, defaults to the script path with the signature extension
+	SignaturePath string
+	// the values of the env variables with matching names are masked in all output,
+	// secrets.DefaultEnvNamePattern is used if it's empty
+	SecretEnvPattern string
+	// encrypted secrets file, the secrets are available in the templates as {{ secrets.name }}
+	SecretsFile string
+	// the key file of the secrets file, TACO_SECRETS_KEY_FILE or TACO_SECRETS_PASSPHRASE are used if it's empty
+	SecretsKeyFile string
+	// every run is saved to the history in the state directory if it's set
+	StateDir string
+	// the number of runs kept in the history, all runs are kept if it's not positive
+	HistoryKeep int
+	// the run holds the exclusive run lock of the directory, so runs sharing the directory never overlap,
+	// the run is not locked if it's empty
+	LockDir string
+	// how long the run waits for the run lock held by another process, it fails immediately if it's not positive
+	LockWait time.Duration
+	// runs all tasks in dry run mode, the tasks report the changes they would make without making them
+	DryRun bool
+}
+
+// RunScript main entry point for the script execution, cancelling the context stops the execution
+// after killing the currently running command, the results of the tasks run so far are still written to the output.
+// The script path can be a local file, a http, https or ftp URL or '-' to read the script from the standard input.
+func RunScript(ctx context.Context, scriptPath string, opts RunOptions, output io.Writer) error {
+	result, err := ExecuteScript(ctx, scriptPath, opts)
+	failure := &FailureError{}
+	if err != nil && !errors.As(err, &failure) {
+		return err
+	}
+
+	err = WriteResult(output, result)
+	if err != nil {
+		return err
+	}
+
+	return result.Err()
+}
+
+// ExecuteScript runs the script like RunScript and gives its result instead of writing it to the output,
+// if any task failed a FailureError is returned together with the result
+func ExecuteScript(ctx context.Context, scriptPath string, opts RunOptions) (Result, error) {
+	engine, err := newEngine(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	release, err := takeRunLock(ctx, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	defer release()
+
+	scriptName, dataProvider, baseURL, err := scriptSource(ctx, engine, scriptPath, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return engine.run(ctx, scriptName, dataProvider, baseURL)
+}
+
+// takeRunLock takes the run lock of the lock dir if it's set, if the current user has no permission to write
+// the lock dir, e.g. a non-root user with the default state dir, a warning is logged and the script runs without
+// the lock like it runs without the history
+func takeRunLock(ctx context.Context, opts RunOptions) (release func(), err error) {
+	if opts.LockDir == "" {
+		return func() {}, nil
+	}
+
+	lock, err := state.WaitLock(ctx, opts.LockDir, opts.LockWait)
+	if errors.Is(err, fs.ErrPermission) {
+		logrus.Warnf("running without the run lock: %v", err)
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		releaseLock(lock)
+	}, nil
+}
+
+// releaseLock releases the run lock, a failure is logged as the run is finished already
+func releaseLock(lock *state.RunLock) {
+	if err := lock.Release(); err != nil {
+		logrus.Errorf("cannot release the run lock: %v", err)
+	}
+}
+
+// newEngine creates the engine of the run options, it verifies the script signatures if verification keys are given
+func newEngine(opts RunOptions) (*Engine, error) {
+	engineOpts := []Option{WithRunOptions(opts)}
+	if len(opts.VerifyKeys) > 0 || opts.TrustedKeysDir != "" {
+		verifier, err := signing.NewVerifier(opts.VerifyKeys, opts.TrustedKeysDir)
+		if err != nil {
+			return nil, err
+		}
+		engineOpts = append(engineOpts, WithVerifier(verifier))
+	}
+
+	return New(engineOpts...), nil
+}
+
+// scriptSource gives the name, the data provider and the base URL of a local script or a script URL
+func scriptSource(
+	ctx context.Context,
+	engine *Engine,
+	scriptPath string,
+	opts RunOptions,
+) (scriptName string, dataProvider RawDataProvider, baseURL *url.URL, err error) {
+	if scriptURL := ParseRemoteScriptURL(scriptPath); scriptURL != nil {
+		dataProvider = RemoteDataProvider{
+			Ctx:      ctx,
+			URL:      scriptURL,
+			SHA256:   opts.SHA256,
+			Verifier: engine.verifier,
+		}
+		return scriptURL.String(), dataProvider, scriptURL, nil
+	}
+
+	dataProvider, err = localDataProvider(engine, scriptPath, opts, engine.verifier != nil)
+	if err != nil {
+		return "", nil, nil, err
 	}
 
-	parser := Builder{
-		DataProvider: fileDataProvider,
-		TaskBuilder: builder.NewBuilderRouter(map[string]builder.Builder{
-			cmdrun.TaskType:                    &crtbuilder.TaskBuilder{},
-			filemanaged.TaskType:               &fmtbuilder.TaskBuilder{},
-			filereplace.TaskType:               &frtbuilder.TaskBuilder{},
-			realvncserver.TaskTypeConfigUpdate: &rvstbuilder.TaskBuilder{},
-			pkgtask.TaskTypePkgInstalled:       &pkgbuilder.TaskBuilder{},
-			pkgtask.TaskTypePkgRemoved:         &pkgbuilder.TaskBuilder{},
-			pkgtask.TaskTypePkgUpgraded:        &pkgbuilder.TaskBuilder{},
-			winreg.TaskTypeWinRegPresent:       &wrtbuilder.TaskBuilder{},
-			winreg.TaskTypeWinRegAbsent:        &wrtbuilder.TaskBuilder{},
-			winreg.TaskTypeWinRegAbsentKey:     &wrtbuilder.TaskBuilder{},
-		}),
-		TemplateVariablesProvider: utils.OSDataProvider{},
+	return scriptPath, dataProvider, nil, nil
+}
+
+// WriteResult writes the result as YAML to the output
+func WriteResult(output io.Writer, result Result) error {
+	y, err := yaml.Marshal(result)
+	if err != nil {
+		return err
 	}
 
-	cmdRunner := exec.SystemRunner{
-		SystemAPI: exec.OSApi{},
+	fmt.Fprintln(output, string(y))
+
+	return nil
+}
+
+// localDataProvider reads the script from a file or the standard input and verifies its checksum and signature
+func localDataProvider(engine *Engine, scriptPath string, opts RunOptions, verify bool) (RawDataProvider, error) {
+	var dataProvider RawDataProvider = FileDataProvider{Path: scriptPath}
+	if scriptPath == StdinScriptName {
+		dataProvider = ReaderDataProvider{Reader: os.Stdin}
+	}
+
+	if opts.SHA256 != "" {
+		dataProvider = ChecksumDataProvider{DataProvider: dataProvider, SHA256: opts.SHA256}
+	}
+
+	if !verify {
+		return dataProvider, nil
+	}
+
+	signaturePath := opts.SignaturePath
+	if signaturePath == "" {
+		if scriptPath == StdinScriptName {
+			return nil, fmt.Errorf("the signature path is required to verify the script from the standard input")
+		}
+		signaturePath = scriptPath + signing.SignatureExtension
+	}
+
+	return engine.Signed(dataProvider, FileDataProvider{Path: signaturePath}), nil
+}
+
+// taskTypes gives the builders and executors of all task types available to the engine
+func (e *Engine) taskTypes(cmdRunner exec.Runner) (map[string]builder.Builder, map[string]tasks.Executor, error) {
+	builders := map[string]builder.Builder{
+		cmdrun.TaskType:                    &crtbuilder.TaskBuilder{},
+		cmdscript.TaskType:                 &cstbuilder.TaskBuilder{},
+		filemanaged.TaskType:               &fmtbuilder.TaskBuilder{},
+		filereplace.TaskType:               &frtbuilder.TaskBuilder{},
+		fileserialize.TaskType:             &fstbuilder.TaskBuilder{},
+		realvncserver.TaskTypeConfigUpdate: &rvstbuilder.TaskBuilder{},
+		pkgtask.TaskTypePkgInstalled:       &pkgbuilder.TaskBuilder{},
+		pkgtask.TaskTypePkgRemoved:         &pkgbuilder.TaskBuilder{},
+		pkgtask.TaskTypePkgUpgraded:        &pkgbuilder.TaskBuilder{},
+		winreg.TaskTypeWinRegPresent:       &wrtbuilder.TaskBuilder{},
+		winreg.TaskTypeWinRegAbsent:        &wrtbuilder.TaskBuilder{},
+		winreg.TaskTypeWinRegAbsentKey:     &wrtbuilder.TaskBuilder{},
+		host.TaskTypeHostPresent:           &htbuilder.TaskBuilder{},
+		host.TaskTypeHostAbsent:            &htbuilder.TaskBuilder{},
+		ini.TaskTypeOptionsPresent:         &initbuilder.TaskBuilder{},
+		ini.TaskTypeOptionsAbsent:          &initbuilder.TaskBuilder{},
+		ini.TaskTypeSectionsPresent:        &initbuilder.TaskBuilder{},
 	}
 
 	pkgTaskManager := pkgmanager.PackageTaskManager{
@@ -59,53 +270,112 @@ func RunScript(scriptPath string, abortOnError bool, output io.Writer) error {
 	pkgTaskExecutor := &pkgtask.Executor{
 		PackageManager: pkgTaskManager,
 		Runner:         cmdRunner,
-		FsManager:      &utils.FsManager{},
+		FsManager:      e.fsManager,
 	}
 
 	winRegTaskExecutor := &winreg.Executor{
 		Runner:    cmdRunner,
-		FsManager: &utils.FsManager{},
-	}
-
-	execRouter := tasks.ExecutorRouter{
-		Executors: map[string]tasks.Executor{
-			cmdrun.TaskType: &cmdrun.Executor{
-				Runner:    cmdRunner,
-				FsManager: &utils.FsManager{},
-			},
-			filemanaged.TaskType: &filemanaged.Executor{
-				Runner:      cmdRunner,
-				FsManager:   &utils.FsManager{},
-				HashManager: &utils.HashManager{},
-			},
-			filereplace.TaskType: &filereplace.Executor{
-				Runner:    cmdRunner,
-				FsManager: &utils.FsManager{},
-			},
-			realvncserver.TaskTypeConfigUpdate: &realvncserver.Executor{
-				Runner:    cmdRunner,
-				FsManager: &utils.FsManager{},
-			},
-			pkgtask.TaskTypePkgInstalled:   pkgTaskExecutor,
-			pkgtask.TaskTypePkgRemoved:     pkgTaskExecutor,
-			pkgtask.TaskTypePkgUpgraded:    pkgTaskExecutor,
-			winreg.TaskTypeWinRegPresent:   winRegTaskExecutor,
-			winreg.TaskTypeWinRegAbsent:    winRegTaskExecutor,
-			winreg.TaskTypeWinRegAbsentKey: winRegTaskExecutor,
-		},
+		FsManager: e.fsManager,
+	}
+
+	hostTaskExecutor := &host.Executor{
+		Runner:    cmdRunner,
+		FsManager: e.fsManager,
+	}
+
+	iniTaskExecutor := &ini.Executor{
+		Runner:    cmdRunner,
+		FsManager: e.fsManager,
 	}
 
-	scripts, err := parser.BuildScripts()
+	executors := map[string]tasks.Executor{
+		cmdrun.TaskType: &cmdrun.Executor{
+			Runner:    cmdRunner,
+			FsManager: e.fsManager,
+			Stream:    e.stream.Stream,
+			StreamAll: e.stream.All,
+		},
+		cmdscript.TaskType: &cmdscript.Executor{
+			Runner:                    cmdRunner,
+			FsManager:                 e.fsManager,
+			HashManager:               &utils.HashManager{},
+			TemplateVariablesProvider: e.templateVariablesProvider,
+		},
+		filemanaged.TaskType: &filemanaged.Executor{
+			Runner:      cmdRunner,
+			FsManager:   e.fsManager,
+			HashManager: &utils.HashManager{},
+		},
+		filereplace.TaskType: &filereplace.Executor{
+			Runner:    cmdRunner,
+			FsManager: e.fsManager,
+		},
+		fileserialize.TaskType: &fileserialize.Executor{
+			Runner:    cmdRunner,
+			FsManager: e.fsManager,
+		},
+		realvncserver.TaskTypeConfigUpdate: &realvncserver.Executor{
+			Runner:    cmdRunner,
+			FsManager: e.fsManager,
+		},
+		pkgtask.TaskTypePkgInstalled:   pkgTaskExecutor,
+		pkgtask.TaskTypePkgRemoved:     pkgTaskExecutor,
+		pkgtask.TaskTypePkgUpgraded:    pkgTaskExecutor,
+		winreg.TaskTypeWinRegPresent:   winRegTaskExecutor,
+		winreg.TaskTypeWinRegAbsent:    winRegTaskExecutor,
+		winreg.TaskTypeWinRegAbsentKey: winRegTaskExecutor,
+		host.TaskTypeHostPresent:       hostTaskExecutor,
+		host.TaskTypeHostAbsent:        hostTaskExecutor,
+		ini.TaskTypeOptionsPresent:     iniTaskExecutor,
+		ini.TaskTypeOptionsAbsent:      iniTaskExecutor,
+		ini.TaskTypeSectionsPresent:    iniTaskExecutor,
+	}
 
+	err := addRegisteredTaskTypes(builders, executors)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+
+	pluginExecutor := &plugin.Executor{
+		Runner:                    cmdRunner,
+		FsManager:                 e.fsManager,
+		TemplateVariablesProvider: e.templateVariablesProvider,
 	}
+	addPluginTaskTypes(builders, executors, plugin.Discover(plugin.Dirs(e.pluginPath)), pluginExecutor)
+
+	return builders, executors, nil
+}
 
-	runner := Runner{
-		DataProvider:   fileDataProvider,
-		ExecutorRouter: execRouter,
+// addRegisteredTaskTypes adds the task types registered with tasks.Register to the built-in ones
+func addRegisteredTaskTypes(builders map[string]builder.Builder, executors map[string]tasks.Executor) error {
+	for typeName, registration := range tasks.RegisteredTaskTypes() {
+		if _, ok := builders[typeName]; ok {
+			return fmt.Errorf("registered task type '%s' conflicts with a built-in task type", typeName)
+		}
+
+		builders[typeName] = registration.Builder
+		executors[typeName] = registration.Executor
 	}
 
-	err = runner.Run(context.Background(), scripts, abortOnError, output)
-	return err
+	return nil
+}
+
+// addPluginTaskTypes adds the task types of the discovered plugin executables,
+// the plugins can't replace the built-in or registered task types
+func addPluginTaskTypes(
+	builders map[string]builder.Builder,
+	executors map[string]tasks.Executor,
+	plugins map[string]plugin.Plugin,
+	pluginExecutor tasks.Executor,
+) {
+	for typeName, p := range plugins {
+		if _, ok := builders[typeName]; ok {
+			logrus.Warnf("ignoring plugin '%s', the task type '%s' already exists", p.Executable, typeName)
+			continue
+		}
+
+		logrus.Debugf("using plugin '%s' for the task type '%s'", p.Executable, typeName)
+		builders[typeName] = pltbuilder.TaskBuilder{Plugin: p}
+		executors[typeName] = pluginExecutor
+	}
 }
@@ -0,0 +1,206 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/plugin"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+)
+
+const greetingTaskType = "test.greeting"
+
+type greetingTask struct {
+	TypeName string
+	Path     string
+
+	Name    string   `taco:"name"`
+	Require []string `taco:"require"`
+}
+
+func (gt *greetingTask) GetTypeName() string { return gt.TypeName }
+func (gt *greetingTask) Validate(goos string) error {
+	return tasks.ValidateRequired(gt.Name, gt.Path+".name")
+}
+func (gt *greetingTask) GetPath() string               { return gt.Path }
+func (gt *greetingTask) GetRequirements() []string     { return gt.Require }
+func (gt *greetingTask) GetCreatesFilesList() []string { return nil }
+func (gt *greetingTask) GetOnlyIfCmds() []string       { return nil }
+func (gt *greetingTask) GetUnlessCmds() []string       { return nil }
+
+func (gt *greetingTask) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	return tasks.ResultDescription{
+		Name:    gt.Name,
+		Comment: "Greeted " + gt.Name,
+	}
+}
+
+type greetingTaskBuilder struct{}
+
+func (gtb greetingTaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &greetingTask{
+		TypeName: typeName,
+		Path:     path,
+	}
+
+	errs := builder.Build(typeName, path, params, task, nil)
+
+	return task, errs.ToError()
+}
+
+type greetingTaskExecutor struct{}
+
+func (gte greetingTaskExecutor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	return executionresult.ExecutionResult{
+		Changes: map[string]string{"greeting": "hello " + task.(*greetingTask).Name},
+	}
+}
+
+func init() {
+	tasks.Register(greetingTaskType, greetingTaskBuilder{}, greetingTaskExecutor{})
+}
+
+func TestRunScriptWithRegisteredTaskType(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "script.yaml")
+	err := os.WriteFile(scriptPath, []byte(`
+greet-world:
+  test.greeting:
+    - name: world
+`), 0600)
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	err = RunScript(context.Background(), scriptPath, RunOptions{}, output)
+	require.NoError(t, err)
+
+	result := Result{}
+	require.NoError(t, yaml.Unmarshal(output.Bytes(), &result))
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, greetingTaskType, result.Results[0].Function)
+	assert.Equal(t, "world", result.Results[0].Name)
+	assert.Equal(t, "Greeted world", result.Results[0].Comment)
+	assert.Equal(t, map[string]interface{}{"greeting": "hello world"}, result.Results[0].Changes)
+	assert.Equal(t, 1, result.Summary.Changes)
+}
+
+func TestExecuteScriptRunLock(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "script.yaml")
+	err := os.WriteFile(scriptPath, []byte(`
+greet-world:
+  test.greeting:
+    - name: world
+`), 0600)
+	require.NoError(t, err)
+
+	lock, err := state.AcquireLock(dir)
+	require.NoError(t, err)
+
+	_, err = ExecuteScript(context.Background(), scriptPath, RunOptions{LockDir: dir})
+	assert.ErrorIs(t, err, state.ErrLocked)
+	assert.Contains(t, err.Error(), fmt.Sprintf("is held by the process %d", os.Getpid()))
+
+	result, err := ExecuteScript(context.Background(), scriptPath, RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Summary.Succeeded)
+
+	require.NoError(t, lock.Release())
+
+	_, err = ExecuteScript(context.Background(), scriptPath, RunOptions{LockDir: dir})
+	require.NoError(t, err)
+
+	lock, err = state.AcquireLock(dir)
+	require.NoError(t, err, "the run releases the lock")
+	require.NoError(t, lock.Release())
+}
+
+func TestExecuteScriptRunLockWithoutPermission(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("the permissions of the lock dir must apply to the current user")
+	}
+
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "script.yaml")
+	err := os.WriteFile(scriptPath, []byte(`
+greet-world:
+  test.greeting:
+    - name: world
+`), 0600)
+	require.NoError(t, err)
+
+	readOnlyDir := filepath.Join(dir, "readonly")
+	require.NoError(t, os.Mkdir(readOnlyDir, 0500))
+
+	result, err := ExecuteScript(context.Background(), scriptPath, RunOptions{LockDir: filepath.Join(readOnlyDir, "state")})
+	require.NoError(t, err, "the script runs without the lock")
+	assert.Equal(t, 1, result.Summary.Succeeded)
+}
+
+func TestAddRegisteredTaskTypesConflict(t *testing.T) {
+	err := addRegisteredTaskTypes(
+		map[string]builder.Builder{greetingTaskType: greetingTaskBuilder{}},
+		map[string]tasks.Executor{},
+	)
+	assert.EqualError(t, err, "registered task type 'test.greeting' conflicts with a built-in task type")
+}
+
+func TestRunScriptWithPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugin is a shell script")
+	}
+
+	pluginDir := t.TempDir()
+	err := os.WriteFile(
+		filepath.Join(pluginDir, plugin.ExecutablePrefix+"app.deployed"),
+		[]byte("#!/bin/sh\ncat > /dev/null\necho '{\"changed\": true, \"comment\": \"App deployed\", \"changes\": {\"version\": \"1.2\"}}'\n"),
+		0700, //nolint:gosec // the plugin must be executable
+	)
+	require.NoError(t, err)
+
+	scriptPath := filepath.Join(t.TempDir(), "script.yaml")
+	err = os.WriteFile(scriptPath, []byte(`
+deploy-shop:
+  app.deployed:
+    - name: shop
+`), 0600)
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	err = RunScript(context.Background(), scriptPath, RunOptions{PluginPath: pluginDir}, output)
+	require.NoError(t, err)
+
+	result := Result{}
+	require.NoError(t, yaml.Unmarshal(output.Bytes(), &result))
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "shop", result.Results[0].Name)
+	assert.Equal(t, "App deployed", result.Results[0].Comment)
+	assert.Equal(t, map[string]interface{}{"version": "1.2"}, result.Results[0].Changes)
+}
+
+func TestAddPluginTaskTypesKeepsExistingTypes(t *testing.T) {
+	builders := map[string]builder.Builder{greetingTaskType: greetingTaskBuilder{}}
+	executors := map[string]tasks.Executor{greetingTaskType: greetingTaskExecutor{}}
+	pluginExecutor := &plugin.Executor{}
+
+	addPluginTaskTypes(builders, executors, map[string]plugin.Plugin{
+		greetingTaskType: {Executable: "/opt/plugins/taco-plugin-test.greeting"},
+		"app.deployed":   {Executable: "/opt/plugins/taco-plugin-app.deployed"},
+	}, pluginExecutor)
+
+	assert.Equal(t, greetingTaskBuilder{}, builders[greetingTaskType])
+	assert.Equal(t, greetingTaskExecutor{}, executors[greetingTaskType])
+	assert.NotNil(t, builders["app.deployed"])
+	assert.Equal(t, pluginExecutor, executors["app.deployed"])
+}
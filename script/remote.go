@@ -0,0 +1,163 @@
+package script
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/signing"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+// StdinScriptName is the script name which reads the script from the standard input
+const StdinScriptName = "-"
+
+var remoteSchemes = []string{"http", "https", "ftp"}
+
+// ParseRemoteScriptURL gives the URL of a script name if it's a http, https or ftp URL, otherwise nil
+func ParseRemoteScriptURL(scriptName string) *url.URL {
+	u, err := url.Parse(scriptName)
+	if err != nil {
+		return nil
+	}
+
+	for _, scheme := range remoteSchemes {
+		if u.Scheme == scheme {
+			return u
+		}
+	}
+
+	return nil
+}
+
+// RemoteDataProvider downloads the script from an URL, the script must match the SHA256 checksum
+// or the detached signature at the URL with the signature extension
+type RemoteDataProvider struct {
+	Ctx      context.Context
+	URL      *url.URL
+	SHA256   string
+	Verifier *signing.Verifier
+}
+
+func (rdp RemoteDataProvider) Read() ([]byte, error) {
+	if rdp.SHA256 == "" && rdp.Verifier == nil {
+		return nil, fmt.Errorf(
+			"the sha256 checksum or a signature verification key is required for the remote script '%s'",
+			rdp.URL,
+		)
+	}
+
+	data, err := download(rdp.Ctx, rdp.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download script '%s': %w", rdp.URL, err)
+	}
+
+	if rdp.SHA256 != "" {
+		err = VerifySHA256(data, rdp.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("script '%s': %w", rdp.URL, err)
+		}
+	}
+
+	if rdp.Verifier != nil {
+		signatureURL := *rdp.URL
+		signatureURL.Path += signing.SignatureExtension
+
+		signature, err := download(rdp.Ctx, &signatureURL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot download signature '%s': %w", &signatureURL, err)
+		}
+
+		err = rdp.Verifier.Verify(data, signature)
+		if err != nil {
+			return nil, fmt.Errorf("script '%s': %w", rdp.URL, err)
+		}
+	}
+
+	return data, nil
+}
+
+func download(ctx context.Context, u *url.URL) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "taco-download-*")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmpFile.Name()
+	utils.CloseResourceSecure(tmpPath, tmpFile)
+
+	defer func() {
+		if e := os.Remove(tmpPath); e != nil {
+			logrus.Warnf("cannot remove the downloaded file '%s': %v", tmpPath, e)
+		}
+	}()
+
+	err = utils.DownloadFile(ctx, tmpPath, u, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(tmpPath)
+}
+
+// SignedDataProvider verifies the detached signature of the script read by the wrapped data provider
+type SignedDataProvider struct {
+	DataProvider      RawDataProvider
+	SignatureProvider RawDataProvider
+	Verifier          *signing.Verifier
+}
+
+func (sdp SignedDataProvider) Read() ([]byte, error) {
+	data, err := sdp.DataProvider.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sdp.SignatureProvider.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the script signature: %w", err)
+	}
+
+	err = sdp.Verifier.Verify(data, signature)
+	if err != nil {
+		return nil, fmt.Errorf("script verification failed: %w", err)
+	}
+
+	return data, nil
+}
+
+// ChecksumDataProvider verifies the SHA256 checksum of the script read by the wrapped data provider
+type ChecksumDataProvider struct {
+	DataProvider RawDataProvider
+	SHA256       string
+}
+
+func (cdp ChecksumDataProvider) Read() ([]byte, error) {
+	data, err := cdp.DataProvider.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	err = VerifySHA256(data, cdp.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// VerifySHA256 checks that the data matches the hex encoded checksum, a 'sha256=' prefix of the checksum is allowed
+func VerifySHA256(data []byte, expected string) error {
+	expected = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(expected), "sha256="))
+	actual := fmt.Sprintf("%x", sha256.Sum256(data))
+
+	if actual != expected {
+		return fmt.Errorf("sha256 checksum mismatch, expected '%s', got '%s'", expected, actual)
+	}
+
+	return nil
+}
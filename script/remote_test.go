@@ -0,0 +1,171 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/tasks/filemanaged"
+	"github.com/realvnc-labs/tacoscript/tasks/filemanaged/fmtbuilder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+const greetingScript = `
+greet-world:
+  test.greeting:
+    - name: world
+`
+
+func sha256Of(data string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+}
+
+func TestRunScriptFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scripts/site.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(greetingScript))
+	}))
+	defer server.Close()
+
+	scriptURL := server.URL + "/scripts/site.yaml"
+
+	testCases := []struct {
+		name        string
+		sha256      string
+		expectedErr string
+	}{
+		{
+			name:   "matching checksum",
+			sha256: sha256Of(greetingScript),
+		},
+		{
+			name:   "matching checksum with algorithm prefix",
+			sha256: "sha256=" + strings.ToUpper(sha256Of(greetingScript)),
+		},
+		{
+			name:        "missing checksum",
+			expectedErr: fmt.Sprintf("the sha256 checksum or a signature verification key is required for the remote script '%s'", scriptURL),
+		},
+		{
+			name:   "wrong checksum",
+			sha256: sha256Of("other"),
+			expectedErr: fmt.Sprintf(
+				"script '%s': sha256 checksum mismatch, expected '%s', got '%s'",
+				scriptURL,
+				sha256Of("other"),
+				sha256Of(greetingScript),
+			),
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			err := RunScript(context.Background(), scriptURL, RunOptions{SHA256: tc.sha256}, output)
+
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				assert.Empty(t, output.String())
+				return
+			}
+
+			require.NoError(t, err)
+
+			result := Result{}
+			require.NoError(t, yaml.Unmarshal(output.Bytes(), &result))
+			assert.Equal(t, scriptURL, result.Summary.Script)
+			assert.Equal(t, 1, result.Summary.Succeeded)
+		})
+	}
+}
+
+func TestRunScriptFromStdin(t *testing.T) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	require.NoError(t, err)
+
+	_, err = stdinWriter.WriteString(greetingScript)
+	require.NoError(t, err)
+	require.NoError(t, stdinWriter.Close())
+
+	stdin := os.Stdin
+	os.Stdin = stdinReader
+	defer func() {
+		os.Stdin = stdin
+		_ = stdinReader.Close()
+	}()
+
+	output := &bytes.Buffer{}
+	err = RunScript(context.Background(), StdinScriptName, RunOptions{}, output)
+	require.NoError(t, err)
+
+	result := Result{}
+	require.NoError(t, yaml.Unmarshal(output.Bytes(), &result))
+	assert.Equal(t, StdinScriptName, result.Summary.Script)
+	assert.Equal(t, "Greeted world", result.Results[0].Comment)
+}
+
+func TestRunScriptWithChecksum(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(scriptPath, []byte(greetingScript), 0600))
+
+	err := RunScript(context.Background(), scriptPath, RunOptions{SHA256: sha256Of(greetingScript)}, &bytes.Buffer{})
+	assert.NoError(t, err)
+
+	err = RunScript(context.Background(), scriptPath, RunOptions{SHA256: sha256Of("other")}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestBuildScriptsResolvesRelativeSources(t *testing.T) {
+	baseURL, err := url.Parse("https://example.com/scripts/site.yaml")
+	require.NoError(t, err)
+
+	absolutePath := filepath.Join(t.TempDir(), "app.conf")
+
+	parser := Builder{
+		DataProvider: RawDataProviderMock{DataToReturn: fmt.Sprintf(`
+relative-source:
+  file.managed:
+    - name: /etc/app.conf
+    - source: files/app.conf
+    - source_hash: sha256=%s
+absolute-source:
+  file.managed:
+    - name: /etc/app.conf
+    - source: %s
+`, sha256Of(""), absolutePath)},
+		TaskBuilder: builder.NewBuilderRouter(map[string]builder.Builder{
+			filemanaged.TaskType: &fmtbuilder.TaskBuilder{},
+		}),
+		TemplateVariablesProvider: templateVariablesProviderMock{variables: utils.TemplateVarsMap{}},
+		BaseURL:                   baseURL,
+	}
+
+	scripts, err := parser.BuildScripts()
+	require.NoError(t, err)
+	require.Len(t, scripts, 2)
+
+	relativeTask := scripts[0].Tasks[0].(*filemanaged.Task)
+	assert.True(t, relativeTask.Source.IsURL)
+	assert.Equal(t, "https://example.com/scripts/files/app.conf", relativeTask.Source.URL.String())
+
+	absoluteTask := scripts[1].Tasks[0].(*filemanaged.Task)
+	assert.False(t, absoluteTask.Source.IsURL)
+	assert.Equal(t, absolutePath, absoluteTask.Source.LocalPath)
+}
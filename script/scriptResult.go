@@ -1,16 +1,72 @@
 package script
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
 )
 
+// Result is the outcome of a script execution, it's written as YAML to the output of the taco exec command
 type Result struct {
-	Results []taskResult
+	Results []TaskResult
+
+	Summary Summary
+}
+
+// FailureError is returned if tasks of the script failed or were aborted, it wraps ErrCancelled
+// if the execution was cancelled
+type FailureError struct {
+	Aborted   int
+	Failed    int
+	Cancelled bool
+}
 
-	Summary scriptSummary
+func (fe *FailureError) Error() string {
+	msg := fmt.Sprintf("%d aborted, %d failed", fe.Aborted, fe.Failed)
+	if fe.Cancelled {
+		return fmt.Sprintf("%v: %s", ErrCancelled, msg)
+	}
+
+	return msg
 }
 
-type taskResult struct {
+func (fe *FailureError) Unwrap() error {
+	if fe.Cancelled {
+		return ErrCancelled
+	}
+
+	return nil
+}
+
+// Err gives a FailureError if any task failed or was aborted
+func (r Result) Err() error {
+	if !r.Summary.Cancelled && r.Summary.Aborted == 0 && r.Summary.Failed == 0 {
+		return nil
+	}
+
+	return &FailureError{
+		Aborted:   r.Summary.Aborted,
+		Failed:    r.Summary.Failed,
+		Cancelled: r.Summary.Cancelled,
+	}
+}
+
+// Drift gives the result with only the failed tasks and the tasks which changed the host,
+// in a dry run these are the tasks which drifted from the desired state
+func (r Result) Drift() Result {
+	drift := Result{Summary: r.Summary}
+	for _, taskResult := range r.Results {
+		if !taskResult.Result || len(taskResult.Changes) > 0 {
+			drift.Results = append(drift.Results, taskResult)
+		}
+	}
+
+	return drift
+}
+
+// TaskResult is the outcome of a single task
+type TaskResult struct {
 	ID       string `yaml:"ID"`
 	Function string `yaml:"Function"`
 	Name     string `yaml:"Name"`
@@ -18,20 +74,65 @@ type taskResult struct {
 	Comment  string `yaml:"Comment,omitempty"`
 	Error    string `yaml:"Error,omitempty"`
 
+	// the task was interrupted because the script execution was cancelled
+	Cancelled bool `yaml:"Cancelled,omitempty"`
+
 	Started  onlyTime      `yaml:"Started"`
 	Duration time.Duration `yaml:"Duration"`
 
 	Changes map[string]interface{} `yaml:"Changes,omitempty"` // map for custom key-val data depending on type
+
+	// the runs of a task with retries, the task result is the result of the last run
+	Attempts []AttemptResult `yaml:"Attempts,omitempty"`
+}
+
+// AttemptResult is the outcome of a single run of a task with retries
+type AttemptResult struct {
+	Attempt  int           `yaml:"Attempt"`
+	Result   bool          `yaml:"Result"`
+	Retcode  int           `yaml:"Retcode,omitempty"`
+	Error    string        `yaml:"Error,omitempty"`
+	Duration time.Duration `yaml:"Duration"`
+}
+
+func newAttemptResults(attempts []executionresult.AttemptResult) []AttemptResult {
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	results := make([]AttemptResult, 0, len(attempts))
+	for _, attempt := range attempts {
+		errString := ""
+		if attempt.Err != nil {
+			errString = attempt.Err.Error()
+		}
+
+		results = append(results, AttemptResult{
+			Attempt:  attempt.Attempt,
+			Result:   attempt.Err == nil,
+			Retcode:  attempt.ExitCode,
+			Error:    errString,
+			Duration: attempt.Duration,
+		})
+	}
+
+	return results
 }
 
-type scriptSummary struct {
-	Script        string        `yaml:"Script"`
+// Summary gives the totals of a script execution
+type Summary struct {
+	Script string `yaml:"Script"`
+	// the id of the run in the history, it's empty if the history is disabled
+	RunID         string        `yaml:"RunID,omitempty"`
 	Succeeded     int           `yaml:"Succeeded"`
 	Failed        int           `yaml:"Failed"`
 	Aborted       int           `yaml:"Aborted"`
 	Changes       int           `yaml:"Changes"`
 	TotalTasksRun int           `yaml:"TotalTasksRun"`
 	TotalRunTime  time.Duration `yaml:"TotalRunTime"`
+	Cancelled     bool          `yaml:"Cancelled,omitempty"`
+	// the tasks ran as a dry run, the changes are the ones the tasks would make
+	DryRun bool `yaml:"DryRun,omitempty"`
 
 	Total int `yaml:"-"`
 }
@@ -44,6 +145,15 @@ func (c onlyTime) MarshalYAML() (interface{}, error) {
 	return time.Time(c).Format(stampMicro), nil
 }
 
+// MarshalJSON keeps the full time in the JSON history records
+func (c onlyTime) MarshalJSON() ([]byte, error) {
+	return time.Time(c).MarshalJSON()
+}
+
+func (c *onlyTime) UnmarshalJSON(data []byte) error {
+	return (*time.Time)(c).UnmarshalJSON(data)
+}
+
 func (c *onlyTime) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var started string
 	err := unmarshal(&started)
@@ -2,37 +2,74 @@ package script
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 
-	"github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/secrets"
 	"github.com/realvnc-labs/tacoscript/tasks"
-	"github.com/realvnc-labs/tacoscript/tasks/cmdrun"
-	"github.com/realvnc-labs/tacoscript/tasks/filemanaged"
-	"github.com/realvnc-labs/tacoscript/tasks/filereplace"
-	"github.com/realvnc-labs/tacoscript/tasks/pkgtask"
-	"github.com/realvnc-labs/tacoscript/tasks/realvncserver"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
-	"github.com/realvnc-labs/tacoscript/tasks/winreg"
 )
 
+// ErrCancelled is returned when the script execution was interrupted by the cancellation of the context
+var ErrCancelled = errors.New("script execution cancelled")
+
+// TaskEventType tells which stage of a task is reported by a TaskEvent
+type TaskEventType string
+
+const (
+	TaskStarted  TaskEventType = "started"
+	TaskFinished TaskEventType = "finished"
+)
+
+// TaskEvent is passed to the event handler of the runner before and after the execution of each task
+type TaskEvent struct {
+	Type     TaskEventType
+	ScriptID string
+	TaskType string
+	Path     string
+	// the outcome of the task, it's nil for the TaskStarted events
+	Result *TaskResult
+}
+
 type Runner struct {
 	ExecutorRouter tasks.ExecutorRouter
-	DataProvider   FileDataProvider
+	// the script name in the summary of the result
+	ScriptName string
+	// is called before and after the execution of each task, it's optional
+	EventHandler func(event TaskEvent)
 }
 
+// Run executes the scripts and writes the result as YAML to the output
 func (r Runner) Run(ctx context.Context, scripts tasks.Scripts, globalAbortOnError bool, output io.Writer) error {
+	result, err := r.Execute(ctx, scripts, globalAbortOnError)
+	if err != nil {
+		return err
+	}
+
+	y, err := yaml.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(output, string(y))
+
+	return result.Err()
+}
+
+// Execute executes the scripts and gives their result, the error is returned only if the scripts cannot be executed,
+// the failed tasks are reported by the result
+func (r Runner) Execute(ctx context.Context, scripts tasks.Scripts, globalAbortOnError bool) (Result, error) {
 	SortScriptsRespectingRequirements(scripts)
 
 	result := Result{}
 	scriptStart := time.Now()
 
-	summary := scriptSummary{}
+	summary := Summary{}
 
 	for _, script := range scripts {
 		summary.Total += len(script.Tasks)
@@ -42,13 +79,18 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts, globalAbortOnErr
 		logrus.Debugf("will run script '%s'", script.ID)
 		abort := false
 		for _, task := range script.Tasks {
+			if ctx.Err() != nil {
+				break
+			}
+
 			taskStart := time.Now()
 			executor, err := r.ExecutorRouter.GetExecutor(task)
 			if err != nil {
-				return err
+				return Result{}, err
 			}
 
 			logrus.Debugf("will run task '%s' at path '%s'", task.GetTypeName(), task.GetPath())
+			r.notify(TaskEvent{Type: TaskStarted, ScriptID: script.ID, TaskType: task.GetTypeName(), Path: task.GetPath()})
 
 			res := executor.Execute(ctx, task)
 
@@ -62,80 +104,54 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts, globalAbortOnErr
 
 			summary.TotalTasksRun++
 
-			name := ""
-			comment := ""
-			changeMap := make(map[string]interface{})
-
-			if cmdRunTask, ok := task.(*cmdrun.Task); ok {
-				// summary and changeMap will be updated
-				name, comment, abort = handleCmdRunResults(cmdRunTask, &summary, &res, changeMap)
-			}
-
-			if pkgTask, ok := task.(*pkgtask.Task); ok {
-				name = pkgTask.Named.Name
-				comment = res.Comment
-				if res.Err == nil && !pkgTask.Updated {
-					comment = "Package not updated " + res.SkipReason
-				}
-			}
-
-			if winRegTask, ok := task.(*winreg.Task); ok {
-				name = winRegTask.RegPath + `\` + winRegTask.Name
-				comment = res.Comment
-				if res.Err == nil && !winRegTask.Updated {
-					comment = "Windows registry not updated " + res.SkipReason
-				}
+			name, comment, changeMap := describeResult(task, &res)
+			if len(changeMap) > 0 {
+				summary.Changes++
 			}
 
-			if managedTask, ok := task.(*filemanaged.Task); ok {
-				name = managedTask.Name
-				comment = res.Comment
-				if res.Err == nil && !managedTask.Updated {
-					comment = "File not changed " + res.SkipReason
-				}
+			errString := ""
+			if res.Err != nil {
+				errString = res.Err.Error()
 			}
 
-			if replaceTask, ok := task.(*filereplace.Task); ok {
-				name = replaceTask.Name
-				comment = res.Comment
-				if res.Err == nil && !replaceTask.Updated {
-					comment = "File not changed " + res.SkipReason
-				}
+			cancelled := ctx.Err() != nil && !res.Succeeded()
+			if cancelled {
+				logrus.Warnf("task '%s' at path '%s' was cancelled", task.GetTypeName(), task.GetPath())
+				comment = "Task cancelled"
 			}
 
-			if realVNCServerTask, ok := task.(*realvncserver.Task); ok {
-				comment = res.Comment
-				if res.Err == nil && !realVNCServerTask.Updated {
-					comment = "Config not changed " + res.SkipReason
-				}
+			taskRes := TaskResult{
+				ID:        script.ID,
+				Function:  task.GetTypeName(),
+				Name:      name,
+				Result:    res.Succeeded(),
+				Comment:   comment,
+				Started:   onlyTime(taskStart),
+				Duration:  res.Duration,
+				Changes:   changeMap,
+				Error:     errString,
+				Cancelled: cancelled,
+				Attempts:  newAttemptResults(res.Attempts),
 			}
-
-			if len(res.Changes) > 0 {
-				for k, v := range res.Changes {
-					changeMap[k] = v
-				}
-				summary.Changes++
-			}
-
-			errString := ""
-			if res.Err != nil {
-				errString = res.Err.Error()
+			taskRes = maskTaskResult(secrets.MaskerFromContext(ctx), task, taskRes)
+			result.Results = append(result.Results, taskRes)
+			r.notify(TaskEvent{Type: TaskFinished, ScriptID: script.ID, TaskType: task.GetTypeName(), Path: task.GetPath(), Result: &taskRes})
+
+			if !res.Succeeded() && abortsOnError(task, globalAbortOnError || script.FailHard) {
+				logrus.Warnf("task '%s' at path '%s' failed, aborting the execution", task.GetTypeName(), task.GetPath())
+				abort = true
+				break
 			}
+		}
 
-			result.Results = append(result.Results, taskResult{
-				ID:       script.ID,
-				Function: task.GetTypeName(),
-				Name:     name,
-				Result:   res.Succeeded(),
-				Comment:  comment,
-				Started:  onlyTime(taskStart),
-				Duration: res.Duration,
-				Changes:  changeMap,
-				Error:    errString,
-			})
+		if ctx.Err() != nil {
+			logrus.Debugf("aborting due to cancellation: %v", ctx.Err())
+			summary.Cancelled = true
+			summary.Aborted = summary.Total - summary.TotalTasksRun
+			break
 		}
 
-		if abort || globalAbortOnError {
+		if abort {
 			logrus.Debug("aborting due to task failure")
 			summary.Aborted = summary.Total - summary.TotalTasksRun
 			break
@@ -143,63 +159,70 @@ func (r Runner) Run(ctx context.Context, scripts tasks.Scripts, globalAbortOnErr
 		logrus.Debugf("finished script '%s'", script.ID)
 	}
 
-	summary.Script = r.DataProvider.Path
+	summary.Script = r.ScriptName
 	summary.TotalRunTime = time.Since(scriptStart)
 	result.Summary = summary
 
-	y, err := yaml.Marshal(result)
-	if err != nil {
-		return err
+	return result, nil
+}
+
+func (r Runner) notify(event TaskEvent) {
+	if r.EventHandler != nil {
+		r.EventHandler(event)
 	}
+}
 
-	fmt.Fprintln(output, string(y))
+// describeResult gives the name, comment and changes of the task in the script result
+func describeResult(task tasks.CoreTask, res *executionresult.ExecutionResult) (name, comment string, changes map[string]interface{}) {
+	desc := tasks.ResultDescription{
+		Comment: res.Comment,
+	}
+	if describedTask, ok := task.(tasks.TaskWithResultDescription); ok {
+		desc = describedTask.DescribeResult(res)
+	}
 
-	if summary.Aborted > 0 || summary.Failed > 0 {
-		return fmt.Errorf("%d aborted, %d failed", summary.Aborted, summary.Failed)
+	changes = desc.Changes
+	if changes == nil {
+		changes = make(map[string]interface{}, len(res.Changes))
+		for k, v := range res.Changes {
+			changes[k] = v
+		}
 	}
 
-	return nil
+	return desc.Name, desc.Comment, changes
 }
 
-func handleCmdRunResults(
-	cmdRunTask *cmdrun.Task,
-	summary *scriptSummary,
-	res *executionresult.ExecutionResult,
-	changeMap map[string]interface{}) (name string, comment string, abort bool) {
-	name = strings.Join(cmdRunTask.Named.GetNames(), "; ")
-
-	if !res.IsSkipped {
-		comment = `Command "` + name + `" run`
-		changeMap["pid"] = res.Pid
-		if runErr, ok := res.Err.(exec.RunError); ok {
-			changeMap["retcode"] = runErr.ExitCode
-		}
+// maskTaskResult replaces the secret values of the run in the task result, the string changes of sensitive tasks
+// like the command output are masked completely
+func maskTaskResult(masker *secrets.Masker, task tasks.CoreTask, res TaskResult) TaskResult {
+	res.Name = masker.Mask(res.Name)
+	res.Comment = masker.Mask(res.Comment)
+	res.Error = masker.Mask(res.Error)
 
-		changeMap["stderr"] = strings.TrimSpace(strings.ReplaceAll(res.StdErr, "\r\n", "\n"))
-		changeMap["stdout"] = strings.TrimSpace(strings.ReplaceAll(res.StdOut, "\r\n", "\n"))
+	policyTask, ok := task.(tasks.TaskWithExecutionPolicy)
+	sensitive := ok && policyTask.GetExecutionPolicy().Sensitive
 
-		if exec.IsPowerShell(cmdRunTask.Shell) {
-			changeMap["stdout"] = powershellUnquote(changeMap["stdout"].(string))
+	for key, val := range res.Changes {
+		if strVal, isStr := val.(string); isStr && sensitive && strVal != "" {
+			res.Changes[key] = secrets.Mask
+			continue
 		}
-		summary.Changes++
-	} else {
-		comment = `Command skipped: ` + res.SkipReason
+		res.Changes[key] = masker.MaskValue(val)
 	}
 
-	if cmdRunTask.AbortOnError && !res.Succeeded() {
-		abort = true
-		summary.Aborted = summary.Total - summary.TotalTasksRun
+	for i := range res.Attempts {
+		res.Attempts[i].Error = masker.Mask(res.Attempts[i].Error)
 	}
 
-	return name, comment, abort
+	return res
 }
 
-// stdout from multiline powershell scripts often includes trailing spaces on each line.
-// when encoded as yaml, the result does not look pretty. this function strips trailing whitespace
-func powershellUnquote(s string) string {
-	lines := strings.Split(s, "\n")
-	for i, line := range lines {
-		lines[i] = strings.TrimRight(line, " ")
+// abortsOnError decides if the failure of the task stops the execution of the remaining tasks
+func abortsOnError(task tasks.CoreTask, failHard bool) bool {
+	policyTask, ok := task.(tasks.TaskWithExecutionPolicy)
+	if !ok {
+		return failHard
 	}
-	return strings.Join(lines, "\n")
+
+	return policyTask.GetExecutionPolicy().AbortsOnError(failHard)
 }
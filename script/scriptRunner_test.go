@@ -1,16 +1,21 @@
 package script
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 type TaskMock struct {
+	tasks.ExecutionPolicy
+
 	ID           string
 	ExecResult   executionresult.ExecutionResult
 	Requirements []string
@@ -154,3 +159,199 @@ func TestScriptRunner(t *testing.T) {
 		assert.Equal(t, testCase.ExpectedExecutedTasks, actualExecutedTasks)
 	}
 }
+
+type cancellingExecutorMock struct {
+	cancel     context.CancelFunc
+	cancelAt   string
+	InputTasks []tasks.CoreTask
+}
+
+func (em *cancellingExecutorMock) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	em.InputTasks = append(em.InputTasks, task)
+	if task.(*TaskMock).ID != em.cancelAt {
+		return executionresult.ExecutionResult{}
+	}
+
+	em.cancel()
+	return executionresult.ExecutionResult{Err: ctx.Err()}
+}
+
+func TestScriptRunnerCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	executor := &cancellingExecutorMock{
+		cancel:   cancel,
+		cancelAt: "task2",
+	}
+
+	runr := Runner{
+		ExecutorRouter: tasks.ExecutorRouter{
+			Executors: map[string]tasks.Executor{
+				"TaskMock": executor,
+			},
+		},
+	}
+
+	scripts := tasks.Scripts{
+		tasks.Script{
+			ID:    "script1",
+			Tasks: []tasks.CoreTask{&TaskMock{ID: "task1"}, &TaskMock{ID: "task2"}, &TaskMock{ID: "task3"}},
+		},
+		tasks.Script{
+			ID:    "script2",
+			Tasks: []tasks.CoreTask{&TaskMock{ID: "task4"}},
+		},
+	}
+
+	output := &bytes.Buffer{}
+	err := runr.Run(ctx, scripts, false, output)
+	assert.EqualError(t, err, "script execution cancelled: 2 aborted, 1 failed")
+	assert.ErrorIs(t, err, ErrCancelled)
+	assert.Len(t, executor.InputTasks, 2)
+
+	result := Result{}
+	assert.NoError(t, yaml.Unmarshal(output.Bytes(), &result))
+	assert.Len(t, result.Results, 2)
+	assert.False(t, result.Results[0].Cancelled)
+	assert.True(t, result.Results[1].Cancelled)
+	assert.Equal(t, "Task cancelled", result.Results[1].Comment)
+	assert.Equal(t, "context canceled", result.Results[1].Error)
+	assert.True(t, result.Summary.Cancelled)
+	assert.Equal(t, 1, result.Summary.Succeeded)
+	assert.Equal(t, 1, result.Summary.Failed)
+	assert.Equal(t, 2, result.Summary.Aborted)
+	assert.Equal(t, 2, result.Summary.TotalTasksRun)
+}
+
+// failingExecutorMock fails the tasks with the given ids
+type failingExecutorMock struct {
+	failedIDs  []string
+	InputTasks []string
+}
+
+func (em *failingExecutorMock) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	id := task.(*TaskMock).ID
+	em.InputTasks = append(em.InputTasks, id)
+	for _, failedID := range em.failedIDs {
+		if id == failedID {
+			return executionresult.ExecutionResult{Err: errors.New("some error")}
+		}
+	}
+
+	return executionresult.ExecutionResult{}
+}
+
+func TestScriptRunnerAbortOnError(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		failHard              bool
+		globalAbortOnError    bool
+		failingTask           *TaskMock
+		expectedError         string
+		expectedExecutedTasks []string
+	}{
+		{
+			name:                  "failed task doesn't abort",
+			failingTask:           &TaskMock{ID: "task2"},
+			expectedError:         "0 aborted, 1 failed",
+			expectedExecutedTasks: []string{"task1", "task2", "task3", "task4"},
+		},
+		{
+			name: "task with abort_on_error",
+			failingTask: &TaskMock{
+				ID:              "task2",
+				ExecutionPolicy: tasks.ExecutionPolicy{AbortOnError: true},
+			},
+			expectedError:         "2 aborted, 1 failed",
+			expectedExecutedTasks: []string{"task1", "task2"},
+		},
+		{
+			name:                  "failhard script",
+			failHard:              true,
+			failingTask:           &TaskMock{ID: "task2"},
+			expectedError:         "2 aborted, 1 failed",
+			expectedExecutedTasks: []string{"task1", "task2"},
+		},
+		{
+			name:                  "global abort on error",
+			globalAbortOnError:    true,
+			failingTask:           &TaskMock{ID: "task2"},
+			expectedError:         "2 aborted, 1 failed",
+			expectedExecutedTasks: []string{"task1", "task2"},
+		},
+		{
+			name:     "continue_on_error overrides failhard",
+			failHard: true,
+			failingTask: &TaskMock{
+				ID:              "task2",
+				ExecutionPolicy: tasks.ExecutionPolicy{ContinueOnError: true},
+			},
+			expectedError:         "0 aborted, 1 failed",
+			expectedExecutedTasks: []string{"task1", "task2", "task3", "task4"},
+		},
+		{
+			name:                  "successful tasks don't abort",
+			globalAbortOnError:    true,
+			failHard:              true,
+			failingTask:           &TaskMock{ID: "task2", ExecutionPolicy: tasks.ExecutionPolicy{AbortOnError: true}},
+			expectedExecutedTasks: []string{"task1", "task2", "task3", "task4"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			executor := &failingExecutorMock{}
+			if tc.expectedError != "" {
+				executor.failedIDs = []string{tc.failingTask.ID}
+			}
+
+			runr := Runner{
+				ExecutorRouter: tasks.ExecutorRouter{
+					Executors: map[string]tasks.Executor{
+						"TaskMock": executor,
+					},
+				},
+			}
+
+			scripts := tasks.Scripts{
+				tasks.Script{
+					ID:       "script1",
+					Tasks:    []tasks.CoreTask{&TaskMock{ID: "task1"}, tc.failingTask, &TaskMock{ID: "task3"}},
+					FailHard: tc.failHard,
+				},
+				tasks.Script{
+					ID:       "script2",
+					Tasks:    []tasks.CoreTask{&TaskMock{ID: "task4"}},
+					FailHard: tc.failHard,
+				},
+			}
+
+			err := runr.Run(context.Background(), scripts, tc.globalAbortOnError, &bytes.Buffer{})
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+			assert.Equal(t, tc.expectedExecutedTasks, executor.InputTasks)
+		})
+	}
+}
+
+func TestDescribeResult(t *testing.T) {
+	res := &executionresult.ExecutionResult{
+		Comment: "some comment",
+		Changes: map[string]string{"key": "value"},
+	}
+
+	name, comment, changes := describeResult(&TaskMock{ID: "task1"}, res)
+	assert.Equal(t, "", name)
+	assert.Equal(t, "some comment", comment)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, changes)
+
+	name, comment, changes = describeResult(&greetingTask{Name: "world"}, res)
+	assert.Equal(t, "world", name)
+	assert.Equal(t, "Greeted world", comment)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, changes)
+}
@@ -0,0 +1,192 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/realvnc-labs/tacoscript/signing"
+)
+
+func signFile(t *testing.T, privateKeyPath, path string) {
+	t.Helper()
+
+	key, err := signing.ReadPrivateKey(privateKeyPath)
+	require.NoError(t, err)
+
+	_, err = signing.SignFile(key, path)
+	require.NoError(t, err)
+}
+
+func TestRunScriptWithSignatureVerification(t *testing.T) {
+	keysDir := t.TempDir()
+	privateKeyPath, publicKeyPath, err := signing.GenerateKeyPair(filepath.Join(keysDir, "release"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		signScript  bool
+		signSource  bool
+		tamper      bool
+		expectedErr string
+	}{
+		{
+			name:       "signed script and source",
+			signScript: true,
+			signSource: true,
+		},
+		{
+			name:        "unsigned script",
+			signSource:  true,
+			expectedErr: "cannot read the script signature",
+		},
+		{
+			name:        "unsigned source",
+			signScript:  true,
+			expectedErr: "missing signature of",
+		},
+		{
+			name:        "tampered script",
+			signScript:  true,
+			signSource:  true,
+			tamper:      true,
+			expectedErr: "script verification failed: invalid signature",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sourcePath := filepath.Join(dir, "app.conf")
+			require.NoError(t, os.WriteFile(sourcePath, []byte("port=80"), 0600))
+
+			scriptPath := filepath.Join(dir, "site.yaml")
+			script := fmt.Sprintf(`
+app-config:
+  file.managed:
+    - name: %s
+    - source: %s
+    - skip_verify: true
+`, filepath.Join(dir, "target.conf"), sourcePath)
+			require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0600))
+
+			if tc.signScript {
+				signFile(t, privateKeyPath, scriptPath)
+			}
+			if tc.signSource {
+				signFile(t, privateKeyPath, sourcePath)
+			}
+			if tc.tamper {
+				require.NoError(t, os.WriteFile(scriptPath, []byte(script+"\n# tampered\n"), 0600))
+			}
+
+			output := &bytes.Buffer{}
+			err := RunScript(context.Background(), scriptPath, RunOptions{VerifyKeys: []string{publicKeyPath}}, output)
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				assert.NoFileExists(t, filepath.Join(dir, "target.conf"))
+				return
+			}
+
+			require.NoError(t, err, output.String())
+			assert.FileExists(t, filepath.Join(dir, "target.conf"))
+		})
+	}
+}
+
+func TestRunScriptUsesVerifiedSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the source is changed with a unix shell command")
+	}
+
+	keysDir := t.TempDir()
+	privateKeyPath, publicKeyPath, err := signing.GenerateKeyPair(filepath.Join(keysDir, "release"))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(sourcePath, []byte("port=80"), 0600))
+	signFile(t, privateKeyPath, sourcePath)
+
+	// the source is changed after its signature was verified but before the file.managed task runs
+	targetPath := filepath.Join(dir, "target.conf")
+	scriptPath := filepath.Join(dir, "site.yaml")
+	script := fmt.Sprintf(`
+change-source:
+  cmd.run:
+    - name: echo port=666 > %s
+app-config:
+  file.managed:
+    - name: %s
+    - source: %s
+    - skip_verify: true
+    - require:
+      - change-source
+`, sourcePath, targetPath, sourcePath)
+	require.NoError(t, os.WriteFile(scriptPath, []byte(script), 0600))
+	signFile(t, privateKeyPath, scriptPath)
+
+	output := &bytes.Buffer{}
+	err = RunScript(context.Background(), scriptPath, RunOptions{VerifyKeys: []string{publicKeyPath}}, output)
+	require.NoError(t, err, output.String())
+
+	contents, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, "port=80", string(contents))
+}
+
+func TestRunScriptFromURLWithSignature(t *testing.T) {
+	keysDir := t.TempDir()
+	privateKeyPath, _, err := signing.GenerateKeyPair(filepath.Join(keysDir, "release"))
+	require.NoError(t, err)
+
+	key, err := signing.ReadPrivateKey(privateKeyPath)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/site.yaml":
+			_, _ = w.Write([]byte(greetingScript))
+		case "/site.yaml.sig":
+			_, _ = w.Write(signing.Sign(key, []byte(greetingScript)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	err = RunScript(context.Background(), server.URL+"/site.yaml", RunOptions{TrustedKeysDir: keysDir}, &bytes.Buffer{})
+	assert.NoError(t, err)
+}
+
+func TestRunScriptFromStdinWithoutSignature(t *testing.T) {
+	keysDir := t.TempDir()
+	_, publicKeyPath, err := signing.GenerateKeyPair(filepath.Join(keysDir, "release"))
+	require.NoError(t, err)
+
+	err = RunScript(context.Background(), StdinScriptName, RunOptions{VerifyKeys: []string{publicKeyPath}}, &bytes.Buffer{})
+	assert.EqualError(t, err, "the signature path is required to verify the script from the standard input")
+}
+
+func TestEngineWithVerifierRefusesUnsignedScripts(t *testing.T) {
+	engine := New(WithVerifier(&signing.Verifier{}))
+
+	_, err := engine.RunBytes(context.Background(), "inline", []byte(greetingScript))
+	assert.EqualError(t, err, "the signature of the script 'inline' cannot be verified")
+
+	failure := &FailureError{}
+	assert.False(t, errors.As(err, &failure))
+}
@@ -42,6 +42,18 @@ func isCyclic(curScriptID string,
 	return false
 }
 
+// ValidatePolicies checks that the executors of the tasks can honor the execution policies of the tasks
+func ValidatePolicies(scrpts tasks.Scripts, router tasks.ExecutorRouter) error {
+	errs := utils.Errors{}
+	for _, script := range scrpts {
+		for _, task := range script.Tasks {
+			errs.Add(router.ValidatePolicy(task))
+		}
+	}
+
+	return errs.ToError()
+}
+
 func ValidateScripts(scrpts tasks.Scripts) error {
 	scriptIDToNodesMap := make(map[string][]string)
 	scriptIDsMap := make(map[string]bool, len(scrpts))
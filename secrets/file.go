@@ -0,0 +1,280 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// KeyFileEnv is the env variable with the path of the key file, it's used if no key file is given
+	KeyFileEnv = "TACO_SECRETS_KEY_FILE"
+	// PassphraseEnv is the env variable with the passphrase, it's used if no key file is given
+	PassphraseEnv = "TACO_SECRETS_PASSPHRASE"
+	// TemplateNamespace is the template function giving the decrypted secrets, e.g. {{ secrets.db_password }}
+	TemplateNamespace = "secrets"
+
+	fileVersion = 1
+	kdfKeyFile  = "keyfile"
+	kdfPBKDF2   = "pbkdf2-sha256"
+	keySize     = 32
+	saltSize    = 16
+
+	// pbkdf2Iterations is the work factor of the passphrase key derivation, the value is stored in the encrypted file
+	pbkdf2Iterations = 600000
+	// maxPBKDF2Iterations limits the work factor read from a file, so a crafted file cannot stall the decryption
+	maxPBKDF2Iterations = 10 * pbkdf2Iterations
+)
+
+// ErrDecrypt is returned if the secrets cannot be decrypted with the given key
+var ErrDecrypt = errors.New("cannot decrypt secrets, wrong key or passphrase")
+
+// KeySource gives the key of an encrypted secrets file, either a key file or a passphrase
+type KeySource struct {
+	KeyFile    string
+	Passphrase string
+}
+
+// KeySourceFromEnv takes the key file or passphrase from the env variables if the key file is empty
+func KeySourceFromEnv(keyFile string) KeySource {
+	if keyFile != "" {
+		return KeySource{KeyFile: keyFile}
+	}
+
+	if keyFile = os.Getenv(KeyFileEnv); keyFile != "" {
+		return KeySource{KeyFile: keyFile}
+	}
+
+	return KeySource{Passphrase: os.Getenv(PassphraseEnv)}
+}
+
+// encryptedFile is the JSON envelope of the encrypted secrets
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       string `json:"salt,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// GenerateKeyFile writes a new random key, an existing file is not overwritten
+func GenerateKeyFile(path string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Encrypt encrypts the plain secrets with AES-256-GCM
+func Encrypt(plaintext []byte, keySource KeySource) ([]byte, error) {
+	return encrypt(plaintext, keySource, pbkdf2Iterations)
+}
+
+// encrypt encrypts the plain secrets, a passphrase key is derived with the iterations
+func encrypt(plaintext []byte, keySource KeySource, iterations int) ([]byte, error) {
+	envelope := encryptedFile{Version: fileVersion}
+
+	var key []byte
+	var err error
+	if keySource.KeyFile != "" {
+		envelope.KDF = kdfKeyFile
+		key, err = readKeyFile(keySource.KeyFile)
+	} else {
+		salt := make([]byte, saltSize)
+		if _, err = rand.Read(salt); err != nil {
+			return nil, err
+		}
+		envelope.KDF = kdfPBKDF2
+		envelope.Iterations = iterations
+		envelope.Salt = base64.StdEncoding.EncodeToString(salt)
+		key, err = passphraseKey(keySource.Passphrase, salt, envelope.Iterations)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	envelope.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	envelope.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, additionalData(envelope)))
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+// Decrypt gives the plain secrets of data created by Encrypt
+func Decrypt(data []byte, keySource KeySource) ([]byte, error) {
+	envelope := encryptedFile{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+
+	if envelope.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d", envelope.Version)
+	}
+
+	var key []byte
+	var err error
+	switch envelope.KDF {
+	case kdfKeyFile:
+		if keySource.KeyFile == "" {
+			return nil, fmt.Errorf("the secrets are encrypted with a key file, set %s or pass the key file", KeyFileEnv)
+		}
+		key, err = readKeyFile(keySource.KeyFile)
+	case kdfPBKDF2:
+		var salt []byte
+		salt, err = base64.StdEncoding.DecodeString(envelope.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secrets file salt: %w", err)
+		}
+		key, err = passphraseKey(keySource.Passphrase, salt, envelope.Iterations)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation '%s' of the secrets file", envelope.KDF)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(envelope.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets file nonce: %w", err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets file ciphertext: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid secrets file nonce size %d", len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData(envelope))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// LoadFile decrypts a YAML file of secret names and values
+func LoadFile(path string, keySource KeySource) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := Decrypt(data, keySource)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file '%s': %w", path, err)
+	}
+
+	values, err := ParseValues(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file '%s': %w", path, err)
+	}
+
+	return values, nil
+}
+
+// ParseValues parses the plain YAML map of secret names and scalar values
+func ParseValues(plaintext []byte) (map[string]string, error) {
+	rawValues := map[string]interface{}{}
+	if err := yaml.Unmarshal(plaintext, &rawValues); err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+
+	values := make(map[string]string, len(rawValues))
+	for name, rawVal := range rawValues {
+		switch rawVal.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return nil, fmt.Errorf("the value of the secret '%s' must be a scalar", name)
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(rawVal)
+		}
+	}
+
+	return values, nil
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid secrets key file '%s'", path)
+	}
+
+	return key, nil
+}
+
+func passphraseKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("no key file or passphrase given, set %s or %s", KeyFileEnv, PassphraseEnv)
+	}
+
+	if iterations <= 0 || iterations > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("invalid key derivation iterations %d", iterations)
+	}
+
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, keySize, sha256.New), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the key derivation settings to the ciphertext
+func additionalData(envelope encryptedFile) []byte {
+	return []byte(fmt.Sprintf("taco-secrets:%d:%s:%d:%s", envelope.Version, envelope.KDF, envelope.Iterations, envelope.Salt))
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIterations keeps the tests fast, the work factor is stored in the encrypted files
+const testIterations = 1000
+
+func TestEncryptDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "secrets.key")
+	require.NoError(t, GenerateKeyFile(keyFile))
+	assert.Error(t, GenerateKeyFile(keyFile), "existing keys must not be overwritten")
+
+	otherKeyFile := filepath.Join(dir, "other.key")
+	require.NoError(t, GenerateKeyFile(otherKeyFile))
+
+	plaintext := []byte("db_password: bunny\n")
+
+	testCases := []struct {
+		name       string
+		keySource  KeySource
+		wrongKey   KeySource
+		decryptErr string
+	}{
+		{
+			name:      "key file",
+			keySource: KeySource{KeyFile: keyFile},
+			wrongKey:  KeySource{KeyFile: otherKeyFile},
+		},
+		{
+			name:      "passphrase",
+			keySource: KeySource{Passphrase: "correct horse"},
+			wrongKey:  KeySource{Passphrase: "battery staple"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			encrypted, err := encrypt(plaintext, tc.keySource, testIterations)
+			require.NoError(t, err)
+			assert.NotContains(t, string(encrypted), "bunny")
+
+			decrypted, err := Decrypt(encrypted, tc.keySource)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted)
+
+			_, err = Decrypt(encrypted, tc.wrongKey)
+			assert.True(t, errors.Is(err, ErrDecrypt), err)
+		})
+	}
+
+	_, err := encrypt(plaintext, KeySource{}, testIterations)
+	assert.Error(t, err)
+
+	encrypted, err := Encrypt(plaintext, KeySource{KeyFile: keyFile})
+	require.NoError(t, err)
+	_, err = Decrypt(encrypted, KeySource{Passphrase: "correct horse"})
+	assert.Error(t, err)
+}
+
+func TestDecryptIterationsLimit(t *testing.T) {
+	keySource := KeySource{Passphrase: "correct horse"}
+	encrypted, err := encrypt([]byte("db_password: bunny\n"), keySource, testIterations)
+	require.NoError(t, err)
+
+	envelope := encryptedFile{}
+	require.NoError(t, json.Unmarshal(encrypted, &envelope))
+	envelope.Iterations = maxPBKDF2Iterations + 1
+	crafted, err := json.Marshal(envelope)
+	require.NoError(t, err)
+
+	_, err = Decrypt(crafted, keySource)
+	assert.EqualError(t, err, fmt.Sprintf("invalid key derivation iterations %d", maxPBKDF2Iterations+1))
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	keySource := KeySource{Passphrase: "correct horse"}
+
+	encrypted, err := encrypt([]byte("db_password: loaded-secret\nport: 5432\n"), keySource, testIterations)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "secrets.enc")
+	require.NoError(t, os.WriteFile(path, encrypted, 0600))
+
+	values, err := LoadFile(path, keySource)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"db_password": "loaded-secret", "port": "5432"}, values)
+
+	_, err = ParseValues([]byte("db:\n  password: bunny\n"))
+	assert.EqualError(t, err, "the value of the secret 'db' must be a scalar")
+}
+
+func TestKeySourceFromEnv(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "from env")
+	assert.Equal(t, KeySource{Passphrase: "from env"}, KeySourceFromEnv(""))
+	assert.Equal(t, KeySource{KeyFile: "given.key"}, KeySourceFromEnv("given.key"))
+
+	t.Setenv(KeyFileEnv, "env.key")
+	assert.Equal(t, KeySource{KeyFile: "env.key"}, KeySourceFromEnv(""))
+}
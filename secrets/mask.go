@@ -0,0 +1,222 @@
+package secrets
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const (
+	// Mask replaces the secret values in all output
+	Mask = "*****"
+	// MinLength is the minimal length of a masked value, masking shorter values would make the output unreadable
+	MinLength = 3
+	// DefaultEnvNamePattern matches the names of the env variables which values are secrets
+	DefaultEnvNamePattern = `(?i)(password|passwd|secret|token|api_?key|private_?key|credential)`
+)
+
+// Masker replaces the registered secret values in strings, it's safe for concurrent use. Each script run has
+// its own masker, a nil masker ignores the registered values.
+type Masker struct {
+	mu       sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+// Register adds a secret value, the values shorter than MinLength are ignored
+func (m *Masker) Register(value string) {
+	if m == nil || len(value) < MinLength {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.values {
+		if existing == value {
+			return
+		}
+	}
+	m.values = append(m.values, value)
+
+	// the longer values go first, so a secret containing another one is masked completely
+	sort.Slice(m.values, func(i, j int) bool {
+		return len(m.values[i]) > len(m.values[j])
+	})
+
+	oldnew := make([]string, 0, len(m.values)*2)
+	for _, v := range m.values {
+		oldnew = append(oldnew, v, Mask)
+	}
+	m.replacer = strings.NewReplacer(oldnew...)
+}
+
+// Mask replaces all registered values in the string
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.replacer == nil {
+		return s
+	}
+
+	return m.replacer.Replace(s)
+}
+
+// MaskValue replaces the registered values in the strings of a result value, maps and slices are masked recursively
+func (m *Masker) MaskValue(value interface{}) interface{} {
+	return maskValue(value, m.Mask)
+}
+
+// MaskError gives an error with the masked message of the error, errors.Is and errors.As still see the original error
+func (m *Masker) MaskError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := m.Mask(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+
+	return maskedError{err: err, msg: msg}
+}
+
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (me maskedError) Error() string {
+	return me.msg
+}
+
+func (me maskedError) Unwrap() error {
+	return me.err
+}
+
+// activeMaskers are the maskers of the running scripts, the output which doesn't belong to a run like the log
+// is masked with all of them
+var activeMaskers = struct {
+	sync.RWMutex
+	maskers map[*Masker]struct{}
+}{maskers: map[*Masker]struct{}{}}
+
+// Activate masks the values of the masker in all output of the process until the returned function is called,
+// the values of a finished run are not kept
+func Activate(m *Masker) (deactivate func()) {
+	activeMaskers.Lock()
+	activeMaskers.maskers[m] = struct{}{}
+	activeMaskers.Unlock()
+
+	return func() {
+		activeMaskers.Lock()
+		delete(activeMaskers.maskers, m)
+		activeMaskers.Unlock()
+	}
+}
+
+// MaskString replaces the values of all active maskers in the string
+func MaskString(s string) string {
+	activeMaskers.RLock()
+	defer activeMaskers.RUnlock()
+
+	for m := range activeMaskers.maskers {
+		s = m.Mask(s)
+	}
+
+	return s
+}
+
+// MaskValue replaces the values of all active maskers in the strings of a result value
+func MaskValue(value interface{}) interface{} {
+	return maskValue(value, MaskString)
+}
+
+func maskValue(value interface{}, mask func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return mask(v)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[key] = maskValue(val, mask)
+		}
+		return res
+	case map[string]string:
+		res := make(map[string]string, len(v))
+		for key, val := range v {
+			res[key] = mask(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			res[i] = maskValue(val, mask)
+		}
+		return res
+	case []string:
+		res := make([]string, len(v))
+		for i, val := range v {
+			res[i] = mask(val)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+type maskerContextKey struct{}
+
+// WithMasker gives a context which carries the masker of the run
+func WithMasker(ctx context.Context, m *Masker) context.Context {
+	return context.WithValue(ctx, maskerContextKey{}, m)
+}
+
+// MaskerFromContext gives the masker of the run, nil if the context has none
+func MaskerFromContext(ctx context.Context) *Masker {
+	m, _ := ctx.Value(maskerContextKey{}).(*Masker)
+	return m
+}
+
+// TemplateFuncs gives the secret template function, it registers its argument in the masker and returns it unchanged,
+// e.g. {{ secret .db_password }}
+func TemplateFuncs(m *Masker) template.FuncMap {
+	return template.FuncMap{
+		"secret": func(value string) string {
+			m.Register(value)
+			return value
+		},
+	}
+}
+
+// EnvNameMatcher decides if an env variable holds a secret by its name
+type EnvNameMatcher struct {
+	pattern *regexp.Regexp
+}
+
+// NewEnvNameMatcher compiles the name pattern, DefaultEnvNamePattern is used if it's empty
+func NewEnvNameMatcher(pattern string) (*EnvNameMatcher, error) {
+	if pattern == "" {
+		pattern = DefaultEnvNamePattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnvNameMatcher{pattern: re}, nil
+}
+
+// IsSecret tells if the env variable with the name holds a secret
+func (m *EnvNameMatcher) IsSecret(name string) bool {
+	return m.pattern.MatchString(name)
+}
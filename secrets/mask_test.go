@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasker(t *testing.T) {
+	m := &Masker{}
+	assert.Equal(t, "password bunny", m.Mask("password bunny"))
+
+	m.Register("bunny")
+	m.Register("bunny123")
+	m.Register("ab")
+	m.Register("bunny")
+
+	assert.Equal(t, "password *****, other *****, ab", m.Mask("password bunny123, other bunny, ab"))
+}
+
+func TestMaskValue(t *testing.T) {
+	m := &Masker{}
+	m.Register("s3cr3t-value")
+
+	masked := m.MaskValue(map[string]interface{}{
+		"stdout":  "token=s3cr3t-value",
+		"retcode": 0,
+		"lines":   []interface{}{"s3cr3t-value", 1},
+		"env":     map[string]string{"TOKEN": "s3cr3t-value"},
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"stdout":  "token=*****",
+		"retcode": 0,
+		"lines":   []interface{}{"*****", 1},
+		"env":     map[string]string{"TOKEN": "*****"},
+	}, masked)
+}
+
+func TestActiveMaskers(t *testing.T) {
+	first := &Masker{}
+	first.Register("first-secret")
+	second := &Masker{}
+	second.Register("second-secret")
+
+	deactivateFirst := Activate(first)
+	deactivateSecond := Activate(second)
+	assert.Equal(t, "***** and *****", MaskString("first-secret and second-secret"))
+
+	deactivateFirst()
+	assert.Equal(t, "first-secret and *****", MaskString("first-secret and second-secret"))
+
+	deactivateSecond()
+	assert.Equal(t, "first-secret and second-secret", MaskString("first-secret and second-secret"))
+}
+
+func TestMaskError(t *testing.T) {
+	m := &Masker{}
+	m.Register("s3cr3t-value")
+
+	err := m.MaskError(fmt.Errorf("login failed: %w", ErrDecrypt))
+	assert.Same(t, ErrDecrypt, errors.Unwrap(err))
+
+	err = m.MaskError(fmt.Errorf("login with s3cr3t-value failed: %w", ErrDecrypt))
+	assert.EqualError(t, err, "login with ***** failed: "+ErrDecrypt.Error())
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	assert.NoError(t, m.MaskError(nil))
+}
+
+func TestSecretTemplateFunc(t *testing.T) {
+	m := &Masker{}
+	tmpl, err := template.New("script").Funcs(TemplateFuncs(m)).Parse(`password: {{ secret .password }}`)
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, tmpl.Execute(&buf, map[string]string{"password": "from-template"}))
+
+	assert.Equal(t, "password: from-template", buf.String())
+	assert.Equal(t, "password: *****", m.Mask(buf.String()))
+}
+
+func TestEnvNameMatcher(t *testing.T) {
+	m, err := NewEnvNameMatcher("")
+	require.NoError(t, err)
+
+	assert.True(t, m.IsSecret("DB_PASSWORD"))
+	assert.True(t, m.IsSecret("github_token"))
+	assert.True(t, m.IsSecret("APIKEY"))
+	assert.False(t, m.IsSecret("PATH"))
+
+	m, err = NewEnvNameMatcher("^TACO_")
+	require.NoError(t, err)
+	assert.True(t, m.IsSecret("TACO_LICENSE"))
+	assert.False(t, m.IsSecret("DB_PASSWORD"))
+
+	_, err = NewEnvNameMatcher("(")
+	assert.Error(t, err)
+}
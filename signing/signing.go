@@ -0,0 +1,205 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SignatureExtension is appended to the name of a file to get the name of its detached signature
+	SignatureExtension  = ".sig"
+	PublicKeyExtension  = ".pub"
+	PrivateKeyExtension = ".key"
+)
+
+// ErrInvalidSignature is returned if the signature doesn't match any of the trusted keys
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// GenerateKeyPair writes a new ed25519 key pair to <name>.key and <name>.pub
+func GenerateKeyPair(name string) (privateKeyPath, publicKeyPath string, err error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	privateKeyPath = name + PrivateKeyExtension
+	publicKeyPath = name + PublicKeyExtension
+
+	err = writeNewFile(privateKeyPath, encode(privateKey), 0600)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = writeNewFile(publicKeyPath, encode(publicKey), 0644)
+	if err != nil {
+		return "", "", err
+	}
+
+	return privateKeyPath, publicKeyPath, nil
+}
+
+// ReadPrivateKey reads a private key written by GenerateKeyPair
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	key, err := readEncodedFile(path, ed25519.PrivateKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key '%s': %w", path, err)
+	}
+
+	return key, nil
+}
+
+// ReadPublicKey reads a public key written by GenerateKeyPair
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	key, err := readEncodedFile(path, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key '%s': %w", path, err)
+	}
+
+	return key, nil
+}
+
+// Sign gives the encoded detached signature of the data
+func Sign(key ed25519.PrivateKey, data []byte) []byte {
+	return encode(ed25519.Sign(key, data))
+}
+
+// SignFile writes the detached signature of the file to the file name with the signature extension
+func SignFile(key ed25519.PrivateKey, path string) (signaturePath string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	signaturePath = path + SignatureExtension
+	//nolint:gosec // the signature is public
+	err = os.WriteFile(signaturePath, Sign(key, data), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return signaturePath, nil
+}
+
+// Verifier checks detached signatures against a set of trusted public keys
+type Verifier struct {
+	Keys []ed25519.PublicKey
+}
+
+// NewVerifier creates a verifier trusting the given public key files and all *.pub files in the trusted keys directory
+func NewVerifier(keyPaths []string, trustedKeysDir string) (*Verifier, error) {
+	if trustedKeysDir != "" {
+		dirKeyPaths, err := filepath.Glob(filepath.Join(trustedKeysDir, "*"+PublicKeyExtension))
+		if err != nil {
+			return nil, err
+		}
+		keyPaths = append(keyPaths, dirKeyPaths...)
+	}
+
+	if len(keyPaths) == 0 {
+		return nil, fmt.Errorf("no trusted public keys found")
+	}
+
+	v := &Verifier{}
+	for _, keyPath := range keyPaths {
+		key, err := ReadPublicKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		logrus.Debugf("trusting public key '%s'", keyPath)
+		v.Keys = append(v.Keys, key)
+	}
+
+	return v, nil
+}
+
+// Verify checks that the encoded signature of the data was created by one of the trusted keys
+func (v *Verifier) Verify(data, signature []byte) error {
+	sig, err := decode(signature, ed25519.SignatureSize)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	for _, key := range v.Keys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no trusted key matches the signature", ErrInvalidSignature)
+}
+
+// VerifyFile checks the file against its detached signature in the file name with the signature extension
+func (v *Verifier) VerifyFile(path string) error {
+	_, err := v.ReadVerifiedFile(path)
+	return err
+}
+
+// ReadVerifiedFile reads the file once and gives its contents if they match the detached signature,
+// the caller should use the returned contents since the file might change after the verification
+func (v *Verifier) ReadVerifiedFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := os.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return nil, fmt.Errorf("missing signature of '%s': %w", path, err)
+	}
+
+	err = v.Verify(data, signature)
+	if err != nil {
+		return nil, fmt.Errorf("file '%s': %w", path, err)
+	}
+
+	return data, nil
+}
+
+func encode(data []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(data) + "\n")
+}
+
+func decode(data []byte, expectedSize int) ([]byte, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decoded) != expectedSize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", expectedSize, len(decoded))
+	}
+
+	return decoded, nil
+}
+
+func readEncodedFile(path string, expectedSize int) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data, expectedSize)
+}
+
+// writeNewFile doesn't overwrite existing keys
+func writeNewFile(path string, data []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,78 @@
+package signing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	privateKeyPath, publicKeyPath, err := GenerateKeyPair(filepath.Join(dir, "release"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "release.key"), privateKeyPath)
+	assert.Equal(t, filepath.Join(dir, "release.pub"), publicKeyPath)
+
+	_, _, err = GenerateKeyPair(filepath.Join(dir, "release"))
+	assert.Error(t, err, "existing keys must not be overwritten")
+
+	_, _, err = GenerateKeyPair(filepath.Join(dir, "other"))
+	require.NoError(t, err)
+
+	privateKey, err := ReadPrivateKey(privateKeyPath)
+	require.NoError(t, err)
+
+	scriptPath := filepath.Join(dir, "site.yaml")
+	require.NoError(t, os.WriteFile(scriptPath, []byte("script"), 0600))
+
+	signaturePath, err := SignFile(privateKey, scriptPath)
+	require.NoError(t, err)
+	assert.Equal(t, scriptPath+SignatureExtension, signaturePath)
+
+	verifier, err := NewVerifier([]string{publicKeyPath}, "")
+	require.NoError(t, err)
+	assert.NoError(t, verifier.VerifyFile(scriptPath))
+
+	verifier, err = NewVerifier(nil, dir)
+	require.NoError(t, err)
+	assert.Len(t, verifier.Keys, 2)
+	assert.NoError(t, verifier.VerifyFile(scriptPath))
+
+	require.NoError(t, os.WriteFile(scriptPath, []byte("tampered script"), 0600))
+	err = verifier.VerifyFile(scriptPath)
+	assert.True(t, errors.Is(err, ErrInvalidSignature), err)
+
+	otherVerifier, err := NewVerifier([]string{filepath.Join(dir, "other.pub")}, "")
+	require.NoError(t, err)
+	err = otherVerifier.Verify([]byte("script"), Sign(privateKey, []byte("script")))
+	assert.True(t, errors.Is(err, ErrInvalidSignature), err)
+}
+
+func TestVerifyInvalidSignature(t *testing.T) {
+	dir := t.TempDir()
+	_, publicKeyPath, err := GenerateKeyPair(filepath.Join(dir, "release"))
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier([]string{publicKeyPath}, "")
+	require.NoError(t, err)
+
+	err = verifier.Verify([]byte("script"), []byte("not base64"))
+	assert.True(t, errors.Is(err, ErrInvalidSignature), err)
+
+	scriptPath := filepath.Join(dir, "unsigned.yaml")
+	require.NoError(t, os.WriteFile(scriptPath, []byte("script"), 0600))
+	assert.Error(t, verifier.VerifyFile(scriptPath))
+}
+
+func TestNewVerifierWithoutKeys(t *testing.T) {
+	_, err := NewVerifier(nil, t.TempDir())
+	assert.EqualError(t, err, "no trusted public keys found")
+
+	_, err = NewVerifier([]string{filepath.Join(t.TempDir(), "missing.pub")}, "")
+	assert.Error(t, err)
+}
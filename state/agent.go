@@ -0,0 +1,81 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+const (
+	agentStatusFile = "agent-status.json"
+
+	// RunSkipped is the status of an agent run which was skipped because another process held the run lock
+	RunSkipped = "skipped"
+)
+
+// ErrNoAgentStatus is returned if no agent has written its status to the state directory
+var ErrNoAgentStatus = errors.New("no agent status")
+
+// AgentStatus is the status of the agent which applies a script periodically
+type AgentStatus struct {
+	PID          int       `json:"pid"`
+	Script       string    `json:"script"`
+	ScriptSHA256 string    `json:"scriptSha256,omitempty"`
+	Interval     string    `json:"interval"`
+	Splay        string    `json:"splay"`
+	LastRun      *AgentRun `json:"lastRun,omitempty"`
+	NextRun      time.Time `json:"nextRun"`
+	// the agent is stopped
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// AgentRun is the outcome of the last run of the agent
+type AgentRun struct {
+	// the id of the run in the history, it's empty if the run was skipped or the script could not be run
+	ID        string    `json:"id,omitempty"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Changes   int       `json:"changes"`
+}
+
+// SaveAgentStatus writes the status of the agent to the state directory
+func SaveAgentStatus(stateDir string, status AgentStatus) error {
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(filepath.Join(stateDir, agentStatusFile), data, 0600)
+}
+
+// LoadAgentStatus reads the status of the agent from the state directory
+func LoadAgentStatus(stateDir string) (AgentStatus, error) {
+	status := AgentStatus{}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, agentStatusFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return status, ErrNoAgentStatus
+	}
+	if err != nil {
+		return status, err
+	}
+
+	if err = json.Unmarshal(data, &status); err != nil {
+		return status, fmt.Errorf("invalid agent status: %w", err)
+	}
+
+	return status, nil
+}
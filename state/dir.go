@@ -0,0 +1,16 @@
+package state
+
+import "os"
+
+// DirEnv is the env variable with the state directory, it overrides the platform default
+const DirEnv = "TACO_STATE_DIR"
+
+// DefaultDir gives the directory where tacoscript keeps the state between runs,
+// /var/lib/tacoscript or %ProgramData%\tacoscript on Windows unless DirEnv is set
+func DefaultDir() string {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir
+	}
+
+	return defaultDir()
+}
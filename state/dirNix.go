@@ -0,0 +1,8 @@
+//go:build !windows
+// +build !windows
+
+package state
+
+func defaultDir() string {
+	return "/var/lib/tacoscript"
+}
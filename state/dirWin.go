@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func defaultDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+
+	return filepath.Join(programData, "tacoscript")
+}
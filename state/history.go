@@ -0,0 +1,204 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+const (
+	// DefaultHistoryKeep is the number of runs kept in the history if nothing else is configured
+	DefaultHistoryKeep = 100
+
+	// RunSucceeded is the status of a run where all tasks succeeded
+	RunSucceeded = "succeeded"
+	// RunFailed is the status of a run where tasks failed or were aborted
+	RunFailed = "failed"
+	// RunError is the status of a run where the script could not be built or executed
+	RunError = "error"
+
+	historyDir    = "history"
+	journalDir    = "journal"
+	recordExt     = ".json"
+	runIDTimeForm = "20060102-150405.000"
+)
+
+// ErrRunNotFound is returned if the history has no run with the given id
+var ErrRunNotFound = errors.New("run not found")
+
+var runIDRegex = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}\.[0-9]{3}-[0-9a-f]+$`)
+
+// Record is a single script run stored in the history
+type Record struct {
+	ID           string `json:"id"`
+	Script       string `json:"script"`
+	ScriptSHA256 string `json:"scriptSha256,omitempty"`
+	// the script after rendering the templates, secrets are masked
+	RenderedScript string    `json:"renderedScript,omitempty"`
+	Started        time.Time `json:"started"`
+	Finished       time.Time `json:"finished"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error,omitempty"`
+	// the full result of the run as written by taco exec
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Duration gives the run time of the record
+func (r Record) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+// History stores the records of the script runs as JSON files in the state directory
+type History struct {
+	StateDir string
+	// the number of records kept after saving a new one, all records are kept if it's not positive
+	Keep int
+}
+
+// NewHistory gives the history of the state directory
+func NewHistory(stateDir string, keep int) *History {
+	return &History{
+		StateDir: stateDir,
+		Keep:     keep,
+	}
+}
+
+// Journal gives the journal of the file changes of the run
+func (h *History) Journal(runID string) *Journal {
+	return &Journal{Dir: filepath.Join(h.StateDir, journalDir, runID)}
+}
+
+// NewRunID gives a unique run id starting with the UTC start time, so the ids sort by the start time
+func NewRunID(started time.Time) string {
+	random := make([]byte, 3)
+	_, _ = rand.Read(random)
+
+	return started.UTC().Format(runIDTimeForm) + "-" + hex.EncodeToString(random)
+}
+
+// Save writes the record and removes the oldest records exceeding the retention
+func (h *History) Save(record Record) error {
+	if !runIDRegex.MatchString(record.ID) {
+		return fmt.Errorf("invalid run id '%s'", record.ID)
+	}
+
+	if err := os.MkdirAll(h.dir(), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = utils.WriteFileAtomic(h.path(record.ID), data, 0600); err != nil {
+		return err
+	}
+
+	return h.prune()
+}
+
+// Get reads the record of the run
+func (h *History) Get(runID string) (Record, error) {
+	record := Record{}
+	if !runIDRegex.MatchString(runID) {
+		return record, fmt.Errorf("%w: invalid run id '%s'", ErrRunNotFound, runID)
+	}
+
+	data, err := os.ReadFile(h.path(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return record, fmt.Errorf("%w: '%s'", ErrRunNotFound, runID)
+	}
+	if err != nil {
+		return record, err
+	}
+
+	if err = json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("invalid history record '%s': %w", runID, err)
+	}
+
+	return record, nil
+}
+
+// List reads all records, the latest run comes first
+func (h *History) List() ([]Record, error) {
+	runIDs, err := h.runIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(runIDs))
+	for i := len(runIDs) - 1; i >= 0; i-- {
+		record, err := h.Get(runIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// runIDs gives the ids of all stored runs sorted by the start time
+func (h *History) runIDs() ([]string, error) {
+	entries, err := os.ReadDir(h.dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	runIDs := []string{}
+	for _, entry := range entries {
+		runID := strings.TrimSuffix(entry.Name(), recordExt)
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordExt) || !runIDRegex.MatchString(runID) {
+			continue
+		}
+		runIDs = append(runIDs, runID)
+	}
+	sort.Strings(runIDs)
+
+	return runIDs, nil
+}
+
+func (h *History) prune() error {
+	if h.Keep <= 0 {
+		return nil
+	}
+
+	runIDs, err := h.runIDs()
+	if err != nil {
+		return err
+	}
+
+	for len(runIDs) > h.Keep {
+		if err = os.RemoveAll(h.Journal(runIDs[0]).Dir); err != nil {
+			return err
+		}
+		if err = os.Remove(h.path(runIDs[0])); err != nil {
+			return err
+		}
+		runIDs = runIDs[1:]
+	}
+
+	return nil
+}
+
+func (h *History) dir() string {
+	return filepath.Join(h.StateDir, historyDir)
+}
+
+func (h *History) path(runID string) string {
+	return filepath.Join(h.dir(), runID+recordExt)
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	stateDir := t.TempDir()
+	history := NewHistory(stateDir, 2)
+
+	records, err := history.List()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	started := time.Date(2022, 5, 17, 10, 0, 0, 0, time.UTC)
+	runIDs := []string{}
+	for i := 0; i < 3; i++ {
+		record := Record{
+			ID:             NewRunID(started.Add(time.Duration(i) * time.Minute)),
+			Script:         "script.yaml",
+			ScriptSHA256:   "abc",
+			RenderedScript: "task:\n",
+			Started:        started.Add(time.Duration(i) * time.Minute),
+			Finished:       started.Add(time.Duration(i)*time.Minute + time.Second),
+			Status:         RunSucceeded,
+			Result:         []byte(`{"Summary":{"Succeeded":1}}`),
+		}
+		require.NoError(t, history.Save(record))
+		runIDs = append(runIDs, record.ID)
+	}
+	assert.Regexp(t, `^20220517-100000\.000-[0-9a-f]{6}$`, runIDs[0])
+
+	records, err = history.List()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, runIDs[2], records[0].ID)
+	assert.Equal(t, runIDs[1], records[1].ID)
+	assert.Equal(t, time.Second, records[0].Duration())
+	assert.JSONEq(t, `{"Summary":{"Succeeded":1}}`, string(records[0].Result))
+
+	_, err = history.Get(runIDs[0])
+	assert.True(t, errors.Is(err, ErrRunNotFound), err)
+
+	_, err = history.Get("../../etc/passwd")
+	assert.True(t, errors.Is(err, ErrRunNotFound), err)
+
+	info, err := os.Stat(filepath.Join(stateDir, "history", runIDs[2]+".json"))
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	assert.Error(t, history.Save(Record{ID: "invalid"}))
+}
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv(DirEnv, "/opt/taco-state")
+	assert.Equal(t, "/opt/taco-state", DefaultDir())
+
+	t.Setenv(DirEnv, "")
+	assert.NotEmpty(t, DefaultDir())
+}
+
+func TestHistoryPrunesJournals(t *testing.T) {
+	history := NewHistory(t.TempDir(), 1)
+	started := time.Date(2022, 5, 17, 10, 0, 0, 0, time.UTC)
+
+	oldRunID := NewRunID(started)
+	require.NoError(t, os.MkdirAll(history.Journal(oldRunID).Dir, 0700))
+	require.NoError(t, history.Save(Record{ID: oldRunID, Started: started, Status: RunSucceeded}))
+	require.NoError(t, history.Save(Record{ID: NewRunID(started.Add(time.Minute)), Status: RunSucceeded}))
+
+	assert.NoDirExists(t, history.Journal(oldRunID).Dir)
+}
@@ -0,0 +1,244 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+const journalFile = "journal.json"
+
+// ErrNoJournal is returned if a run has no recorded file changes
+var ErrNoJournal = errors.New("the run has no recorded file changes")
+
+// FileState is the content checksum, mode and ownership of a file at a point in time
+type FileState struct {
+	Exists bool        `json:"exists"`
+	SHA256 string      `json:"sha256,omitempty"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+	// the owner ids are -1 on Windows
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+}
+
+// JournalEntry is a file modification of a task with the file states before and after the task
+type JournalEntry struct {
+	Path     string    `json:"path"`
+	TaskPath string    `json:"task"`
+	Before   FileState `json:"before"`
+	After    FileState `json:"after"`
+	// the file name of the content before the task in the journal directory, empty if the file didn't exist
+	Snapshot string `json:"snapshot,omitempty"`
+	// the file was restored by a rollback which failed on another file, a retry of the rollback skips it
+	Restored bool `json:"restored,omitempty"`
+}
+
+// Journal records the file modifications of a run with snapshots of the previous content,
+// so the modifications can be rolled back. All methods of a nil journal do nothing.
+type Journal struct {
+	Dir        string         `json:"-"`
+	Entries    []JournalEntry `json:"entries"`
+	RolledBack *time.Time     `json:"rolledBack,omitempty"`
+
+	mu            sync.Mutex
+	pending       map[string]pendingSnapshot
+	snapshotCount int
+}
+
+type pendingSnapshot struct {
+	state FileState
+	// the file name of the content copy in the journal directory, empty if the file didn't exist
+	snapshot string
+}
+
+type journalContextKey struct{}
+
+// WithJournal gives a context which makes the journal available to the task executors
+func WithJournal(ctx context.Context, journal *Journal) context.Context {
+	return context.WithValue(ctx, journalContextKey{}, journal)
+}
+
+// JournalFromContext gives the journal of the context or nil if there is none
+func JournalFromContext(ctx context.Context) *Journal {
+	journal, _ := ctx.Value(journalContextKey{}).(*Journal)
+	return journal
+}
+
+// Snapshot copies the current state and content of the file to the journal directory,
+// it must be called before a task modifies the file
+func (j *Journal) Snapshot(path string) error {
+	if j == nil {
+		return nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err = os.MkdirAll(j.Dir, 0700); err != nil {
+		return err
+	}
+
+	j.snapshotCount++
+	snapshot := fmt.Sprintf("%06d.snapshot", j.snapshotCount)
+
+	state, err := readFileState(absPath, filepath.Join(j.Dir, snapshot))
+	if err != nil {
+		return fmt.Errorf("cannot snapshot '%s' for the rollback: %w", path, err)
+	}
+	if !state.Exists {
+		snapshot = ""
+	}
+
+	if j.pending == nil {
+		j.pending = map[string]pendingSnapshot{}
+	}
+	j.pending[absPath] = pendingSnapshot{state: state, snapshot: snapshot}
+
+	return nil
+}
+
+// Record compares the file with its snapshot and writes a journal entry if the task modified the file,
+// otherwise the snapshot is removed
+func (j *Journal) Record(taskPath, path string) error {
+	if j == nil {
+		return nil
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pending, ok := j.pending[absPath]
+	if !ok {
+		return fmt.Errorf("no snapshot of '%s'", path)
+	}
+	delete(j.pending, absPath)
+
+	after, err := readFileState(absPath, "")
+	if err != nil {
+		return err
+	}
+
+	if after == pending.state {
+		if pending.snapshot != "" {
+			if err = os.Remove(filepath.Join(j.Dir, pending.snapshot)); err != nil {
+				return err
+			}
+		}
+		if len(j.Entries) == 0 && len(j.pending) == 0 {
+			// the directory of a run without file changes is removed
+			_ = os.Remove(j.Dir)
+		}
+		return nil
+	}
+
+	j.Entries = append(j.Entries, JournalEntry{
+		Path:     absPath,
+		TaskPath: taskPath,
+		Before:   pending.state,
+		After:    after,
+		Snapshot: pending.snapshot,
+	})
+
+	return j.save()
+}
+
+// RecordOrLog records the file modification and logs a failure, it's used after a task has already modified the file
+func (j *Journal) RecordOrLog(taskPath, path string) {
+	if err := j.Record(taskPath, path); err != nil {
+		logrus.Errorf("cannot record the changes of '%s' for the rollback: %v", path, err)
+	}
+}
+
+// LoadJournal reads the journal from the directory
+func LoadJournal(dir string) (*Journal, error) {
+	data, err := os.ReadFile(filepath.Join(dir, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoJournal
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{Dir: dir}
+	if err = json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("invalid journal '%s': %w", dir, err)
+	}
+
+	return journal, nil
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(filepath.Join(j.Dir, journalFile), data, 0600)
+}
+
+// readFileState gives the state of the file and copies its content to the snapshot path if it's not empty,
+// a missing file is not an error
+func readFileState(path, snapshotPath string) (FileState, error) {
+	state := FileState{UID: -1, GID: -1}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return state, fmt.Errorf("'%s' is not a regular file", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return state, err
+	}
+	defer utils.CloseResourceSecure(path, f)
+
+	hash := sha256.New()
+	var w io.Writer = hash
+	if snapshotPath != "" {
+		snapshotFile, err := os.OpenFile(snapshotPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return state, err
+		}
+		defer utils.CloseResourceSecure(snapshotPath, snapshotFile)
+		w = io.MultiWriter(hash, snapshotFile)
+	}
+
+	if _, err = io.Copy(w, f); err != nil {
+		return state, err
+	}
+
+	state.Exists = true
+	state.SHA256 = fmt.Sprintf("%x", hash.Sum(nil))
+	state.Mode = info.Mode().Perm()
+	state.UID, state.GID = fileOwner(info)
+
+	return state, nil
+}
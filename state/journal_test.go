@@ -0,0 +1,148 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalRollback(t *testing.T) {
+	dir := t.TempDir()
+	history := NewHistory(filepath.Join(dir, "state"), 0)
+	runID := NewRunID(time.Now())
+
+	changedFile := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(changedFile, []byte("port=80\n"), 0640))
+	createdFile := filepath.Join(dir, "new.conf")
+	unchangedFile := filepath.Join(dir, "unchanged.conf")
+	require.NoError(t, os.WriteFile(unchangedFile, []byte("keep\n"), 0644))
+
+	journal := JournalFromContext(WithJournal(context.Background(), history.Journal(runID)))
+	require.NotNil(t, journal)
+
+	require.NoError(t, journal.Snapshot(changedFile))
+	require.NoError(t, os.WriteFile(changedFile, []byte("port=8080\n"), 0640))
+	require.NoError(t, os.Chmod(changedFile, 0600))
+	require.NoError(t, journal.Record("first.file.managed[1]", changedFile))
+
+	require.NoError(t, journal.Snapshot(changedFile))
+	require.NoError(t, os.WriteFile(changedFile, []byte("port=8443\n"), 0600))
+	require.NoError(t, journal.Record("second.file.replace[1]", changedFile))
+
+	require.NoError(t, journal.Snapshot(createdFile))
+	require.NoError(t, os.WriteFile(createdFile, []byte("created\n"), 0644))
+	require.NoError(t, journal.Record("create.file.managed[1]", createdFile))
+
+	require.NoError(t, journal.Snapshot(unchangedFile))
+	require.NoError(t, journal.Record("keep.file.managed[1]", unchangedFile))
+
+	loaded, err := LoadJournal(history.Journal(runID).Dir)
+	require.NoError(t, err)
+	require.Len(t, loaded.Entries, 3)
+	assert.Equal(t, "first.file.managed[1]", loaded.Entries[0].TaskPath)
+	assert.False(t, loaded.Entries[2].Before.Exists)
+
+	snapshots, err := filepath.Glob(filepath.Join(loaded.Dir, "*.snapshot"))
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2, "the snapshot of the unchanged file is removed")
+
+	require.NoError(t, os.WriteFile(changedFile, []byte("port=9000\n"), 0600))
+	_, err = loaded.Rollback(false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the files were modified since the run: "+changedFile)
+
+	require.NoError(t, os.WriteFile(changedFile, []byte("port=8443\n"), 0600))
+	rolledBack, err := loaded.Rollback(false)
+	require.NoError(t, err)
+	assert.Equal(t, []RolledBackFile{
+		{Path: changedFile, Action: RollbackRestored},
+		{Path: createdFile, Action: RollbackRemoved},
+	}, rolledBack)
+
+	content, err := os.ReadFile(changedFile)
+	require.NoError(t, err)
+	assert.Equal(t, "port=80\n", string(content))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(changedFile)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	}
+	assert.NoFileExists(t, createdFile)
+
+	loaded, err = LoadJournal(history.Journal(runID).Dir)
+	require.NoError(t, err)
+	_, err = loaded.Rollback(true)
+	assert.EqualError(t, err, "the run was already rolled back at "+loaded.RolledBack.Local().Format(time.RFC3339))
+}
+
+func TestJournalRollbackRetry(t *testing.T) {
+	dir := t.TempDir()
+	history := NewHistory(filepath.Join(dir, "state"), 0)
+	runID := NewRunID(time.Now())
+	journal := history.Journal(runID)
+
+	firstFile := filepath.Join(dir, "first.conf")
+	secondFile := filepath.Join(dir, "second.conf")
+	for _, file := range []string{firstFile, secondFile} {
+		require.NoError(t, os.WriteFile(file, []byte("before\n"), 0644))
+		require.NoError(t, journal.Snapshot(file))
+		require.NoError(t, os.WriteFile(file, []byte("after\n"), 0644))
+		require.NoError(t, journal.Record("file.managed[1]", file))
+	}
+
+	loaded, err := LoadJournal(journal.Dir)
+	require.NoError(t, err)
+
+	// the rollback fails on the second file which snapshot is missing
+	secondSnapshot := filepath.Join(loaded.Dir, loaded.Entries[1].Snapshot)
+	snapshotContent, err := os.ReadFile(secondSnapshot)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(secondSnapshot))
+
+	rolledBack, err := loaded.Rollback(false)
+	assert.ErrorContains(t, err, "cannot read the snapshot of '"+secondFile+"'")
+	assert.Equal(t, []RolledBackFile{{Path: firstFile, Action: RollbackRestored}}, rolledBack)
+
+	require.NoError(t, os.WriteFile(secondSnapshot, snapshotContent, 0600))
+
+	loaded, err = LoadJournal(journal.Dir)
+	require.NoError(t, err)
+	assert.True(t, loaded.Entries[0].Restored)
+	assert.Nil(t, loaded.RolledBack)
+
+	rolledBack, err = loaded.Rollback(false)
+	require.NoError(t, err, "the restored file is skipped instead of being reported as modified")
+	assert.Equal(t, []RolledBackFile{{Path: secondFile, Action: RollbackRestored}}, rolledBack)
+
+	for _, file := range []string{firstFile, secondFile} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, "before\n", string(content))
+	}
+}
+
+func TestJournalWithoutChanges(t *testing.T) {
+	dir := t.TempDir()
+	journal := NewHistory(dir, 0).Journal(NewRunID(time.Now()))
+
+	file := filepath.Join(dir, "app.conf")
+	require.NoError(t, os.WriteFile(file, []byte("port=80\n"), 0644))
+	require.NoError(t, journal.Snapshot(file))
+	require.NoError(t, journal.Record("task", file))
+
+	assert.NoDirExists(t, journal.Dir)
+	_, err := LoadJournal(journal.Dir)
+	assert.True(t, errors.Is(err, ErrNoJournal), err)
+
+	var nilJournal *Journal
+	assert.NoError(t, nilJournal.Snapshot(file))
+	assert.NoError(t, nilJournal.Record("task", file))
+	assert.Nil(t, JournalFromContext(context.Background()))
+}
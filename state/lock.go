@@ -0,0 +1,176 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const lockFile = "run.lock"
+
+// ErrLocked is returned if another process holds the run lock
+var ErrLocked = errors.New("the run lock is taken")
+
+// LockHolder is the process which holds the run lock
+type LockHolder struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+// LockedError tells which process holds the run lock, it wraps ErrLocked
+type LockedError struct {
+	Path   string
+	Holder LockHolder
+}
+
+func (le *LockedError) Error() string {
+	return fmt.Sprintf(
+		"%v: '%s' is held by the process %d since %s",
+		ErrLocked,
+		le.Path,
+		le.Holder.PID,
+		le.Holder.Started.Local().Format(time.RFC3339),
+	)
+}
+
+func (le *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+// RunLock is the exclusive lock of the state directory which prevents concurrent runs
+type RunLock struct {
+	file *os.File
+}
+
+const (
+	lockPollDelay    = 100 * time.Millisecond
+	lockMaxPollDelay = time.Second
+)
+
+// errLockHeld is returned by tryLock if another process holds the lock of the file
+var errLockHeld = errors.New("the file is locked")
+
+// AcquireLock takes the OS lock of the lock file in the state directory and writes the PID and the start time
+// of the current process to it, a LockedError is returned if another process holds the lock. The lock is held
+// on the open file, so the OS releases it if the process dies without releasing it.
+func AcquireLock(stateDir string) (*RunLock, error) {
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(stateDir, lockFile)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tryLock(f)
+	if errors.Is(err, errLockHeld) {
+		_ = f.Close()
+		return nil, lockedError(path)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	lock := &RunLock{file: f}
+	if err = lock.writeHolder(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// WaitLock acquires the run lock like AcquireLock, if another process holds the lock it's retried until the wait
+// duration has passed or the context is cancelled
+func WaitLock(ctx context.Context, stateDir string, wait time.Duration) (*RunLock, error) {
+	deadline := time.Now().Add(wait)
+	delay := lockPollDelay
+
+	for {
+		lock, err := AcquireLock(stateDir)
+		if !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if wait > 0 {
+				return nil, fmt.Errorf("%w, waited %v", err, wait)
+			}
+			return nil, err
+		}
+
+		if delay > remaining {
+			delay = remaining
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w, stopped waiting: %v", err, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > lockMaxPollDelay {
+			delay = lockMaxPollDelay
+		}
+	}
+}
+
+// writeHolder replaces the holder in the lock file with the current process
+func (l *RunLock) writeHolder() error {
+	data, err := json.Marshal(LockHolder{PID: os.Getpid(), Started: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	if err = l.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err = l.file.WriteAt(data, 0)
+
+	return err
+}
+
+// Release clears the holder and closes the lock file, which releases the lock. The file itself is kept,
+// removing it would let a new process lock a new file while a waiting one still locks the removed file.
+func (l *RunLock) Release() error {
+	err := l.file.Truncate(0)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// lockedError reads the holder of the lock file, a lock file which cannot be read is reported without the holder
+func lockedError(path string) error {
+	lockedErr := &LockedError{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: '%s' cannot be read: %v", ErrLocked, path, err)
+	}
+
+	if len(data) == 0 {
+		// the holder has taken the lock but not written itself yet
+		return fmt.Errorf("%w: '%s' is held by another process", ErrLocked, path)
+	}
+
+	if err = json.Unmarshal(data, &lockedErr.Holder); err != nil {
+		return fmt.Errorf("%w: '%s' is invalid: %v", ErrLocked, path, err)
+	}
+
+	return lockedErr
+}
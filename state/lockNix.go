@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package state
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLock takes the exclusive flock of the open file without waiting, errLockHeld is returned if another open file
+// holds it. The kernel releases the lock when the file is closed or the process exits.
+func tryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+
+	return err
+}
@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package state
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffsetHigh places the locked byte at 4 GiB, far behind the holder data, as the locked range cannot be read
+// by other processes
+const lockOffsetHigh = 1
+
+// tryLock takes the exclusive lock of the open file without waiting, errLockHeld is returned if another handle
+// holds it. Windows releases the lock when the handle is closed or the process exits.
+func tryLock(f *os.File) error {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	err := windows.LockFileEx(
+		windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0,
+		1,
+		0,
+		overlapped,
+	)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+
+	return err
+}
@@ -0,0 +1,170 @@
+package state
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLock(t *testing.T) {
+	stateDir := filepath.Join(t.TempDir(), "state")
+
+	lock, err := AcquireLock(stateDir)
+	require.NoError(t, err)
+
+	_, err = AcquireLock(stateDir)
+	require.ErrorIs(t, err, ErrLocked)
+	lockedErr := &LockedError{}
+	require.True(t, errors.As(err, &lockedErr))
+	assert.Equal(t, os.Getpid(), lockedErr.Holder.PID)
+	assert.False(t, lockedErr.Holder.Started.IsZero())
+	assert.Contains(t, err.Error(), fmt.Sprintf("is held by the process %d since", os.Getpid()))
+
+	require.NoError(t, lock.Release())
+
+	lock, err = AcquireLock(stateDir)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+
+	data, err := os.ReadFile(filepath.Join(stateDir, lockFile))
+	require.NoError(t, err)
+	assert.Empty(t, data, "the holder is cleared on release")
+}
+
+func TestRunLockLeftoverLockFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+	}{
+		{
+			name:     "holder with a reused PID",
+			contents: fmt.Sprintf(`{"pid":1,"started":"%s"}`, time.Now().Add(-time.Hour).Format(time.RFC3339)),
+		},
+		{
+			name:     "own PID",
+			contents: fmt.Sprintf(`{"pid":%d,"started":"%s"}`, os.Getpid(), time.Now().Format(time.RFC3339)),
+		},
+		{
+			name:     "invalid holder",
+			contents: "garbage",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(stateDir, lockFile), []byte(tc.contents), 0600))
+
+			// a lock file which no process holds the OS lock of is taken over
+			lock, err := AcquireLock(stateDir)
+			require.NoError(t, err)
+
+			_, err = AcquireLock(stateDir)
+			lockedErr := &LockedError{}
+			require.True(t, errors.As(err, &lockedErr))
+			assert.Equal(t, os.Getpid(), lockedErr.Holder.PID)
+
+			require.NoError(t, lock.Release())
+		})
+	}
+}
+
+func TestRunLockReleasedOnExit(t *testing.T) {
+	stateDir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHolderProcess$")
+	cmd.Env = append(os.Environ(), lockHolderDirEnv+"="+stateDir)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "locked\n", line)
+
+	_, err = AcquireLock(stateDir)
+	lockedErr := &LockedError{}
+	require.True(t, errors.As(err, &lockedErr))
+	assert.Equal(t, cmd.Process.Pid, lockedErr.Holder.PID)
+
+	// the killed process cannot release the lock, the OS does
+	require.NoError(t, cmd.Process.Kill())
+	_ = cmd.Wait()
+
+	lock, err := AcquireLock(stateDir)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+const lockHolderDirEnv = "TACO_TEST_LOCK_HOLDER_DIR"
+
+// TestLockHolderProcess is the process of TestRunLockReleasedOnExit which holds the lock until it's killed
+func TestLockHolderProcess(t *testing.T) {
+	stateDir := os.Getenv(lockHolderDirEnv)
+	if stateDir == "" {
+		t.Skip("runs as the lock holder process of TestRunLockReleasedOnExit only")
+	}
+
+	_, err := AcquireLock(stateDir)
+	require.NoError(t, err)
+
+	fmt.Println("locked")
+	time.Sleep(time.Minute)
+}
+
+func TestWaitLock(t *testing.T) {
+	stateDir := t.TempDir()
+
+	lock, err := AcquireLock(stateDir)
+	require.NoError(t, err)
+
+	_, err = WaitLock(context.Background(), stateDir, 150*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), "waited 150ms")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = WaitLock(ctx, stateDir, time.Minute)
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Contains(t, err.Error(), "stopped waiting")
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		assert.NoError(t, lock.Release())
+	}()
+
+	waitedLock, err := WaitLock(context.Background(), stateDir, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, waitedLock.Release())
+}
+
+func TestAgentStatus(t *testing.T) {
+	stateDir := t.TempDir()
+
+	_, err := LoadAgentStatus(stateDir)
+	assert.ErrorIs(t, err, ErrNoAgentStatus)
+
+	status := AgentStatus{
+		PID:      42,
+		Script:   "script.yaml",
+		Interval: "30m0s",
+		Splay:    "0s",
+		LastRun:  &AgentRun{ID: "20220517-100000.000-abcdef", Status: RunSucceeded, Succeeded: 2, Changes: 1},
+	}
+	require.NoError(t, SaveAgentStatus(stateDir, status))
+
+	loadedStatus, err := LoadAgentStatus(stateDir)
+	require.NoError(t, err)
+	assert.Equal(t, status.LastRun, loadedStatus.LastRun)
+	assert.Equal(t, status.Script, loadedStatus.Script)
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package state
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileOwner(info os.FileInfo) (uid, gid int) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1
+	}
+
+	return int(stat.Uid), int(stat.Gid)
+}
+
+func chownFile(path string, uid, gid int) error {
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+
+	return os.Chown(path, uid, gid)
+}
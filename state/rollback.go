@@ -0,0 +1,140 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RollbackRestored is the action of a rollback which restored the previous content of a file
+	RollbackRestored = "restored"
+	// RollbackRemoved is the action of a rollback which removed a file created by the run
+	RollbackRemoved = "removed"
+)
+
+// RolledBackFile is a file which was restored by a rollback
+type RolledBackFile struct {
+	Path   string `yaml:"Path"`
+	Action string `yaml:"Action"`
+}
+
+// fileChange is the first and the last modification of a file in a run
+type fileChange struct {
+	first JournalEntry
+	last  JournalEntry
+}
+
+// Rollback restores the files modified by the run with their content, mode and ownership before the run.
+// It fails without changing anything if a file was modified since the run unless force is set. If a file cannot be
+// restored, the files restored so far are saved in the journal, so a retry of the rollback skips them.
+func (j *Journal) Rollback(force bool) ([]RolledBackFile, error) {
+	if j.RolledBack != nil {
+		return nil, fmt.Errorf("the run was already rolled back at %s", j.RolledBack.Local().Format(time.RFC3339))
+	}
+
+	changes := []fileChange{}
+	for _, change := range j.fileChanges() {
+		if !change.first.Restored {
+			changes = append(changes, change)
+		}
+	}
+
+	if !force {
+		modified := []string{}
+		for _, change := range changes {
+			current, err := readFileState(change.last.Path, "")
+			if err != nil {
+				return nil, err
+			}
+			if current != change.last.After {
+				modified = append(modified, change.last.Path)
+			}
+		}
+
+		if len(modified) > 0 {
+			return nil, fmt.Errorf(
+				"refusing the rollback, the files were modified since the run: %s, use --force to restore them anyway",
+				strings.Join(modified, ", "),
+			)
+		}
+	}
+
+	rolledBack := make([]RolledBackFile, 0, len(changes))
+	for _, change := range changes {
+		rolledBackFile, err := j.restore(change.first)
+		if err != nil {
+			if saveErr := j.save(); saveErr != nil {
+				logrus.Errorf("cannot save the progress of the rollback: %v", saveErr)
+			}
+			return rolledBack, err
+		}
+		j.markRestored(change.first.Path)
+		rolledBack = append(rolledBack, rolledBackFile)
+	}
+
+	now := time.Now()
+	j.RolledBack = &now
+
+	return rolledBack, j.save()
+}
+
+// fileChanges gives the changes per file in the order of the first modification
+func (j *Journal) fileChanges() []fileChange {
+	changes := []fileChange{}
+	indexes := map[string]int{}
+	for _, entry := range j.Entries {
+		if i, ok := indexes[entry.Path]; ok {
+			changes[i].last = entry
+			continue
+		}
+		indexes[entry.Path] = len(changes)
+		changes = append(changes, fileChange{first: entry, last: entry})
+	}
+
+	return changes
+}
+
+// markRestored marks all entries of the file as restored
+func (j *Journal) markRestored(path string) {
+	for i := range j.Entries {
+		if j.Entries[i].Path == path {
+			j.Entries[i].Restored = true
+		}
+	}
+}
+
+// restore brings the file back to the state before the journal entry
+func (j *Journal) restore(entry JournalEntry) (RolledBackFile, error) {
+	if !entry.Before.Exists {
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			return RolledBackFile{}, err
+		}
+
+		return RolledBackFile{Path: entry.Path, Action: RollbackRemoved}, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(j.Dir, entry.Snapshot))
+	if err != nil {
+		return RolledBackFile{}, fmt.Errorf("cannot read the snapshot of '%s': %w", entry.Path, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return RolledBackFile{}, err
+	}
+
+	if err = utils.WriteFileAtomic(entry.Path, content, entry.Before.Mode); err != nil {
+		return RolledBackFile{}, err
+	}
+
+	if err = chownFile(entry.Path, entry.Before.UID, entry.Before.GID); err != nil {
+		return RolledBackFile{}, err
+	}
+
+	return RolledBackFile{Path: entry.Path, Action: RollbackRestored}, nil
+}
@@ -3,11 +3,15 @@ package cmdrun
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"time"
 
 	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	tacoio "github.com/realvnc-labs/tacoscript/io"
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
@@ -25,6 +29,8 @@ const (
 )
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	TypeName string
 	Path     string
 	Named    names.TaskNames
@@ -38,8 +44,27 @@ type Task struct {
 	OnlyIf     []string `taco:"onlyif"`
 	Unless     []string `taco:"unless"`
 
-	// aborts task execution if one task fails
-	AbortOnError bool
+	MaxOutput      string `taco:"max_output"`
+	OutputLogLevel string `taco:"output_loglevel"`
+	Stream         bool   `taco:"stream"`
+	Become         bool   `taco:"become"`
+	BecomeMethod   string `taco:"become_method"`
+
+	// the exit codes which are considered successful additionally to 0
+	SuccessRetcodes []int
+	// regular expressions which must match the output for the command to succeed
+	SuccessStdout string `taco:"success_stdout"`
+	SuccessStderr string `taco:"success_stderr"`
+	// the command reports itself if it changed anything, see ParseStateLine
+	Stateful bool `taco:"stateful"`
+
+	// values created during task build
+	maxOutputCalculated uint64
+	successStdoutRe     *regexp.Regexp
+	successStderrRe     *regexp.Regexp
+
+	// false if a stateful command reported that nothing was changed
+	Updated bool
 }
 
 func (crt *Task) GetTypeName() string {
@@ -50,6 +75,19 @@ func (crt *Task) GetRequirements() []string {
 	return crt.Require
 }
 
+func (crt *Task) GetEnvs() conv.KeyValues {
+	return crt.Envs
+}
+
+// outputLogLevel doesn't log the output of sensitive tasks
+func (crt *Task) outputLogLevel() string {
+	if crt.Sensitive {
+		return tacoexec.QuietOutputLogLevel
+	}
+
+	return crt.OutputLogLevel
+}
+
 func (crt *Task) Validate(goos string) error {
 	errs := &utils.Errors{}
 	err1 := tasks.ValidateRequired(crt.Named.Name, crt.Path+"."+tasks.NameField)
@@ -61,7 +99,50 @@ func (crt *Task) Validate(goos string) error {
 		return errs.ToError()
 	}
 
-	return nil
+	if crt.MaxOutput != "" {
+		maxOutput, err := conv.ConvertToFileSize(crt.MaxOutput)
+		if err != nil {
+			errs.Add(fmt.Errorf("%w at path '%s.%s'", err, crt.Path, tasks.MaxOutputField))
+		}
+		crt.maxOutputCalculated = maxOutput
+	}
+
+	err := tacoexec.ValidateOutputLogLevel(crt.OutputLogLevel)
+	if err != nil {
+		errs.Add(fmt.Errorf("%w at path '%s.%s'", err, crt.Path, tasks.OutputLogLevel))
+	}
+
+	errs.Add(tasks.ValidateBecome(crt.Become, crt.BecomeMethod, crt.Path, goos))
+
+	crt.successStdoutRe, err = compileSuccessPattern(crt.SuccessStdout, crt.Path+"."+tasks.SuccessStdoutField)
+	errs.Add(err)
+
+	crt.successStderrRe, err = compileSuccessPattern(crt.SuccessStderr, crt.Path+"."+tasks.SuccessStderrField)
+	errs.Add(err)
+
+	if crt.Shell == tacoexec.NoShell {
+		for _, rawCmd := range crt.Named.GetNames() {
+			_, err = tacoexec.ParseCommandLine(rawCmd)
+			if err != nil {
+				errs.Add(fmt.Errorf("%w at path '%s'", err, crt.Path))
+			}
+		}
+	}
+
+	return errs.ToError()
+}
+
+func compileSuccessPattern(pattern, path string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression at path '%s': %w", path, err)
+	}
+
+	return re, nil
 }
 
 func (crt *Task) GetPath() string {
@@ -84,9 +165,80 @@ func (crt *Task) GetCreatesFilesList() []string {
 	return crt.Creates
 }
 
+// DescribeResult reports the pid, exit code and output of the command as changes
+func (crt *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	name := strings.Join(crt.Named.GetNames(), "; ")
+
+	if res.IsSkipped {
+		return tasks.ResultDescription{
+			Name:    name,
+			Comment: `Command skipped: ` + res.SkipReason,
+		}
+	}
+
+	if res.DryRun {
+		return tasks.ResultDescription{
+			Name:    name,
+			Comment: `Command "` + name + `" would run`,
+			Changes: map[string]interface{}{"cmd": name},
+		}
+	}
+
+	// a stateful command reported that nothing was changed
+	if res.Err == nil && crt.Stateful && !crt.Updated {
+		comment := `Command "` + name + `" run, nothing changed`
+		if res.Comment != "" {
+			comment = res.Comment
+		}
+
+		return tasks.ResultDescription{
+			Name:    name,
+			Comment: comment,
+			Changes: map[string]interface{}{},
+		}
+	}
+
+	comment := `Command "` + name + `" run`
+	if res.Comment != "" {
+		comment = res.Comment
+	}
+
+	// the changes reported by a stateful command are counted once together with the command output
+	changes := res.CommandChanges(crt.Shell)
+	for k, v := range res.Changes {
+		changes[k] = v
+	}
+
+	return tasks.ResultDescription{
+		Name:    name,
+		Comment: comment,
+		Changes: changes,
+	}
+}
+
 type Executor struct {
 	Runner    tacoexec.Runner
 	FsManager tasks.FsManager
+
+	// receives the output lines while the commands are running
+	Stream tacoio.LineStream
+	// streams the output of all tasks, otherwise only the tasks with the stream field are streamed
+	StreamAll bool
+}
+
+// SupportsDryRun tells that the executor evaluates the conditions of the command in a dry run
+func (crte *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// SupportsTimeout tells that the executor kills the command when the context is done
+func (crte *Executor) SupportsTimeout() bool {
+	return true
+}
+
+// ReportsExitCode tells that the executor sets the exit code of the command in the execution result
+func (crte *Executor) ReportsExitCode() bool {
+	return true
 }
 
 func (crte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
@@ -99,16 +251,21 @@ func (crte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 	execRes.Name = strings.Join(cmdRunTask.Named.GetNames(), "; ")
 
 	var stdoutBuf, stderrBuf bytes.Buffer
+	stdoutWriter, stderrWriter := crte.limitOutput(cmdRunTask, &stdoutBuf, &stderrBuf)
+
 	execCtx := &tacoexec.Context{
-		Ctx:          ctx,
-		StdoutWriter: &stdoutBuf,
-		StderrWriter: &stderrBuf,
-		WorkingDir:   cmdRunTask.WorkingDir,
-		User:         cmdRunTask.User,
-		Path:         cmdRunTask.Path,
-		Envs:         cmdRunTask.Envs,
-		Cmds:         cmdRunTask.Named.GetNames(),
-		Shell:        cmdRunTask.Shell,
+		Ctx:            ctx,
+		StdoutWriter:   stdoutWriter,
+		StderrWriter:   stderrWriter,
+		WorkingDir:     cmdRunTask.WorkingDir,
+		User:           cmdRunTask.User,
+		Path:           cmdRunTask.Path,
+		Envs:           cmdRunTask.Envs,
+		Cmds:           cmdRunTask.Named.GetNames(),
+		Shell:          cmdRunTask.Shell,
+		OutputLogLevel: cmdRunTask.outputLogLevel(),
+		Become:         cmdRunTask.Become,
+		BecomeMethod:   cmdRunTask.BecomeMethod,
 	}
 
 	shouldNotBeExecutedReason, err := conditionals.Check(execCtx, crte.FsManager, crte.Runner, cmdRunTask)
@@ -124,19 +281,121 @@ func (crte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 		return execRes
 	}
 
+	if tasks.IsDryRun(ctx) {
+		execRes.DryRun = true
+		return execRes
+	}
+
+	streamStdout, streamStderr, flushStream := crte.streamOutput(cmdRunTask)
+	execCtx.StdoutWriter = io.MultiWriter(stdoutWriter, streamStdout)
+	execCtx.StderrWriter = io.MultiWriter(stderrWriter, streamStderr)
+
 	start := time.Now()
 
-	err = crte.Runner.Run(execCtx)
+	runErr := crte.Runner.Run(execCtx)
+	exitCode := tacoexec.ExitCodeOf(runErr)
+	err = cmdRunTask.checkSuccess(runErr, stdoutBuf.String(), stderrBuf.String())
+	flushStream()
 
 	execRes.Duration = time.Since(start)
-	if err != nil {
-		execRes.Err = err
-	}
 	logrus.Debugf("execution of %s has finished, took: %v", cmdRunTask.Named.Name, execRes.Duration)
 
-	execRes.StdErr = stderrBuf.String()
-	execRes.StdOut = stdoutBuf.String()
+	stdout := stdoutBuf.String()
+	cmdRunTask.Updated = true
+	if err == nil && cmdRunTask.Stateful {
+		var state State
+		state, stdout, err = ParseStateLine(stdout)
+		if err == nil {
+			cmdRunTask.Updated = state.Changed
+			execRes.Comment = state.Comment
+			execRes.Changes = state.Changes
+		}
+	}
+
+	execRes.Err = err
+	execRes.ExitCode = exitCode
+	execRes.StdErr = stderrBuf.String() + truncationNote(stderrWriter)
+	execRes.StdOut = stdout + truncationNote(stdoutWriter)
 	execRes.Pid = execCtx.Pid
 
 	return execRes
 }
+
+// checkSuccess decides if the command run was successful by its exit code and output
+func (crt *Task) checkSuccess(runErr error, stdout, stderr string) error {
+	if !crt.isSuccessfulExit(runErr) {
+		return runErr
+	}
+
+	if crt.successStdoutRe != nil && !crt.successStdoutRe.MatchString(stdout) {
+		return fmt.Errorf("stdout doesn't match the success pattern '%s'", crt.SuccessStdout)
+	}
+
+	if crt.successStderrRe != nil && !crt.successStderrRe.MatchString(stderr) {
+		return fmt.Errorf("stderr doesn't match the success pattern '%s'", crt.SuccessStderr)
+	}
+
+	return nil
+}
+
+func (crt *Task) isSuccessfulExit(runErr error) bool {
+	if runErr != nil {
+		// killed commands fail regardless of their exit code
+		if errors.Is(runErr, context.Canceled) || errors.Is(runErr, context.DeadlineExceeded) {
+			return false
+		}
+
+		timeoutErr := tacoexec.TimeoutError{}
+		runErrWithCode := tacoexec.RunError{}
+		if errors.As(runErr, &timeoutErr) || !errors.As(runErr, &runErrWithCode) {
+			return false
+		}
+	}
+
+	exitCode := tacoexec.ExitCodeOf(runErr)
+	if exitCode == 0 {
+		return runErr == nil
+	}
+
+	for _, successRetcode := range crt.SuccessRetcodes {
+		if exitCode == successRetcode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// streamOutput gives the writers passing the output lines of the task to the stream,
+// if the task shouldn't be streamed, the writers discard the output
+func (crte *Executor) streamOutput(t *Task) (stdoutWriter, stderrWriter io.Writer, flush func()) {
+	if crte.Stream == nil || (!crte.StreamAll && !t.Stream) || t.Sensitive {
+		return io.Discard, io.Discard, func() {}
+	}
+
+	stdoutLineWriter, flushStdout := tacoio.NewLineWriter(crte.Stream, t.Path, "stdout")
+	stderrLineWriter, flushStderr := tacoio.NewLineWriter(crte.Stream, t.Path, "stderr")
+
+	return stdoutLineWriter, stderrLineWriter, func() {
+		flushStdout()
+		flushStderr()
+	}
+}
+
+func (crte *Executor) limitOutput(t *Task, stdoutBuf, stderrBuf *bytes.Buffer) (stdoutWriter, stderrWriter io.Writer) {
+	if t.maxOutputCalculated == 0 {
+		return stdoutBuf, stderrBuf
+	}
+
+	return &tacoio.LimitedWriter{Writer: stdoutBuf, Limit: int64(t.maxOutputCalculated)},
+		&tacoio.LimitedWriter{Writer: stderrBuf, Limit: int64(t.maxOutputCalculated)}
+}
+
+func truncationNote(w io.Writer) string {
+	limitedWriter, ok := w.(*tacoio.LimitedWriter)
+	if !ok || limitedWriter.Discarded == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("\n... output truncated, %d bytes discarded", limitedWriter.Discarded)
+}
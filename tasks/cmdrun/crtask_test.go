@@ -3,6 +3,8 @@ package cmdrun
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -399,6 +401,34 @@ func TestCmdRunTaskValidation(t *testing.T) {
 			},
 			ExpectedError: "empty required value at path '.name', empty required values at path '.names'",
 		},
+		{
+			InputTask: Task{
+				Path:           "somepath",
+				Named:          names.TaskNames{Name: "seven"},
+				MaxOutput:      "10x",
+				OutputLogLevel: "loud",
+			},
+			ExpectedError: "file size has invalid units at path 'somepath.max_output', " +
+				"not a valid logrus Level: \"loud\" at path 'somepath.output_loglevel'",
+		},
+		{
+			InputTask: Task{
+				Path:  "somepath",
+				Named: names.TaskNames{Names: []string{"echo 'ok' | wc -c", "echo 'unterminated"}},
+				Shell: "none",
+			},
+			ExpectedError: "unterminated single quote in 'echo 'unterminated' at path 'somepath'",
+		},
+		{
+			InputTask: Task{
+				Path:          "somepath",
+				Named:         names.TaskNames{Name: "eight"},
+				SuccessStdout: "(unclosed",
+				SuccessStderr: "^$",
+			},
+			ExpectedError: "invalid regular expression at path 'somepath.success_stdout': " +
+				"error parsing regexp: missing closing ): `(unclosed`",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -411,6 +441,257 @@ func TestCmdRunTaskValidation(t *testing.T) {
 	}
 }
 
+func TestTaskExecutionWithMaxOutput(t *testing.T) {
+	runner := &appExec.RunnerMock{
+		RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+			_, err := stdOutWriter.Write([]byte("0123456789"))
+			assert.NoError(t, err)
+			_, err = stdErrWriter.Write([]byte("err"))
+			assert.NoError(t, err)
+		},
+	}
+
+	cmdRunExecutor := &Executor{
+		Runner:    runner,
+		FsManager: &apptest.FsManagerMock{},
+	}
+
+	task := &Task{
+		Named:     names.TaskNames{Name: "chatty command"},
+		MaxOutput: "4b",
+	}
+	err := task.Validate(runtime.GOOS)
+	assert.NoError(t, err)
+
+	res := cmdRunExecutor.Execute(context.Background(), task)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "0123\n... output truncated, 6 bytes discarded", res.StdOut)
+	assert.Equal(t, "err", res.StdErr)
+}
+
+func TestTaskExecutionWithSuccessCriteria(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Task             Task
+		ExitCode         int
+		Stdout           string
+		Stderr           string
+		ExpectedErr      string
+		ExpectedExitCode int
+	}{
+		{
+			Name:             "accepted exit code",
+			Task:             Task{SuccessRetcodes: []int{2, 3}},
+			ExitCode:         2,
+			ExpectedExitCode: 2,
+		},
+		{
+			Name:             "not accepted exit code",
+			Task:             Task{SuccessRetcodes: []int{2, 3}},
+			ExitCode:         1,
+			ExpectedErr:      "exit status 1",
+			ExpectedExitCode: 1,
+		},
+		{
+			Name:   "stdout matches",
+			Task:   Task{SuccessStdout: "(?m)^status: ok$"},
+			Stdout: "checking\nstatus: ok\n",
+		},
+		{
+			Name:        "stdout doesn't match",
+			Task:        Task{SuccessStdout: "(?m)^status: ok$"},
+			Stdout:      "checking\nstatus: degraded\n",
+			ExpectedErr: "stdout doesn't match the success pattern '(?m)^status: ok$'",
+		},
+		{
+			Name:        "stderr doesn't match",
+			Task:        Task{SuccessStderr: "^$"},
+			Stderr:      "warning: disk almost full",
+			ExpectedErr: "stderr doesn't match the success pattern '^$'",
+		},
+		{
+			Name:             "failed exit code is not overridden by the pattern",
+			Task:             Task{SuccessStdout: "ok"},
+			ExitCode:         1,
+			Stdout:           "ok",
+			ExpectedErr:      "exit status 1",
+			ExpectedExitCode: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			runner := &appExec.RunnerMock{}
+			runner.RunOutputCallback = func(stdOutWriter, stdErrWriter io.Writer) {
+				_, err := stdOutWriter.Write([]byte(tc.Stdout))
+				assert.NoError(tt, err)
+				_, err = stdErrWriter.Write([]byte(tc.Stderr))
+				assert.NoError(tt, err)
+
+				if tc.ExitCode != 0 {
+					runner.ErrToReturn = appExec.RunError{
+						Err:      fmt.Errorf("exit status %d", tc.ExitCode),
+						ExitCode: tc.ExitCode,
+					}
+				}
+			}
+
+			cmdRunExecutor := &Executor{
+				Runner:    runner,
+				FsManager: &apptest.FsManagerMock{},
+			}
+
+			task := tc.Task
+			task.Named = names.TaskNames{Name: "picky command"}
+			err := task.Validate(runtime.GOOS)
+			assert.NoError(tt, err)
+
+			res := cmdRunExecutor.Execute(context.Background(), &task)
+			assert.Equal(tt, tc.ExpectedExitCode, res.ExitCode)
+			if tc.ExpectedErr == "" {
+				assert.NoError(tt, res.Err)
+			} else {
+				assert.EqualError(tt, res.Err, tc.ExpectedErr)
+			}
+		})
+	}
+}
+
+func TestStatefulTaskExecution(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Stdout          string
+		ExpectedErr     string
+		ExpectedUpdated bool
+		ExpectedComment string
+		ExpectedChanges map[string]string
+		ExpectedStdOut  string
+	}{
+		{
+			Name:            "nothing changed",
+			Stdout:          "checking config\nchanged=no comment=\"config is up to date\"\n",
+			ExpectedComment: "config is up to date",
+			ExpectedStdOut:  "checking config",
+		},
+		{
+			Name:            "changed with json state",
+			Stdout:          "updating config\n{\"changed\": true, \"version\": \"1.2\"}",
+			ExpectedUpdated: true,
+			ExpectedChanges: map[string]string{"version": "1.2"},
+			ExpectedStdOut:  "updating config",
+		},
+		{
+			Name:            "missing state line",
+			Stdout:          "",
+			ExpectedErr:     "stateful command didn't print a state line",
+			ExpectedUpdated: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(tt *testing.T) {
+			runner := &appExec.RunnerMock{
+				RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+					_, err := stdOutWriter.Write([]byte(tc.Stdout))
+					assert.NoError(tt, err)
+				},
+			}
+
+			cmdRunExecutor := &Executor{
+				Runner:    runner,
+				FsManager: &apptest.FsManagerMock{},
+			}
+
+			task := &Task{
+				Named:    names.TaskNames{Name: "stateful command"},
+				Stateful: true,
+			}
+
+			res := cmdRunExecutor.Execute(context.Background(), task)
+			if tc.ExpectedErr == "" {
+				assert.NoError(tt, res.Err)
+			} else {
+				assert.EqualError(tt, res.Err, tc.ExpectedErr)
+			}
+			assert.Equal(tt, tc.ExpectedUpdated, task.Updated)
+			assert.Equal(tt, tc.ExpectedComment, res.Comment)
+			assert.Equal(tt, tc.ExpectedChanges, res.Changes)
+			if tc.ExpectedErr == "" {
+				assert.Equal(tt, tc.ExpectedStdOut, res.StdOut)
+			}
+		})
+	}
+}
+
+type lineStreamMock struct {
+	lines []string
+}
+
+func (s *lineStreamMock) WriteLine(taskPath, streamName, line string) {
+	s.lines = append(s.lines, taskPath+" "+streamName+": "+line)
+}
+
+func TestTaskExecutionWithStream(t *testing.T) {
+	runner := &appExec.RunnerMock{
+		RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+			_, err := stdOutWriter.Write([]byte("line1\nli"))
+			assert.NoError(t, err)
+			_, err = stdOutWriter.Write([]byte("ne2\nline3"))
+			assert.NoError(t, err)
+			_, err = stdErrWriter.Write([]byte("some error\n"))
+			assert.NoError(t, err)
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		streamAll     bool
+		taskStream    bool
+		expectedLines []string
+	}{
+		{
+			name:          "stream all tasks",
+			streamAll:     true,
+			expectedLines: []string{"task1 stdout: line1", "task1 stdout: line2", "task1 stderr: some error", "task1 stdout: line3"},
+		},
+		{
+			name:          "stream single task",
+			taskStream:    true,
+			expectedLines: []string{"task1 stdout: line1", "task1 stdout: line2", "task1 stderr: some error", "task1 stdout: line3"},
+		},
+		{
+			name: "no streaming",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			stream := &lineStreamMock{}
+			cmdRunExecutor := &Executor{
+				Runner:    runner,
+				FsManager: &apptest.FsManagerMock{},
+				Stream:    stream,
+				StreamAll: tc.streamAll,
+			}
+
+			task := &Task{
+				Path:   "task1",
+				Named:  names.TaskNames{Name: "chatty command"},
+				Stream: tc.taskStream,
+			}
+
+			res := cmdRunExecutor.Execute(context.Background(), task)
+			assert.NoError(t, res.Err)
+			assert.Equal(t, "line1\nline2\nline3", res.StdOut)
+			assert.Equal(t, "some error\n", res.StdErr)
+			assert.Equal(t, tc.expectedLines, stream.lines)
+		})
+	}
+}
+
 func assertEnvValuesMatch(t *testing.T, expectedEnvs conv.KeyValues, actualCmdEnvs []string) {
 	expectedRawEnvs := expectedEnvs.ToEqualSignStrings()
 	notFoundEnvs := make([]string, 0, len(expectedEnvs))
@@ -40,6 +40,15 @@ var cmdRunTaskParamsFnMap = parser.TaskFieldsParserConfig{
 		},
 		FieldName: "Env",
 	},
+	tasks.SuccessRetcodesField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			var err error
+			t := task.(*cmdrun.Task)
+			t.SuccessRetcodes, err = parseRetcodes(val, path+"."+tasks.SuccessRetcodesField)
+			return err
+		},
+		FieldName: "SuccessRetcodes",
+	},
 }
 
 func (tb TaskBuilder) Build(typeName, path string, params interface{}) (t tasks.CoreTask, err error) {
@@ -52,3 +61,22 @@ func (tb TaskBuilder) Build(typeName, path string, params interface{}) (t tasks.
 
 	return task, errs.ToError()
 }
+
+// parseRetcodes accepts a single exit code or a list of exit codes
+func parseRetcodes(val interface{}, path string) ([]int, error) {
+	rawRetcodes, ok := val.([]interface{})
+	if !ok {
+		rawRetcodes = []interface{}{val}
+	}
+
+	retcodes := make([]int, 0, len(rawRetcodes))
+	for _, rawRetcode := range rawRetcodes {
+		retcode, err := conv.ConvertToInt(rawRetcode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code '%v' at path '%s': %w", rawRetcode, path, err)
+		}
+		retcodes = append(retcodes, retcode)
+	}
+
+	return retcodes, nil
+}
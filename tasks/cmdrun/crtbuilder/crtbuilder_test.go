@@ -3,6 +3,7 @@ package crtbuilder
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/realvnc-labs/tacoscript/conv"
 	"github.com/realvnc-labs/tacoscript/tasks"
@@ -14,6 +15,8 @@ import (
 )
 
 func TestTaskBuilder(t *testing.T) {
+	untilExitCode := 2
+
 	testCases := []struct {
 		typeName      string
 		path          string
@@ -35,6 +38,21 @@ func TestTaskBuilder(t *testing.T) {
 				}}},
 				yaml.MapSlice{yaml.MapItem{Key: tasks.CreatesField, Value: "somefile.txt"}},
 				yaml.MapSlice{yaml.MapItem{Key: tasks.OnlyIfField, Value: "one condition"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.TimeoutField, Value: "1m"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.RetryField, Value: yaml.MapSlice{
+					{Key: tasks.AttemptsField, Value: 3},
+					{Key: tasks.IntervalField, Value: 5},
+					{Key: tasks.UntilField, Value: 2},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.MaxOutputField, Value: "1M"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.OutputLogLevel, Value: "info"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.StreamField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.BecomeField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.BecomeMethodField, Value: "doas"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SuccessRetcodesField, Value: []interface{}{2, "3"}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SuccessStdoutField, Value: "^ok$"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SuccessStderrField, Value: "^$"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.StatefulField, Value: true}},
 			},
 			expectedTask: &cmdrun.Task{
 				TypeName:   "someType",
@@ -55,7 +73,81 @@ func TestTaskBuilder(t *testing.T) {
 				},
 				Creates: []string{"somefile.txt"},
 				OnlyIf:  []string{"one condition"},
+				ExecutionPolicy: tasks.ExecutionPolicy{
+					Timeout: time.Minute,
+					Retry: tasks.Retry{
+						Attempts:      3,
+						Interval:      5 * time.Second,
+						UntilExitCode: &untilExitCode,
+					},
+				},
+				MaxOutput:       "1M",
+				OutputLogLevel:  "info",
+				Stream:          true,
+				Become:          true,
+				BecomeMethod:    "doas",
+				SuccessRetcodes: []int{2, 3},
+				SuccessStdout:   "^ok$",
+				SuccessStderr:   "^$",
+				Stateful:        true,
+			},
+		},
+		{
+			typeName: "someTypeWithSingleRetcode",
+			path:     "somePathWithSingleRetcode",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "1"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SuccessRetcodesField, Value: 1}},
+			},
+			expectedTask: &cmdrun.Task{
+				TypeName:        "someTypeWithSingleRetcode",
+				Path:            "somePathWithSingleRetcode",
+				Named:           names.TaskNames{Name: "1"},
+				SuccessRetcodes: []int{1},
+			},
+		},
+		{
+			typeName: "someTypeWithRetcodeErrors",
+			path:     "somePathWithRetcodeErrors",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SuccessRetcodesField, Value: []interface{}{1, "one"}}},
+			},
+			expectedTask: &cmdrun.Task{
+				TypeName: "someTypeWithRetcodeErrors",
+				Path:     "somePathWithRetcodeErrors",
+			},
+			expectedError: "invalid exit code 'one' at path 'somePathWithRetcodeErrors.success_retcodes': " +
+				"value is not a number: success_retcodes",
+		},
+		{
+			typeName: "someTypeWithRetryAttempts",
+			path:     "somePathWithRetryAttempts",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "1"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.RetryField, Value: 4}},
+			},
+			expectedTask: &cmdrun.Task{
+				TypeName: "someTypeWithRetryAttempts",
+				Path:     "somePathWithRetryAttempts",
+				Named:    names.TaskNames{Name: "1"},
+				ExecutionPolicy: tasks.ExecutionPolicy{
+					Retry: tasks.Retry{Attempts: 4},
+				},
+			},
+		},
+		{
+			typeName: "someTypeWithRetryErrors",
+			path:     "somePathWithRetryErrors",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.RetryField, Value: yaml.MapSlice{
+					{Key: "times", Value: 3},
+				}}},
+			},
+			expectedTask: &cmdrun.Task{
+				TypeName: "someTypeWithRetryErrors",
+				Path:     "somePathWithRetryErrors",
 			},
+			expectedError: "unknown key 'times' at path 'somePathWithRetryErrors.retry': retry",
 		},
 
 		{
@@ -227,6 +319,16 @@ func TestTaskBuilder(t *testing.T) {
 			assert.Equal(t, tc.expectedTask.Require, actualCmdRunTask.Require)
 			assert.Equal(t, tc.expectedTask.OnlyIf, actualCmdRunTask.OnlyIf)
 			assert.Equal(t, tc.expectedTask.Unless, actualCmdRunTask.Unless)
+			assert.Equal(t, tc.expectedTask.ExecutionPolicy, actualCmdRunTask.ExecutionPolicy)
+			assert.Equal(t, tc.expectedTask.MaxOutput, actualCmdRunTask.MaxOutput)
+			assert.Equal(t, tc.expectedTask.OutputLogLevel, actualCmdRunTask.OutputLogLevel)
+			assert.Equal(t, tc.expectedTask.Stream, actualCmdRunTask.Stream)
+			assert.Equal(t, tc.expectedTask.Become, actualCmdRunTask.Become)
+			assert.Equal(t, tc.expectedTask.BecomeMethod, actualCmdRunTask.BecomeMethod)
+			assert.Equal(t, tc.expectedTask.SuccessRetcodes, actualCmdRunTask.SuccessRetcodes)
+			assert.Equal(t, tc.expectedTask.SuccessStdout, actualCmdRunTask.SuccessStdout)
+			assert.Equal(t, tc.expectedTask.SuccessStderr, actualCmdRunTask.SuccessStderr)
+			assert.Equal(t, tc.expectedTask.Stateful, actualCmdRunTask.Stateful)
 		})
 	}
 }
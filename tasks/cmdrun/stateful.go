@@ -0,0 +1,156 @@
+package cmdrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	stateChangedKey = "changed"
+	stateCommentKey = "comment"
+)
+
+// State is reported by a stateful command in the last line of its output
+type State struct {
+	Changed bool
+	Comment string
+	// all other reported keys
+	Changes map[string]string
+}
+
+// ParseStateLine reads the state from the last non-empty line of the output, which is either a list of key=value pairs,
+// e.g. changed=no comment="config is up to date", or a JSON object like {"changed": false, "comment": "..."},
+// the output without the state line is returned
+func ParseStateLine(output string) (state State, remainingOutput string, err error) {
+	trimmedOutput := strings.TrimRight(output, " \t\r\n")
+	lastLineStart := strings.LastIndex(trimmedOutput, "\n") + 1
+	stateLine := strings.TrimSpace(trimmedOutput[lastLineStart:])
+
+	if stateLine == "" {
+		return state, output, fmt.Errorf("stateful command didn't print a state line")
+	}
+
+	var values map[string]string
+	if strings.HasPrefix(stateLine, "{") {
+		values, err = parseJSONStateLine(stateLine)
+	} else {
+		values, err = parseKeyValueStateLine(stateLine)
+	}
+	if err != nil {
+		return state, output, fmt.Errorf("invalid state line '%s': %w", stateLine, err)
+	}
+
+	changed, ok := values[stateChangedKey]
+	if !ok {
+		return state, output, fmt.Errorf("invalid state line '%s': missing key '%s'", stateLine, stateChangedKey)
+	}
+
+	state.Changed, err = parseStateBool(changed)
+	if err != nil {
+		return state, output, fmt.Errorf("invalid state line '%s': %w", stateLine, err)
+	}
+
+	state.Comment = values[stateCommentKey]
+	delete(values, stateChangedKey)
+	delete(values, stateCommentKey)
+	if len(values) > 0 {
+		state.Changes = values
+	}
+
+	return state, strings.TrimRight(trimmedOutput[:lastLineStart], "\r\n"), nil
+}
+
+func parseStateBool(val string) (bool, error) {
+	switch strings.ToLower(val) {
+	case "yes", "true", "1":
+		return true, nil
+	case "no", "false", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("value of '%s' should be yes or no, got '%s'", stateChangedKey, val)
+	}
+}
+
+func parseJSONStateLine(stateLine string) (map[string]string, error) {
+	rawValues := map[string]interface{}{}
+	err := json.Unmarshal([]byte(stateLine), &rawValues)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(rawValues))
+	for key, rawValue := range rawValues {
+		switch v := rawValue.(type) {
+		case string:
+			values[key] = v
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		case nil:
+			values[key] = ""
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = string(encoded)
+		}
+	}
+
+	return values, nil
+}
+
+// parseKeyValueStateLine splits the line to the key=value pairs, values with spaces should be double quoted
+func parseKeyValueStateLine(stateLine string) (map[string]string, error) {
+	values := map[string]string{}
+	rest := stateLine
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return values, nil
+		}
+
+		eqPos := strings.Index(rest, "=")
+		if eqPos <= 0 || strings.ContainsAny(rest[:eqPos], " \t") {
+			return nil, fmt.Errorf("expected key=value pairs")
+		}
+		key := rest[:eqPos]
+		rest = rest[eqPos+1:]
+
+		if strings.HasPrefix(rest, `"`) {
+			value, remaining, err := readQuotedValue(rest)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+			rest = remaining
+			continue
+		}
+
+		valueEnd := strings.IndexAny(rest, " \t")
+		if valueEnd < 0 {
+			valueEnd = len(rest)
+		}
+		values[key] = rest[:valueEnd]
+		rest = rest[valueEnd:]
+	}
+}
+
+func readQuotedValue(s string) (value, rest string, err error) {
+	escaped := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			value, err = strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated quoted value")
+}
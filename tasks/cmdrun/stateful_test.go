@@ -0,0 +1,81 @@
+package cmdrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStateLine(t *testing.T) {
+	testCases := []struct {
+		name              string
+		output            string
+		expectedState     State
+		expectedRemaining string
+		expectedErr       string
+	}{
+		{
+			name:              "key value pairs",
+			output:            "line1\nline2\nchanged=yes comment=\"file \\\"a\\\" updated\" size=10\n\n",
+			expectedState:     State{Changed: true, Comment: `file "a" updated`, Changes: map[string]string{"size": "10"}},
+			expectedRemaining: "line1\nline2",
+		},
+		{
+			name:          "only state line",
+			output:        "changed=false",
+			expectedState: State{},
+		},
+		{
+			name:              "json",
+			output:            "line1\r\n{\"changed\": \"no\", \"comment\": \"up to date\", \"count\": 2, \"missing\": null}\r\n",
+			expectedState:     State{Comment: "up to date", Changes: map[string]string{"count": "2", "missing": ""}},
+			expectedRemaining: "line1",
+		},
+		{
+			name:        "empty output",
+			output:      "\n \n",
+			expectedErr: "stateful command didn't print a state line",
+		},
+		{
+			name:        "missing changed key",
+			output:      "comment=done",
+			expectedErr: "invalid state line 'comment=done': missing key 'changed'",
+		},
+		{
+			name:        "invalid changed value",
+			output:      "changed=maybe",
+			expectedErr: "invalid state line 'changed=maybe': value of 'changed' should be yes or no, got 'maybe'",
+		},
+		{
+			name:        "not key value pairs",
+			output:      "done",
+			expectedErr: "invalid state line 'done': expected key=value pairs",
+		},
+		{
+			name:        "unterminated quote",
+			output:      `changed=yes comment="done`,
+			expectedErr: `invalid state line 'changed=yes comment="done': unterminated quoted value`,
+		},
+		{
+			name:        "invalid json",
+			output:      `{"changed": yes}`,
+			expectedErr: `invalid state line '{"changed": yes}': invalid character 'y' looking for beginning of value`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			state, remaining, err := ParseStateLine(tc.output)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				assert.Equal(t, tc.output, remaining)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedState, state)
+			assert.Equal(t, tc.expectedRemaining, remaining)
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package cmdscript
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/secrets"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+
+	"github.com/realvnc-labs/tacoscript/utils"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	TaskType = "cmd.script"
+
+	// TemplateGo renders the script with the Go template engine before running it
+	TemplateGo = "go"
+)
+
+type Task struct {
+	tasks.ExecutionPolicy
+
+	TypeName string
+	Path     string
+	Source   utils.Location
+	Envs     conv.KeyValues
+
+	Name       string   `taco:"name"`
+	SourceHash string   `taco:"source_hash"`
+	SkipVerify bool     `taco:"skip_verify"`
+	Args       []string `taco:"args"`
+	Template   string   `taco:"template"`
+	WorkingDir string   `taco:"cwd"`
+	User       string   `taco:"user"`
+	Shell      string   `taco:"shell"`
+	Creates    []string `taco:"creates"`
+	Require    []string `taco:"require"`
+	OnlyIf     []string `taco:"onlyif"`
+	Unless     []string `taco:"unless"`
+}
+
+func (cst *Task) GetTypeName() string {
+	return cst.TypeName
+}
+
+func (cst *Task) GetRequirements() []string {
+	return cst.Require
+}
+
+func (cst *Task) GetEnvs() conv.KeyValues {
+	return cst.Envs
+}
+
+func (cst *Task) Validate(goos string) error {
+	errs := &utils.Errors{}
+
+	err := tasks.ValidateRequired(cst.Name, cst.Path+"."+tasks.NameField)
+	errs.Add(err)
+
+	if cst.Source.IsURL && cst.SourceHash == "" && !cst.SkipVerify {
+		errs.Add(
+			fmt.Errorf(
+				`empty '%s' field at path '%s.%s' for remote url source '%s'`,
+				tasks.SourceHashField,
+				cst.Path,
+				tasks.SourceHashField,
+				cst.Source.RawLocation,
+			),
+		)
+	}
+
+	if cst.Template != "" && cst.Template != TemplateGo {
+		errs.Add(fmt.Errorf(
+			"unsupported template engine '%s' at path '%s.%s', only '%s' is supported",
+			cst.Template,
+			cst.Path,
+			tasks.TemplateField,
+			TemplateGo,
+		))
+	}
+
+	if cst.Shell == tacoexec.NoShell {
+		errs.Add(fmt.Errorf("scripts cannot run without a shell at path '%s.%s'", cst.Path, tasks.ShellField))
+	}
+
+	return errs.ToError()
+}
+
+func (cst *Task) GetPath() string {
+	return cst.Path
+}
+
+func (cst *Task) String() string {
+	return conv.ConvertSourceToJSONStrIfPossible(cst)
+}
+
+func (cst *Task) GetOnlyIfCmds() []string {
+	return cst.OnlyIf
+}
+
+func (cst *Task) GetUnlessCmds() []string {
+	return cst.Unless
+}
+
+func (cst *Task) GetCreatesFilesList() []string {
+	return cst.Creates
+}
+
+func (cst *Task) GetSource() *utils.Location {
+	return &cst.Source
+}
+
+type HashManager interface {
+	HashEquals(hashStr, filePath string) (hashEquals bool, actualCache string, err error)
+}
+
+type TemplateVariablesProvider interface {
+	GetTemplateVariables() (utils.TemplateVarsMap, error)
+}
+
+// DescribeResult reports the pid, exit code and output of the script as changes
+func (cst *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	if res.IsSkipped {
+		return tasks.ResultDescription{
+			Name:    cst.Name,
+			Comment: `Script skipped: ` + res.SkipReason,
+		}
+	}
+
+	if res.DryRun {
+		return tasks.ResultDescription{
+			Name:    cst.Name,
+			Comment: `Script "` + cst.Name + `" would run`,
+			Changes: map[string]interface{}{"script": cst.Name},
+		}
+	}
+
+	return tasks.ResultDescription{
+		Name:    cst.Name,
+		Comment: `Script "` + cst.Name + `" run`,
+		Changes: res.CommandChanges(cst.Shell),
+	}
+}
+
+type Executor struct {
+	Runner                    tacoexec.Runner
+	FsManager                 tasks.FsManager
+	HashManager               HashManager
+	TemplateVariablesProvider TemplateVariablesProvider
+}
+
+// SupportsDryRun tells that the executor evaluates the conditions of the script in a dry run
+func (cste *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// SupportsTimeout tells that the executor kills the script when the context is done
+func (cste *Executor) SupportsTimeout() bool {
+	return true
+}
+
+// ReportsExitCode tells that the executor sets the exit code of the script in the execution result
+func (cste *Executor) ReportsExitCode() bool {
+	return true
+}
+
+func (cste *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	execRes := executionresult.ExecutionResult{}
+	scriptTask, ok := task.(*Task)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to Task", task)
+		return execRes
+	}
+	execRes.Name = scriptTask.Name
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &tacoexec.Context{
+		Ctx:          ctx,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		WorkingDir:   scriptTask.WorkingDir,
+		User:         scriptTask.User,
+		Path:         scriptTask.Path,
+		Envs:         scriptTask.Envs,
+		Shell:        scriptTask.Shell,
+	}
+
+	shouldNotBeExecutedReason, err := conditionals.Check(execCtx, cste.FsManager, cste.Runner, scriptTask)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if shouldNotBeExecutedReason != "" {
+		execRes.IsSkipped = true
+		execRes.SkipReason = shouldNotBeExecutedReason
+		execRes.Comment = `Script "` + execRes.Name + `" did not run: ` + shouldNotBeExecutedReason
+		return execRes
+	}
+
+	if tasks.IsDryRun(ctx) {
+		execRes.DryRun = true
+		return execRes
+	}
+
+	start := time.Now()
+
+	script, err := cste.readScript(ctx, scriptTask)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	// the onlyif and unless commands write to the same buffers, the result should contain the script output only
+	stdoutBuf.Reset()
+	stderrBuf.Reset()
+	execCtx.Cmds = []string{script}
+	execCtx.Args = scriptTask.Args
+
+	execRes.Err = cste.Runner.Run(execCtx)
+	execRes.ExitCode = tacoexec.ExitCodeOf(execRes.Err)
+
+	execRes.Duration = time.Since(start)
+	logrus.Debugf("execution of script %s has finished, took: %v", scriptTask.Name, execRes.Duration)
+
+	execRes.StdErr = stderrBuf.String()
+	execRes.StdOut = stdoutBuf.String()
+	execRes.Pid = execCtx.Pid
+
+	return execRes
+}
+
+// readScript gives the verified and optionally rendered contents of the script source
+func (cste *Executor) readScript(ctx context.Context, scriptTask *Task) (string, error) {
+	scriptPath := scriptTask.Source.LocalPath
+
+	// a verified local source is handled like a downloaded one, its verified contents are the script
+	if scriptTask.Source.IsURL || scriptTask.Source.VerifiedContents != nil {
+		tmpFile, err := os.CreateTemp("", "taco-script-*")
+		if err != nil {
+			return "", err
+		}
+		scriptPath = tmpFile.Name()
+		_ = tmpFile.Close()
+
+		defer func() {
+			if removeErr := cste.FsManager.Remove(scriptPath); removeErr != nil {
+				logrus.Errorf("failed to delete '%s': %v", scriptPath, removeErr)
+			}
+		}()
+
+		err = cste.copySource(ctx, scriptTask, scriptPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if scriptTask.SourceHash != "" {
+		hashEquals, actualHash, err := cste.HashManager.HashEquals(scriptTask.SourceHash, scriptPath)
+		if err != nil {
+			return "", err
+		}
+		if !hashEquals {
+			return "", fmt.Errorf(
+				"expected hash sum '%s' didn't match with checksum '%s' of the script '%s'",
+				scriptTask.SourceHash,
+				actualHash,
+				scriptTask.Source.RawLocation,
+			)
+		}
+	}
+
+	script, err := cste.FsManager.ReadFile(scriptPath)
+	if err != nil {
+		return "", err
+	}
+
+	if scriptTask.Template != TemplateGo {
+		return script, nil
+	}
+
+	return cste.render(ctx, scriptTask, script)
+}
+
+// copySource writes the verified contents of a local source or downloads a remote source to the path
+func (cste *Executor) copySource(ctx context.Context, scriptTask *Task, scriptPath string) error {
+	if scriptTask.Source.VerifiedContents != nil {
+		return cste.FsManager.WriteFile(scriptPath, string(scriptTask.Source.VerifiedContents), 0600)
+	}
+
+	err := cste.FsManager.DownloadFile(ctx, scriptPath, scriptTask.Source.URL, false)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("downloaded script '%s' to '%s'", scriptTask.Source.RawLocation, scriptPath)
+
+	return nil
+}
+
+func (cste *Executor) render(ctx context.Context, scriptTask *Task, script string) (string, error) {
+	variables, err := cste.TemplateVariablesProvider.GetTemplateVariables()
+	if err != nil {
+		return "", err
+	}
+
+	scriptTemplate, err := template.New(scriptTask.Source.RawLocation).
+		Funcs(secrets.TemplateFuncs(secrets.MaskerFromContext(ctx))).
+		Option("missingkey=zero").
+		Parse(script)
+	if err != nil {
+		return "", fmt.Errorf("invalid template in script '%s': %w", scriptTask.Source.RawLocation, err)
+	}
+
+	buf := strings.Builder{}
+	err = scriptTemplate.Execute(&buf, variables)
+	if err != nil {
+		return "", fmt.Errorf("failed to render script '%s': %w", scriptTask.Source.RawLocation, err)
+	}
+
+	return buf.String(), nil
+}
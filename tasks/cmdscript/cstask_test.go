@@ -0,0 +1,237 @@
+package cmdscript
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/utils"
+
+	appExec "github.com/realvnc-labs/tacoscript/exec"
+)
+
+const (
+	testScript      = "echo {{ .taco_os_kernel }}\n"
+	wrongScriptHash = "sha256=7a7bd5c4ec4b3a7a6a8eb1b1e6b05ee9eb6b73b3b04fb6d7d4a7a67b0ee3f5d8"
+)
+
+type templateVariablesProviderMock struct{}
+
+func (tvpm templateVariablesProviderMock) GetTemplateVariables() (utils.TemplateVarsMap, error) {
+	return utils.TemplateVarsMap{utils.OSKernel: "linux"}, nil
+}
+
+func TestCmdScriptTaskExecution(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "script.sh")
+	err := os.WriteFile(scriptPath, []byte(testScript), 0600)
+	assert.NoError(t, err)
+
+	testScriptSha256, err := utils.HashSum("sha256", scriptPath)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testScript))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name           string
+		task           *Task
+		runErr         error
+		expectedErr    string
+		expectedScript string
+	}{
+		{
+			name: "local script",
+			task: &Task{
+				Name:       "local script",
+				Source:     utils.ParseLocation(scriptPath),
+				Args:       []string{"one", "two"},
+				WorkingDir: "somedir",
+				User:       "someuser",
+				Envs:       conv.KeyValues{{Key: "KEY", Value: "value"}},
+			},
+			expectedScript: testScript,
+		},
+		{
+			name: "verified local script",
+			task: &Task{
+				Name: "verified script",
+				Source: utils.Location{
+					LocalPath:        scriptPath,
+					RawLocation:      scriptPath,
+					VerifiedContents: []byte("echo verified\n"),
+				},
+			},
+			expectedScript: "echo verified\n",
+		},
+		{
+			name: "rendered remote script",
+			task: &Task{
+				Name:       "remote script",
+				Source:     utils.ParseLocation(srv.URL + "/script.sh"),
+				SourceHash: "sha256=" + testScriptSha256,
+				Template:   TemplateGo,
+			},
+			expectedScript: "echo linux\n",
+		},
+		{
+			name: "hash mismatch",
+			task: &Task{
+				Name:       "modified script",
+				Source:     utils.ParseLocation(scriptPath),
+				SourceHash: wrongScriptHash,
+			},
+			expectedErr: fmt.Sprintf(
+				"expected hash sum '%s' didn't match with checksum '%s' of the script '%s'",
+				wrongScriptHash,
+				"sha256="+testScriptSha256,
+				scriptPath,
+			),
+		},
+		{
+			name: "missing script",
+			task: &Task{
+				Name:   "missing script",
+				Source: utils.ParseLocation(filepath.Join(t.TempDir(), "missing.sh")),
+			},
+			expectedErr: "missing.sh",
+		},
+		{
+			name: "failed script",
+			task: &Task{
+				Name:   "failing script",
+				Source: utils.ParseLocation(scriptPath),
+			},
+			runErr:         appExec.RunError{Err: fmt.Errorf("exit status 2"), ExitCode: 2},
+			expectedErr:    "exit status 2",
+			expectedScript: testScript,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &appExec.RunnerMock{
+				ErrToReturn: tc.runErr,
+				RunOutputCallback: func(stdOutWriter, stdErrWriter io.Writer) {
+					_, e := stdOutWriter.Write([]byte("script output"))
+					assert.NoError(t, e)
+				},
+			}
+
+			executor := &Executor{
+				Runner:                    runner,
+				FsManager:                 &utils.FsManager{},
+				HashManager:               &utils.HashManager{},
+				TemplateVariablesProvider: templateVariablesProviderMock{},
+			}
+
+			res := executor.Execute(context.Background(), tc.task)
+			assert.Equal(t, tc.task.Name, res.Name)
+
+			if tc.expectedErr != "" {
+				assert.Error(t, res.Err)
+				if res.Err != nil {
+					assert.Contains(t, res.Err.Error(), tc.expectedErr)
+				}
+			} else {
+				assert.NoError(t, res.Err)
+			}
+
+			if tc.expectedScript == "" {
+				assert.Len(t, runner.GivenExecContexts, 0)
+				return
+			}
+
+			assert.Len(t, runner.GivenExecContexts, 1)
+			execCtx := runner.GivenExecContexts[0]
+			assert.Equal(t, []string{tc.expectedScript}, execCtx.Cmds)
+			assert.Equal(t, tc.task.Args, execCtx.Args)
+			assert.Equal(t, tc.task.WorkingDir, execCtx.WorkingDir)
+			assert.Equal(t, tc.task.User, execCtx.User)
+			assert.Equal(t, tc.task.Envs, execCtx.Envs)
+			assert.Equal(t, "script output", res.StdOut)
+		})
+	}
+}
+
+func TestCmdScriptTaskSkipped(t *testing.T) {
+	runner := &appExec.RunnerMock{
+		ErrToReturn: appExec.RunError{Err: fmt.Errorf("exit status 1"), ExitCode: 1},
+	}
+
+	executor := &Executor{
+		Runner:    runner,
+		FsManager: &utils.FsManager{},
+	}
+
+	res := executor.Execute(context.Background(), &Task{
+		Name:   "skipped script",
+		Source: utils.ParseLocation("/some/script.sh"),
+		OnlyIf: []string{"false"},
+	})
+
+	assert.NoError(t, res.Err)
+	assert.True(t, res.IsSkipped)
+	assert.Len(t, runner.GivenExecContexts, 1)
+	assert.Equal(t, []string{"false"}, runner.GivenExecContexts[0].Cmds)
+}
+
+func TestCmdScriptTaskValidation(t *testing.T) {
+	testCases := []struct {
+		task          Task
+		expectedError string
+	}{
+		{
+			task: Task{
+				Path:   "somepath",
+				Name:   "script.sh",
+				Source: utils.ParseLocation("script.sh"),
+			},
+		},
+		{
+			task:          Task{Path: "somepath"},
+			expectedError: "empty required value at path 'somepath.name'",
+		},
+		{
+			task: Task{
+				Path:     "somepath",
+				Name:     "remote",
+				Source:   utils.ParseLocation("https://example.com/script.sh"),
+				Template: "jinja",
+				Shell:    "none",
+			},
+			expectedError: "empty 'source_hash' field at path 'somepath.source_hash' for remote url source " +
+				"'https://example.com/script.sh', " +
+				"unsupported template engine 'jinja' at path 'somepath.template', only 'go' is supported, " +
+				"scripts cannot run without a shell at path 'somepath.shell'",
+		},
+		{
+			task: Task{
+				Path:       "somepath",
+				Name:       "remote",
+				Source:     utils.ParseLocation("https://example.com/script.sh"),
+				SkipVerify: true,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.task.Validate(runtime.GOOS)
+		if testCase.expectedError == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedError)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cstbuilder
+
+import (
+	"fmt"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/cmdscript"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder/parser"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+type TaskBuilder struct {
+}
+
+var cmdScriptTaskParamsFnMap = parser.TaskFieldsParserConfig{
+	tasks.SourceField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*cmdscript.Task)
+			t.Source = utils.ParseLocation(fmt.Sprint(val))
+			return nil
+		},
+		FieldName: "Source",
+	},
+	tasks.EnvField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			var err error
+			t := task.(*cmdscript.Task)
+			t.Envs, err = conv.ConvertToKeyValues(val, path)
+			return err
+		},
+		FieldName: "Env",
+	},
+}
+
+func (tb TaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &cmdscript.Task{
+		TypeName: typeName,
+		Path:     path,
+	}
+
+	errs := builder.Build(typeName, path, params, task, cmdScriptTaskParamsFnMap)
+
+	// the name is the script location if no source is given
+	if task.Source.RawLocation == "" && task.Name != "" {
+		task.Source = utils.ParseLocation(task.Name)
+	}
+
+	return task, errs.ToError()
+}
@@ -0,0 +1,103 @@
+package cstbuilder
+
+import (
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/cmdscript"
+	"github.com/realvnc-labs/tacoscript/utils"
+	"gopkg.in/yaml.v2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskBuilder(t *testing.T) {
+	testCases := []struct {
+		typeName      string
+		path          string
+		ctx           []interface{}
+		expectedTask  *cmdscript.Task
+		expectedError string
+	}{
+		{
+			typeName: "someType",
+			path:     "somePath",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "install agent"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SourceField, Value: "https://example.com/install.sh"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SourceHashField, Value: "sha256=abc"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.ArgsField, Value: []interface{}{"--quiet", 1}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.TemplateField, Value: "go"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.CwdField, Value: "somedir"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.UserField, Value: "someuser"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.ShellField, Value: "bash"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.EnvField, Value: []interface{}{
+					yaml.MapSlice{yaml.MapItem{Key: "one", Value: "1"}},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.UnlessField, Value: "test -e /opt/agent"}},
+			},
+			expectedTask: &cmdscript.Task{
+				TypeName:   "someType",
+				Path:       "somePath",
+				Name:       "install agent",
+				Source:     utils.ParseLocation("https://example.com/install.sh"),
+				SourceHash: "sha256=abc",
+				Args:       []string{"--quiet", "1"},
+				Template:   "go",
+				WorkingDir: "somedir",
+				User:       "someuser",
+				Shell:      "bash",
+				Envs:       conv.KeyValues{{Key: "one", Value: "1"}},
+				Unless:     []string{"test -e /opt/agent"},
+			},
+		},
+		{
+			typeName: "nameAsSourceType",
+			path:     "nameAsSourcePath",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/opt/scripts/setup.sh"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.ArgsField, Value: "--all"}},
+			},
+			expectedTask: &cmdscript.Task{
+				TypeName: "nameAsSourceType",
+				Path:     "nameAsSourcePath",
+				Name:     "/opt/scripts/setup.sh",
+				Source:   utils.ParseLocation("/opt/scripts/setup.sh"),
+				Args:     []string{"--all"},
+			},
+		},
+		{
+			typeName: "someTypeWithErrors",
+			path:     "somePathWithErrors",
+			ctx: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.EnvField, Value: 123}},
+			},
+			expectedError: "key value array expected at 'somePathWithErrors' but got '123': env",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.typeName, func(t *testing.T) {
+			taskBuilder := TaskBuilder{}
+			actualTask, err := taskBuilder.Build(
+				tc.typeName,
+				tc.path,
+				tc.ctx,
+			)
+
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			if err != nil {
+				return
+			}
+
+			assert.Equal(t, tc.expectedTask, actualTask)
+		})
+	}
+}
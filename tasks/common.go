@@ -1,12 +1,19 @@
 package tasks
 
-import "github.com/realvnc-labs/tacoscript/tasks/shared/fieldstatus"
+import (
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/fieldstatus"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
 
 type Scripts []Script
 
 type Script struct {
 	ID    string
 	Tasks []CoreTask
+	// any failed task stops the script execution, set by the failhard option of the script file
+	FailHard bool
 }
 
 type CoreTask interface {
@@ -26,3 +33,29 @@ type TaskWithFieldTracker interface {
 	SetMapper(mapper fieldstatus.NameMapper)
 	SetTracker(tracker fieldstatus.Tracker)
 }
+
+// ResultDescription is the name, comment and changes of a task in the script result
+type ResultDescription struct {
+	Name    string
+	Comment string
+	// replaces the changes of the execution result if not nil
+	Changes map[string]interface{}
+}
+
+// TaskWithResultDescription is implemented by the tasks which describe their execution result in the script result,
+// the comment of the execution result is used for the other tasks
+type TaskWithResultDescription interface {
+	DescribeResult(res *executionresult.ExecutionResult) ResultDescription
+}
+
+// TaskWithSource is implemented by the tasks which read a source file, the relative sources of a remote script
+// are resolved against the script URL
+type TaskWithSource interface {
+	GetSource() *utils.Location
+}
+
+// TaskWithEnvs is implemented by the tasks which pass env variables to their commands, the values of the
+// secret env variables are masked in all output
+type TaskWithEnvs interface {
+	GetEnvs() conv.KeyValues
+}
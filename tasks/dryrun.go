@@ -0,0 +1,34 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDryRunNotSupported is the error of the tasks which types don't support the dry run
+var ErrDryRunNotSupported = errors.New("the task type doesn't support the dry run")
+
+type dryRunContextKey struct{}
+
+// WithDryRun gives a context in which the executors report the changes they would make without making them
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, true)
+}
+
+// IsDryRun tells if the context is a dry run
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey{}).(bool)
+	return dryRun
+}
+
+// DryRunExecutor is implemented by the executors which support the dry run, in a dry run they set the DryRun
+// flag of the execution result and report the changes they would make as the changes
+type DryRunExecutor interface {
+	Executor
+	SupportsDryRun() bool
+}
+
+func supportsDryRun(executor Executor) bool {
+	dryRunExecutor, ok := executor.(DryRunExecutor)
+	return ok && dryRunExecutor.SupportsDryRun()
+}
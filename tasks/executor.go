@@ -2,9 +2,16 @@ package tasks
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/exec"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/utils"
 )
 
 type Executor interface {
@@ -15,11 +22,196 @@ type ExecutorRouter struct {
 	Executors map[string]Executor
 }
 
+// GetExecutor gives the executor of the task type, which additionally applies the execution policy of the task
 func (er ExecutorRouter) GetExecutor(task CoreTask) (Executor, error) {
 	e, ok := er.Executors[task.GetTypeName()]
 	if !ok {
 		return nil, fmt.Errorf("cannot find executor for task %s", task.GetTypeName())
 	}
 
-	return e, nil
+	return PolicyExecutor{Executor: e}, nil
+}
+
+// TimeoutExecutor is implemented by the executors which stop a running task when its context is done,
+// the timeout of the execution policy is only supported by them
+type TimeoutExecutor interface {
+	Executor
+	SupportsTimeout() bool
+}
+
+// ExitCodeExecutor is implemented by the executors which set the exit code of the task command in the execution result,
+// the tasks of other executors cannot be repeated until an exit code
+type ExitCodeExecutor interface {
+	Executor
+	ReportsExitCode() bool
+}
+
+// ValidatePolicy checks that the executor of the task type can honor the execution policy of the task
+func (er ExecutorRouter) ValidatePolicy(task CoreTask) error {
+	policyTask, ok := task.(TaskWithExecutionPolicy)
+	if !ok {
+		return nil
+	}
+	policy := policyTask.GetExecutionPolicy()
+
+	e, ok := er.Executors[task.GetTypeName()]
+	if !ok {
+		return fmt.Errorf("cannot find executor for task %s", task.GetTypeName())
+	}
+
+	errs := utils.Errors{}
+	if policy.Timeout > 0 && !supportsTimeout(e) {
+		errs.Add(fmt.Errorf("'%s' at path '%s' is not supported by the task type %s", TimeoutField, task.GetPath(), task.GetTypeName()))
+	}
+
+	if policy.Retry.UntilExitCode != nil && !reportsExitCode(e) {
+		errs.Add(fmt.Errorf(
+			"'%s.%s' at path '%s' is not supported by the task type %s, it runs no command with an exit code",
+			RetryField,
+			UntilField,
+			task.GetPath(),
+			task.GetTypeName(),
+		))
+	}
+
+	return errs.ToError()
+}
+
+func supportsTimeout(executor Executor) bool {
+	timeoutExecutor, ok := executor.(TimeoutExecutor)
+	return ok && timeoutExecutor.SupportsTimeout()
+}
+
+func reportsExitCode(executor Executor) bool {
+	exitCodeExecutor, ok := executor.(ExitCodeExecutor)
+	return ok && exitCodeExecutor.ReportsExitCode()
+}
+
+// PolicyExecutor repeats the failed runs of a task and cancels the runs exceeding the timeout
+// according to the ExecutionPolicy of the task
+type PolicyExecutor struct {
+	Executor Executor
+}
+
+func (pe PolicyExecutor) Execute(ctx context.Context, task CoreTask) executionresult.ExecutionResult {
+	if IsDryRun(ctx) && !supportsDryRun(pe.Executor) {
+		return executionresult.ExecutionResult{
+			Err: fmt.Errorf("%w: %s", ErrDryRunNotSupported, task.GetTypeName()),
+		}
+	}
+
+	policyTask, ok := task.(TaskWithExecutionPolicy)
+	if !ok {
+		return pe.Executor.Execute(ctx, task)
+	}
+	policy := policyTask.GetExecutionPolicy()
+
+	attempts := policy.Retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	start := time.Now()
+	attemptResults := []executionresult.AttemptResult{}
+	for attempt := 1; ; attempt++ {
+		res := pe.executeAttempt(ctx, task, policy)
+
+		if policy.Retry.Attempts > 1 {
+			attemptResults = append(attemptResults, executionresult.NewAttemptResult(attempt, &res))
+			res.Attempts = attemptResults
+		}
+
+		if res.Succeeded() || attempt >= attempts || ctx.Err() != nil {
+			if attempt > 1 {
+				res.Duration = time.Since(start)
+			}
+			if errors.As(res.Err, &untilExitCodeError{}) {
+				res.Err = fmt.Errorf("command did not exit with code %d after %d attempt(s)", *policy.Retry.UntilExitCode, attempt)
+			}
+			return res
+		}
+
+		delay := retryDelay(policy.Retry)
+		logrus.Infof(
+			"task at path '%s' did not succeed: %s, will retry in %s (attempt %d of %d)",
+			task.GetPath(),
+			res.Err,
+			delay,
+			attempt+1,
+			attempts,
+		)
+
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(delay):
+		}
+	}
+}
+
+// executeAttempt runs the task once, the run is cancelled if it exceeds the timeout of the policy
+func (pe PolicyExecutor) executeAttempt(ctx context.Context, task CoreTask, policy *ExecutionPolicy) executionresult.ExecutionResult {
+	attemptCtx := ctx
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	res := pe.Executor.Execute(attemptCtx, task)
+
+	timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	if timedOut && !res.Succeeded() {
+		res.Err = toTimeoutError(res.Err, policy.Timeout)
+		res.ExitCode = exec.ExitCodeOf(res.Err)
+		return res
+	}
+
+	if policy.Retry.UntilExitCode != nil && !res.IsSkipped {
+		res.Err = checkUntilExitCode(&res, *policy.Retry.UntilExitCode)
+	}
+
+	return res
+}
+
+func toTimeoutError(err error, timeout time.Duration) error {
+	timeoutErr := exec.TimeoutError{Timeout: timeout}
+	if errors.As(err, &exec.RunError{}) {
+		// the killed command has no meaningful exit code
+		return exec.RunError{Err: timeoutErr, ExitCode: -1}
+	}
+
+	return timeoutErr
+}
+
+// untilExitCodeError is the error of a successful command run which didn't exit with the expected code
+type untilExitCodeError struct {
+	exitCode      int
+	untilExitCode int
+}
+
+func (ue untilExitCodeError) Error() string {
+	return fmt.Sprintf("command exited with code %d instead of %d", ue.exitCode, ue.untilExitCode)
+}
+
+// checkUntilExitCode decides if the run was successful when the task is repeated until its command exits with the code
+func checkUntilExitCode(res *executionresult.ExecutionResult, untilExitCode int) error {
+	if res.ExitCode == untilExitCode {
+		return nil
+	}
+
+	if res.Err == nil {
+		return untilExitCodeError{exitCode: res.ExitCode, untilExitCode: untilExitCode}
+	}
+
+	return res.Err
+}
+
+func retryDelay(retry Retry) time.Duration {
+	if retry.Splay <= 0 {
+		return retry.Interval
+	}
+
+	//nolint:gosec // the splay doesn't need a secure random source
+	return retry.Interval + time.Duration(rand.Int63n(int64(retry.Splay)))
 }
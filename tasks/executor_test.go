@@ -0,0 +1,268 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+)
+
+type policyTaskMock struct {
+	ExecutionPolicy
+	Path string
+}
+
+func (ptm *policyTaskMock) GetTypeName() string           { return "mock" }
+func (ptm *policyTaskMock) Validate(goos string) error    { return nil }
+func (ptm *policyTaskMock) GetPath() string               { return ptm.Path }
+func (ptm *policyTaskMock) GetRequirements() []string     { return nil }
+func (ptm *policyTaskMock) GetCreatesFilesList() []string { return nil }
+func (ptm *policyTaskMock) GetOnlyIfCmds() []string       { return nil }
+func (ptm *policyTaskMock) GetUnlessCmds() []string       { return nil }
+
+// executorMock gives the results in the order of the calls, the last result is repeated
+type executorMock struct {
+	results []executionresult.ExecutionResult
+	// the run blocks until the context is done if set
+	blocking bool
+	calls    int
+}
+
+func (em *executorMock) Execute(ctx context.Context, task CoreTask) executionresult.ExecutionResult {
+	em.calls++
+
+	if em.blocking {
+		<-ctx.Done()
+		return executionresult.ExecutionResult{Err: exec.RunError{Err: ctx.Err(), ExitCode: -1}}
+	}
+
+	if em.calls > len(em.results) {
+		return em.results[len(em.results)-1]
+	}
+
+	return em.results[em.calls-1]
+}
+
+func exitCode(code int) *int {
+	return &code
+}
+
+func failedResult(exitCode int) executionresult.ExecutionResult {
+	return executionresult.ExecutionResult{
+		Err:      exec.RunError{Err: fmt.Errorf("exit status %d", exitCode), ExitCode: exitCode},
+		ExitCode: exitCode,
+	}
+}
+
+func TestPolicyExecutor(t *testing.T) {
+	testCases := []struct {
+		name             string
+		policy           ExecutionPolicy
+		results          []executionresult.ExecutionResult
+		expectedErr      string
+		expectedCalls    int
+		expectedAttempts int
+	}{
+		{
+			name:          "no retries",
+			results:       []executionresult.ExecutionResult{failedResult(1)},
+			expectedErr:   "exit status 1",
+			expectedCalls: 1,
+		},
+		{
+			name: "success after retries",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 3, Interval: time.Millisecond},
+			},
+			results:          []executionresult.ExecutionResult{failedResult(1), failedResult(2), {}},
+			expectedCalls:    3,
+			expectedAttempts: 3,
+		},
+		{
+			name: "attempts exhausted",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 2, Interval: time.Millisecond, Splay: time.Millisecond},
+			},
+			results:          []executionresult.ExecutionResult{failedResult(1)},
+			expectedErr:      "exit status 1",
+			expectedCalls:    2,
+			expectedAttempts: 2,
+		},
+		{
+			name: "until exit code reached",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 3, UntilExitCode: exitCode(3)},
+			},
+			results:          []executionresult.ExecutionResult{{}, failedResult(3)},
+			expectedCalls:    2,
+			expectedAttempts: 2,
+		},
+		{
+			name: "until exit code not reached",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 2, UntilExitCode: exitCode(3)},
+			},
+			results:          []executionresult.ExecutionResult{{}},
+			expectedErr:      "command did not exit with code 3 after 2 attempt(s)",
+			expectedCalls:    2,
+			expectedAttempts: 2,
+		},
+		{
+			name: "until exit code zero",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 3, UntilExitCode: exitCode(0)},
+			},
+			results:          []executionresult.ExecutionResult{failedResult(1), {}},
+			expectedCalls:    2,
+			expectedAttempts: 2,
+		},
+		{
+			name: "skipped task is not repeated until exit code",
+			policy: ExecutionPolicy{
+				Retry: Retry{Attempts: 2, UntilExitCode: exitCode(3)},
+			},
+			results:          []executionresult.ExecutionResult{{IsSkipped: true}},
+			expectedCalls:    1,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			executor := &executorMock{results: tc.results}
+			task := &policyTaskMock{ExecutionPolicy: tc.policy, Path: "somepath"}
+
+			res := PolicyExecutor{Executor: executor}.Execute(context.Background(), task)
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, res.Err)
+			} else {
+				assert.EqualError(t, res.Err, tc.expectedErr)
+			}
+			assert.Equal(t, tc.expectedCalls, executor.calls)
+			assert.Len(t, res.Attempts, tc.expectedAttempts)
+			for i, attempt := range res.Attempts {
+				assert.Equal(t, i+1, attempt.Attempt)
+			}
+		})
+	}
+}
+
+func TestPolicyExecutorTimeout(t *testing.T) {
+	executor := &executorMock{blocking: true}
+	task := &policyTaskMock{
+		ExecutionPolicy: ExecutionPolicy{
+			Timeout: 10 * time.Millisecond,
+			Retry:   Retry{Attempts: 2},
+		},
+	}
+
+	res := PolicyExecutor{Executor: executor}.Execute(context.Background(), task)
+
+	assert.EqualError(t, res.Err, "command timed out after 10ms")
+	assert.ErrorIs(t, res.Err, exec.TimeoutError{Timeout: 10 * time.Millisecond})
+	assert.Equal(t, -1, res.ExitCode)
+	assert.Equal(t, 2, executor.calls)
+	assert.Len(t, res.Attempts, 2)
+}
+
+func TestPolicyExecutorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	executor := &executorMock{results: []executionresult.ExecutionResult{failedResult(1)}}
+	task := &policyTaskMock{
+		ExecutionPolicy: ExecutionPolicy{
+			Retry: Retry{Attempts: 3, Interval: time.Hour},
+		},
+	}
+
+	res := PolicyExecutor{Executor: executor}.Execute(ctx, task)
+
+	assert.EqualError(t, res.Err, "exit status 1")
+	assert.Equal(t, 1, executor.calls)
+}
+
+// dryRunExecutorMock is an executor mock which supports the dry run
+type dryRunExecutorMock struct {
+	executorMock
+}
+
+func (dem *dryRunExecutorMock) SupportsDryRun() bool { return true }
+
+func TestPolicyExecutorDryRun(t *testing.T) {
+	ctx := WithDryRun(context.Background())
+	task := &policyTaskMock{Path: "somepath"}
+
+	unsupported := &executorMock{results: []executionresult.ExecutionResult{{}}}
+	res := PolicyExecutor{Executor: unsupported}.Execute(ctx, task)
+	assert.ErrorIs(t, res.Err, ErrDryRunNotSupported)
+	assert.EqualError(t, res.Err, "the task type doesn't support the dry run: mock")
+	assert.Equal(t, 0, unsupported.calls)
+
+	supported := &dryRunExecutorMock{executorMock{results: []executionresult.ExecutionResult{{DryRun: true}}}}
+	res = PolicyExecutor{Executor: supported}.Execute(ctx, task)
+	assert.NoError(t, res.Err)
+	assert.True(t, res.DryRun)
+	assert.Equal(t, 1, supported.calls)
+}
+
+// commandExecutorMock is an executor mock which supports the timeout and reports the exit code
+type commandExecutorMock struct {
+	executorMock
+}
+
+func (cem *commandExecutorMock) SupportsTimeout() bool { return true }
+func (cem *commandExecutorMock) ReportsExitCode() bool { return true }
+
+func TestValidatePolicy(t *testing.T) {
+	policy := ExecutionPolicy{Timeout: time.Second, Retry: Retry{Attempts: 2, UntilExitCode: exitCode(0)}}
+	task := &policyTaskMock{ExecutionPolicy: policy, Path: "somepath"}
+
+	router := ExecutorRouter{Executors: map[string]Executor{"mock": &commandExecutorMock{}}}
+	assert.NoError(t, router.ValidatePolicy(task))
+
+	router = ExecutorRouter{Executors: map[string]Executor{"mock": &executorMock{}}}
+	assert.EqualError(
+		t,
+		router.ValidatePolicy(task),
+		"'timeout' at path 'somepath' is not supported by the task type mock, "+
+			"'retry.until' at path 'somepath' is not supported by the task type mock, it runs no command with an exit code",
+	)
+
+	assert.NoError(t, router.ValidatePolicy(&policyTaskMock{Path: "somepath"}))
+}
+
+func TestExecutionPolicyValidation(t *testing.T) {
+	testCases := []struct {
+		policy        ExecutionPolicy
+		expectedError string
+	}{
+		{
+			policy: ExecutionPolicy{Timeout: time.Second, Retry: Retry{Attempts: 2, Interval: time.Second}},
+		},
+		{
+			policy:        ExecutionPolicy{Timeout: -time.Second},
+			expectedError: "negative timeout at path 'somepath.timeout'",
+		},
+		{
+			policy:        ExecutionPolicy{Retry: Retry{Splay: -time.Second}},
+			expectedError: "negative retry value at path 'somepath.retry'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.policy.Validate("somepath")
+		if testCase.expectedError == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, testCase.expectedError)
+		}
+	}
+}
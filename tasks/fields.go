@@ -13,22 +13,42 @@ const (
 	PatternField = "pattern"
 	ReplField    = "repl"
 
-	CwdField          = "cwd"
-	UserField         = "user"
-	ShellField        = "shell"
-	EnvField          = "env"
-	SourceField       = "source"
-	SourceHashField   = "source_hash"
-	MakeDirsField     = "makedirs"
-	ReplaceField      = "replace"
-	SkipVerifyField   = "skip_verify"
-	ContentsField     = "contents"
-	GroupField        = "group"
-	ModeField         = "mode"
-	EncodingField     = "encoding"
-	AbortOnErrorField = "abort_on_error"
-	Version           = "version"
-	Refresh           = "refresh"
+	CwdField             = "cwd"
+	UserField            = "user"
+	ShellField           = "shell"
+	EnvField             = "env"
+	SourceField          = "source"
+	SourceHashField      = "source_hash"
+	MakeDirsField        = "makedirs"
+	ReplaceField         = "replace"
+	SkipVerifyField      = "skip_verify"
+	ContentsField        = "contents"
+	GroupField           = "group"
+	ModeField            = "mode"
+	EncodingField        = "encoding"
+	AbortOnErrorField    = "abort_on_error"
+	ContinueOnErrorField = "continue_on_error"
+	FailHardField        = "failhard"
+	TimeoutField         = "timeout"
+	RetryField           = "retry"
+	AttemptsField        = "attempts"
+	IntervalField        = "interval"
+	SplayField           = "splay"
+	UntilField           = "until"
+	MaxOutputField       = "max_output"
+	OutputLogLevel       = "output_loglevel"
+	StreamField          = "stream"
+	BecomeField          = "become"
+	BecomeMethodField    = "become_method"
+	SuccessRetcodesField = "success_retcodes"
+	SuccessStdoutField   = "success_stdout"
+	SuccessStderrField   = "success_stderr"
+	SensitiveField       = "sensitive"
+	StatefulField        = "stateful"
+	ArgsField            = "args"
+	TemplateField        = "template"
+	Version              = "version"
+	Refresh              = "refresh"
 
 	CountField             = "count"
 	AppendIfNotFoundField  = "append_if_not_found"
@@ -36,6 +56,11 @@ const (
 	NotFoundContentField   = "not_found_content"
 	BackupExtensionField   = "backup"
 	MaxFileSizeField       = "max_file_size"
+	FlagsField             = "flags"
+	DeleteLinesField       = "delete_lines"
+	IgnoreIfMissingField   = "ignore_if_missing"
+	DryRunField            = "dry_run"
+	ShowChangesField       = "show_changes"
 
 	RegPathField = "reg_path"
 	ValField     = "value"
@@ -61,6 +86,20 @@ const (
 	UseVNCLicenseReload = "use_vnclicense_reload"
 
 	SkipBackupField = "skip_backup"
+
+	IPField        = "ip"
+	CleanField     = "clean"
+	HostsFileField = "hosts_file"
+
+	SectionsField  = "sections"
+	OptionsField   = "options"
+	SeparatorField = "separator"
+	StrictField    = "strict"
+
+	FormatField = "format"
+	SetField    = "set"
+	MergeField  = "merge"
+	DeleteField = "delete"
 )
 
 var (
@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/realvnc-labs/tacoscript/state"
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
@@ -27,6 +28,8 @@ const (
 )
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	TypeName string
 	Path     string
 	Mode     os.FileMode
@@ -109,17 +112,43 @@ func (t *Task) GetCreatesFilesList() []string {
 	return t.Creates
 }
 
+func (t *Task) GetSource() *utils.Location {
+	return &t.Source
+}
+
 type HashManager interface {
 	HashEquals(hashStr, filePath string) (hashEquals bool, actualCache string, err error)
 	HashSum(hashAlgoName, filePath string) (hashSum string, err error)
 }
 
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "File not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    t.Name,
+		Comment: comment,
+	}
+}
+
 type Executor struct {
 	FsManager   tasks.FsManager
 	HashManager HashManager
 	Runner      tacoexec.Runner
 }
 
+// SupportsDryRun tells that the executor reports the file changes without making them in a dry run
+func (fmte *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// SupportsTimeout tells that the download of the source file is cancelled when the context is done
+func (fmte *Executor) SupportsTimeout() bool {
+	return true
+}
+
 func (fmte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
 	logrus.Debugf("will trigger '%s' task", task.GetPath())
 	execRes := executionresult.ExecutionResult{
@@ -168,6 +197,18 @@ func (fmte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 		return execRes
 	}
 
+	if tasks.IsDryRun(ctx) {
+		execRes.Err = fmte.dryRun(fileManagedTask, &execRes)
+		return execRes
+	}
+
+	journal := state.JournalFromContext(ctx)
+	if err = journal.Snapshot(fileManagedTask.Name); err != nil {
+		execRes.Err = err
+		return execRes
+	}
+	defer journal.RecordOrLog(task.GetPath(), fileManagedTask.Name)
+
 	start := time.Now()
 
 	fileShouldBeReplaced, err := fmte.fileShouldBeReplaced(fileManagedTask)
@@ -219,6 +260,68 @@ func (fmte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 	return execRes
 }
 
+// dryRun reports the changes of the contents, mode and ownership which the task would make to the file
+func (fmte *Executor) dryRun(fileManagedTask *Task, execRes *executionresult.ExecutionResult) error {
+	execRes.DryRun = true
+
+	fileShouldBeReplaced, err := fmte.fileShouldBeReplaced(fileManagedTask)
+	if err != nil {
+		return err
+	}
+
+	source := fileManagedTask.Source
+	switch {
+	case !fileShouldBeReplaced:
+		// the content check reports the diff even if an existing file is not replaced
+		delete(execRes.Changes, "diff")
+		delete(execRes.Changes, "size_diff")
+	case fileManagedTask.Contents.Valid:
+		// the diff of the contents is reported by the content check
+	case source.IsURL:
+		execRes.Changes["source"] = source.RawLocation
+	case source.RawLocation != "":
+		shouldBeCopied, err := fmte.checkIfLocalFileShouldBeCopied(fileManagedTask, source.LocalPath)
+		if err != nil {
+			return err
+		}
+		if shouldBeCopied {
+			execRes.Changes["source"] = source.RawLocation
+		}
+	}
+
+	fileExists, err := fmte.FsManager.FileExists(fileManagedTask.Name)
+	if err != nil {
+		return err
+	}
+
+	if fileExists {
+		info, err := fmte.FsManager.Stat(fileManagedTask.Name)
+		if err != nil {
+			return err
+		}
+
+		if fileManagedTask.Mode > 0 && fileManagedTask.Mode != info.Mode() {
+			execRes.Changes["mode"] = fmt.Sprintf("%v -> %v", info.Mode(), fileManagedTask.Mode)
+		}
+
+		if fileManagedTask.User != "" || fileManagedTask.Group != "" {
+			owned, err := utils.IsOwnedBy(fileManagedTask.Name, fileManagedTask.User, fileManagedTask.Group)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				execRes.Changes["owner"] = fileManagedTask.User + ":" + fileManagedTask.Group
+			}
+		}
+	}
+
+	if len(execRes.Changes) > 0 {
+		execRes.Comment = "File would be updated"
+	}
+
+	return nil
+}
+
 func (fmte *Executor) fileShouldBeReplaced(fileManagedTask *Task) (bool, error) {
 	if fileManagedTask.Replace {
 		return true, nil
@@ -277,14 +380,16 @@ func (fmte *Executor) copySourceToTarget(ctx context.Context, fileManagedTask *T
 		return nil
 	}
 
-	if !source.IsURL {
+	if !source.IsURL && source.VerifiedContents == nil {
 		return fmte.handleLocalSource(fileManagedTask, source.LocalPath)
 	}
 
-	return fmte.handleRemoteSource(ctx, fileManagedTask)
+	return fmte.handleTempSource(ctx, fileManagedTask)
 }
 
-func (fmte *Executor) handleRemoteSource(ctx context.Context, fileManagedTask *Task) error {
+// handleTempSource copies a remote source or the verified contents of a local source to a temp location
+// and moves it to the target location if the target should be changed
+func (fmte *Executor) handleTempSource(ctx context.Context, fileManagedTask *Task) error {
 	tempTargetPath := fileManagedTask.Name + "_temp"
 
 	defer func(f string) {
@@ -299,13 +404,12 @@ func (fmte *Executor) handleRemoteSource(ctx context.Context, fileManagedTask *T
 		}
 	}(tempTargetPath)
 
-	err := fmte.FsManager.DownloadFile(ctx, tempTargetPath, fileManagedTask.Source.URL, fileManagedTask.SkipTLSCheck)
-
+	err := fmte.copySourceToTemp(ctx, fileManagedTask, tempTargetPath)
 	if err != nil {
 		return err
 	}
 	logrus.Debugf(
-		"copied remove source '%s' to a temp location '%s'",
+		"copied source '%s' to a temp location '%s'",
 		fileManagedTask.Source.RawLocation,
 		tempTargetPath,
 	)
@@ -332,6 +436,19 @@ func (fmte *Executor) handleRemoteSource(ctx context.Context, fileManagedTask *T
 	return nil
 }
 
+func (fmte *Executor) copySourceToTemp(ctx context.Context, fileManagedTask *Task, tempTargetPath string) error {
+	if fileManagedTask.Source.VerifiedContents == nil {
+		return fmte.FsManager.DownloadFile(ctx, tempTargetPath, fileManagedTask.Source.URL, fileManagedTask.SkipTLSCheck)
+	}
+
+	mode := os.FileMode(DefaultFileMode)
+	if fileManagedTask.Mode > 0 {
+		mode = fileManagedTask.Mode
+	}
+
+	return fmte.FsManager.WriteFile(tempTargetPath, string(fileManagedTask.Source.VerifiedContents), mode)
+}
+
 func (fmte *Executor) handleLocalSource(fileManagedTask *Task, sourcePath string) error {
 	logrus.Debug("source location is a local file path")
 	source := fileManagedTask.Source
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -759,6 +760,88 @@ func TestFileManagedUserAndGroup(t *testing.T) {
 	}
 }
 
+func TestFileManagedDryRun(t *testing.T) {
+	testCases := []struct {
+		name            string
+		initialContents string
+		noInitialFile   bool
+		contents        string
+		mode            os.FileMode
+		expectedChanges []string
+		expectedComment string
+		expectedSkipped bool
+	}{
+		{
+			name:            "changed contents and mode",
+			initialContents: "one",
+			contents:        "two",
+			mode:            0600,
+			expectedChanges: []string{"diff", "mode", "size_diff"},
+			expectedComment: "File would be updated",
+		},
+		{
+			name:            "missing file",
+			noInitialFile:   true,
+			contents:        "two",
+			expectedChanges: []string{"diff", "size_diff"},
+			expectedComment: "File would be updated",
+		},
+		{
+			name:            "desired state",
+			initialContents: "two",
+			contents:        "two",
+			mode:            0640,
+			expectedComment: "File not changed file '%s' matched with the expected contents, will skip the execution",
+			expectedSkipped: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			if !tc.noInitialFile {
+				assert.NoError(t, os.WriteFile(filePath, []byte(tc.initialContents), 0640))
+				assert.NoError(t, os.Chmod(filePath, 0640))
+			}
+
+			task := &Task{
+				Name:       filePath,
+				SkipVerify: true,
+				Replace:    true,
+				Contents:   sql.NullString{Valid: true, String: tc.contents},
+				Mode:       tc.mode,
+			}
+
+			executor := &Executor{
+				FsManager:   &utils.FsManager{},
+				HashManager: &utils.HashManager{},
+			}
+
+			res := executor.Execute(tasks.WithDryRun(context.Background()), task)
+			assert.NoError(t, res.Err)
+			assert.Equal(t, tc.expectedSkipped, res.IsSkipped)
+			assert.Equal(t, !tc.expectedSkipped, res.DryRun)
+
+			changes := make([]string, 0, len(res.Changes))
+			for key := range res.Changes {
+				changes = append(changes, key)
+			}
+			assert.ElementsMatch(t, tc.expectedChanges, changes)
+			assert.Equal(t, strings.ReplaceAll(tc.expectedComment, "%s", filePath), task.DescribeResult(&res).Comment)
+
+			if tc.noInitialFile {
+				assert.NoFileExists(t, filePath)
+				return
+			}
+
+			actualContents, err := os.ReadFile(filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.initialContents, string(actualContents))
+		})
+	}
+}
+
 func TestFileManagedTaskValidation(t *testing.T) {
 	testCases := []struct {
 		Name          string
@@ -5,11 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/realvnc-labs/tacoscript/conv"
 	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/state"
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
@@ -20,8 +23,23 @@ import (
 var (
 	ErrAppendAndPrependSetAtTheSameTime = errors.New("append_if_not_found and prepend_if_not_found cannot be set at the same time." +
 		"please set one or the other")
+	ErrDeleteLinesWithNotFoundContent = errors.New("delete_lines cannot be used together with append_if_not_found or " +
+		"prepend_if_not_found")
+	ErrUnknownFlag = errors.New("unknown regular expression flag")
 )
 
+// regexpFlags maps the supported flag names to the inline flags of the golang regexp engine
+var regexpFlags = map[string]string{
+	"IGNORECASE": "i",
+	"I":          "i",
+	"MULTILINE":  "m",
+	"M":          "m",
+	"DOTALL":     "s",
+	"S":          "s",
+	"UNGREEDY":   "U",
+	"U":          "U",
+}
+
 const (
 	TaskType = "file.replace"
 
@@ -29,6 +47,8 @@ const (
 )
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	TypeName string // TaskType
 	Path     string // TaskName
 
@@ -41,6 +61,11 @@ type Task struct {
 	NotFoundContent   string   `taco:"not_found_content"`
 	BackupExtension   string   `taco:"backup"`
 	MaxFileSize       string   `taco:"max_file_size"`
+	Flags             []string `taco:"flags"`
+	DeleteLines       bool     `taco:"delete_lines"`
+	IgnoreIfMissing   bool     `taco:"ignore_if_missing"`
+	DryRun            bool     `taco:"dry_run"`
+	ShowChanges       bool     `taco:"show_changes"`
 	Require           []string `taco:"require"`
 	Creates           []string `taco:"creates"`
 	OnlyIf            []string `taco:"onlyif"`
@@ -97,7 +122,17 @@ func (t *Task) Validate(goos string) error {
 	}
 
 	if t.Pattern != "" {
-		compiledRegExp, err := regexp.Compile(t.Pattern)
+		flags, err := convertFlags(t.Flags)
+		if err != nil {
+			errs.Add(fmt.Errorf("%w at path '%s.%s'", err, t.Path, tasks.FlagsField))
+		}
+
+		pattern := t.Pattern
+		if flags != "" {
+			pattern = "(?" + flags + ")" + pattern
+		}
+
+		compiledRegExp, err := regexp.Compile(pattern)
 		if err != nil {
 			errs.Add(err)
 		}
@@ -118,14 +153,36 @@ func (t *Task) Validate(goos string) error {
 		errs.Add(ErrAppendAndPrependSetAtTheSameTime)
 	}
 
+	if t.DeleteLines && (t.AppendIfNotFound || t.PrependIfNotFound) {
+		errs.Add(ErrDeleteLinesWithNotFoundContent)
+	}
+
 	return errs.ToError()
 }
 
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	// dry runs report the changes which would be made without updating the file
+	if res.Err == nil && !t.Updated && len(res.Changes) == 0 {
+		comment = "File not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    t.Name,
+		Comment: comment,
+	}
+}
+
 type Executor struct {
 	FsManager tasks.FsManager
 	Runner    tacoexec.Runner
 }
 
+// SupportsDryRun tells that the executor reports the replacements without making them in a dry run
+func (frte *Executor) SupportsDryRun() bool {
+	return true
+}
+
 func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
 	logrus.Debugf("will trigger '%s' task", task.GetPath())
 	execRes := executionresult.ExecutionResult{
@@ -168,6 +225,12 @@ func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 
 	origfileInfo, err := frte.FsManager.Stat(origFilename)
 	if err != nil {
+		if frt.IgnoreIfMissing && errors.Is(err, os.ErrNotExist) {
+			logrus.Debugf("the task '%s' will be be skipped", task.GetPath())
+			execRes.IsSkipped = true
+			execRes.SkipReason = "file does not exist"
+			return execRes
+		}
 		execRes.Err = err
 		return execRes
 	}
@@ -186,8 +249,11 @@ func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 
 	start := time.Now()
 
+	dryRun := frt.DryRun || tasks.IsDryRun(ctx)
+	execRes.DryRun = dryRun
+
 	backupFilename := ""
-	makeBackup := frt.BackupExtension != ""
+	makeBackup := frt.BackupExtension != "" && !dryRun
 
 	if makeBackup {
 		backupFilename = makeBackupFilename(origFilename, frt.BackupExtension)
@@ -202,15 +268,22 @@ func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 	var updatedFileContents string
 	var replacementCount int
 	var additionsCount int
+	var deletionsCount int
 
 	match := frt.patternCompiled.MatchString(origFileContents)
-	if match {
+	switch {
+	case match && frt.DeleteLines:
+		updatedFileContents, deletionsCount = DeleteMatchingLinesWithCount(
+			origFileContents,
+			frt.patternCompiled,
+			frt.Count)
+	case match:
 		updatedFileContents, replacementCount = ReplaceUsingRegexpWithCount(
 			origFileContents,
 			frt.patternCompiled,
 			frt.Repl,
 			frt.Count)
-	} else {
+	case frt.AppendIfNotFound || frt.PrependIfNotFound:
 		newContent := frt.Repl
 		if frt.NotFoundContent != "" {
 			newContent = frt.NotFoundContent
@@ -218,13 +291,31 @@ func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 		additionsCount = 1
 		if frt.AppendIfNotFound {
 			updatedFileContents = origFileContents + newContent
-		} else if frt.PrependIfNotFound {
+		} else {
 			updatedFileContents = newContent + origFileContents
 		}
 	}
 
-	// will only be non-nil if the original contents have been updated
-	if updatedFileContents != "" {
+	// the updated contents might be empty when all lines are deleted, so the counts are checked instead
+	fileChanged := replacementCount > 0 || additionsCount > 0 || deletionsCount > 0
+
+	if fileChanged && (frt.ShowChanges || dryRun) {
+		contentDiff, err := utils.UnifiedDiff(origFilename, origFileContents, updatedFileContents)
+		if err != nil {
+			execRes.Err = err
+			return execRes
+		}
+		execRes.Changes["diff"] = contentDiff
+	}
+
+	if fileChanged && !dryRun {
+		journal := state.JournalFromContext(ctx)
+		if err = journal.Snapshot(frt.Name); err != nil {
+			execRes.Err = err
+			return execRes
+		}
+		defer journal.RecordOrLog(task.GetPath(), frt.Name)
+
 		if makeBackup {
 			err := frte.FsManager.WriteFile(backupFilename, origFileContents, origfileInfo.Mode())
 			if err != nil {
@@ -245,11 +336,17 @@ func (frte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 		logrus.Debugf("updated file contents for %s", origFilename)
 	}
 
-	if frt.Updated && execRes.Err == nil {
+	if fileChanged {
 		execRes.Comment = "File updated"
-		if replacementCount > 0 {
+		if dryRun {
+			execRes.Comment = "File would be updated"
+		}
+		switch {
+		case replacementCount > 0:
 			execRes.Changes["count"] = fmt.Sprintf("%d replacement(s) made", replacementCount)
-		} else if additionsCount > 0 {
+		case deletionsCount > 0:
+			execRes.Changes["count"] = fmt.Sprintf("%d deletion(s) made", deletionsCount)
+		case additionsCount > 0:
 			execRes.Changes["count"] = fmt.Sprintf("%d addition(s) made", additionsCount)
 		}
 	}
@@ -281,3 +378,64 @@ func ReplaceUsingRegexpWithCount(contents string, re *regexp.Regexp, repl string
 
 	return replContents, count
 }
+
+// DeleteMatchingLinesWithCount removes all lines which contain a match of the regular expression. If a match spans
+// multiple lines, all of them are removed.
+func DeleteMatchingLinesWithCount(contents string, re *regexp.Regexp, maxDeletions int) (newContents string, deletionCount int) {
+	builder := strings.Builder{}
+	lastEnd := 0
+
+	for _, matchPos := range re.FindAllStringIndex(contents, -1) {
+		if maxDeletions > 0 && deletionCount >= maxDeletions {
+			break
+		}
+
+		if matchPos[0] == len(contents) && matchPos[0] > 0 && contents[matchPos[0]-1] == '\n' {
+			// an empty match after the last line ending is not a line
+			break
+		}
+
+		lineStart := strings.LastIndex(contents[:matchPos[0]], "\n") + 1
+		if lineStart < lastEnd {
+			// the match is on a line which is already deleted
+			continue
+		}
+
+		lineEnd := len(contents)
+		searchFrom := matchPos[1]
+		if matchPos[1] > matchPos[0] && contents[matchPos[1]-1] == '\n' {
+			// the match already includes the line ending
+			searchFrom = matchPos[1] - 1
+		}
+		if newLinePos := strings.Index(contents[searchFrom:], "\n"); newLinePos >= 0 {
+			lineEnd = searchFrom + newLinePos + 1
+		}
+
+		builder.WriteString(contents[lastEnd:lineStart])
+		lastEnd = lineEnd
+		deletionCount++
+	}
+
+	builder.WriteString(contents[lastEnd:])
+
+	return builder.String(), deletionCount
+}
+
+func convertFlags(flags []string) (string, error) {
+	res := ""
+	for _, flagsItem := range flags {
+		for _, flag := range strings.FieldsFunc(flagsItem, func(r rune) bool {
+			return r == ',' || r == '|' || r == ' '
+		}) {
+			inlineFlag, ok := regexpFlags[strings.ToUpper(flag)]
+			if !ok {
+				return "", fmt.Errorf("%w '%s'", ErrUnknownFlag, flag)
+			}
+			if !strings.Contains(res, inlineFlag) {
+				res += inlineFlag
+			}
+		}
+	}
+
+	return res, nil
+}
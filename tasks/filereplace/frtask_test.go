@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
@@ -53,6 +54,25 @@ func TestFileReplaceTaskValidation(t *testing.T) {
 			},
 			ExpectedErrorStr: conv.ErrFileSizeInvalidUnits.Error(),
 		},
+		{
+			Name: "unknown flag",
+			InputTask: Task{
+				Name:    "some p",
+				Pattern: "search for this text",
+				Flags:   []string{"IGNORECASE", "VERBOSE"},
+			},
+			ExpectedErrorStr: "unknown regular expression flag 'VERBOSE' at path '.flags'",
+		},
+		{
+			Name: "delete lines and append",
+			InputTask: Task{
+				Name:             "some p",
+				Pattern:          "search for this text",
+				DeleteLines:      true,
+				AppendIfNotFound: true,
+			},
+			ExpectedErrorStr: ErrDeleteLinesWithNotFoundContent.Error(),
+		},
 		{
 			Name: "append and prepend",
 			InputTask: Task{
@@ -538,3 +558,205 @@ func TestShouldUseReplContentWhenWhenNoNotFoundContent(t *testing.T) {
 	assert.NotEqual(t, -1, index)
 	assert.Equal(t, 29, index)
 }
+
+func TestShouldApplyFlags(t *testing.T) {
+	ctx := context.Background()
+
+	testFilename := getTestFilename()
+
+	WriteTestFile(t, testFilename, "[section]\nLine 1\nline 2\n")
+	defer os.Remove(testFilename)
+
+	executor := &Executor{
+		FsManager: &utils.FsManager{},
+	}
+	task := &Task{
+		Path:    "replace-1",
+		Name:    testFilename,
+		Pattern: `^line (\d)$`,
+		Repl:    "entry $1",
+		Flags:   []string{"IGNORECASE, MULTILINE"},
+	}
+
+	err := task.Validate(runtime.GOOS)
+	require.NoError(t, err)
+
+	res := executor.Execute(ctx, task)
+	require.NoError(t, res.Err)
+	require.True(t, task.Updated)
+
+	assert.Equal(t, "2 replacement(s) made", res.Changes["count"])
+	assert.Equal(t, "[section]\nentry 1\nentry 2\n", ReadFileContents(t, testFilename))
+}
+
+func TestDeleteMatchingLinesWithCount(t *testing.T) {
+	testCases := []struct {
+		name             string
+		contents         string
+		pattern          string
+		maxDeletions     int
+		expectedContents string
+		expectedCount    int
+	}{
+		{
+			name:             "delete all matching lines",
+			contents:         "keep 1\ndelete 1\nkeep 2\ndelete 2\n",
+			pattern:          "delete",
+			expectedContents: "keep 1\nkeep 2\n",
+			expectedCount:    2,
+		},
+		{
+			name:             "delete limited count",
+			contents:         "delete 1\ndelete 2\ndelete 3\n",
+			pattern:          "delete",
+			maxDeletions:     2,
+			expectedContents: "delete 3\n",
+			expectedCount:    2,
+		},
+		{
+			name:             "multiple matches on the same line",
+			contents:         "a a a\nb\n",
+			pattern:          "a",
+			expectedContents: "b\n",
+			expectedCount:    1,
+		},
+		{
+			name:             "last line without line ending",
+			contents:         "keep\ndelete",
+			pattern:          "delete",
+			expectedContents: "keep\n",
+			expectedCount:    1,
+		},
+		{
+			name:             "multiline match",
+			contents:         "keep\n<block>\ninner\n</block>\nkeep\n",
+			pattern:          `(?s)<block>.*</block>`,
+			expectedContents: "keep\nkeep\n",
+			expectedCount:    1,
+		},
+		{
+			name:             "match with line ending",
+			contents:         "keep\ndelete\nkeep\n",
+			pattern:          "delete\n",
+			expectedContents: "keep\nkeep\n",
+			expectedCount:    1,
+		},
+		{
+			name:             "delete everything",
+			contents:         "delete\n",
+			pattern:          "delete",
+			expectedContents: "",
+			expectedCount:    1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			newContents, count := DeleteMatchingLinesWithCount(tc.contents, regexp.MustCompile(tc.pattern), tc.maxDeletions)
+			assert.Equal(t, tc.expectedContents, newContents)
+			assert.Equal(t, tc.expectedCount, count)
+		})
+	}
+}
+
+func TestShouldDeleteAllLinesAndShowChanges(t *testing.T) {
+	ctx := context.Background()
+
+	testFilename := getTestFilename()
+
+	WriteTestFile(t, testFilename, simpleTestFileContentWithRepetition)
+	defer os.Remove(testFilename)
+
+	executor := &Executor{
+		FsManager: &utils.FsManager{},
+	}
+	task := &Task{
+		Path:        "replace-1",
+		Name:        testFilename,
+		Pattern:     `(?m)^(line \d)?$`,
+		DeleteLines: true,
+		ShowChanges: true,
+	}
+
+	err := task.Validate(runtime.GOOS)
+	require.NoError(t, err)
+
+	res := executor.Execute(ctx, task)
+	require.NoError(t, res.Err)
+	require.True(t, task.Updated)
+
+	assert.Equal(t, "File updated", res.Comment)
+	assert.Equal(t, "5 deletion(s) made", res.Changes["count"])
+	assert.Equal(t, fmt.Sprintf(`--- %s
++++ %s
+@@ -1,5 +0,0 @@
+-
+-line 1
+-line 2
+-line 3
+-line 4
+`, testFilename, testFilename), res.Changes["diff"])
+
+	assert.Equal(t, "", ReadFileContents(t, testFilename))
+}
+
+func TestShouldNotChangeFileOnDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	testFilename := getTestFilename()
+
+	WriteTestFile(t, testFilename, simpleTestFileContentWithRepetition)
+	defer os.Remove(testFilename)
+
+	executor := &Executor{
+		FsManager: &utils.FsManager{},
+	}
+	task := &Task{
+		Path:            "replace-1",
+		Name:            testFilename,
+		Pattern:         "line 2",
+		Repl:            "line two",
+		BackupExtension: "bak",
+		DryRun:          true,
+	}
+
+	err := task.Validate(runtime.GOOS)
+	require.NoError(t, err)
+
+	res := executor.Execute(ctx, task)
+	require.NoError(t, res.Err)
+	require.False(t, task.Updated)
+
+	assert.Equal(t, "File would be updated", res.Comment)
+	assert.Equal(t, "1 replacement(s) made", res.Changes["count"])
+	assert.Contains(t, res.Changes["diff"], "-line 2\n+line two\n")
+
+	assert.Equal(t, simpleTestFileContentWithRepetition, ReadFileContents(t, testFilename))
+	assert.NoFileExists(t, testFilename+".bak")
+}
+
+func TestShouldSkipMissingFileWhenIgnoreIfMissing(t *testing.T) {
+	ctx := context.Background()
+
+	executor := &Executor{
+		FsManager: &utils.FsManager{},
+	}
+	task := &Task{
+		Path:            "replace-1",
+		Name:            getTestFilename() + ".missing",
+		Pattern:         "a test",
+		Repl:            "not a test",
+		IgnoreIfMissing: true,
+	}
+
+	err := task.Validate(runtime.GOOS)
+	require.NoError(t, err)
+
+	res := executor.Execute(ctx, task)
+	require.NoError(t, res.Err)
+	require.False(t, task.Updated)
+
+	assert.True(t, res.IsSkipped)
+	assert.Equal(t, "file does not exist", res.SkipReason)
+}
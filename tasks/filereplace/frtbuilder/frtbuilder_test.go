@@ -31,6 +31,11 @@ func TestTaskBuilder(t *testing.T) {
 				yaml.MapSlice{yaml.MapItem{Key: tasks.NotFoundContentField, Value: "new text when not found"}},
 				yaml.MapSlice{yaml.MapItem{Key: tasks.BackupExtensionField, Value: "bak"}},
 				yaml.MapSlice{yaml.MapItem{Key: tasks.MaxFileSizeField, Value: "100k"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.FlagsField, Value: []interface{}{"IGNORECASE", "MULTILINE"}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.DeleteLinesField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.IgnoreIfMissingField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.DryRunField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.ShowChangesField, Value: true}},
 
 				yaml.MapSlice{yaml.MapItem{Key: tasks.CreatesField, Value: "/tmp/creates-file.txt"}},
 				yaml.MapSlice{yaml.MapItem{Key: tasks.OnlyIfField, Value: "/tmp/onlyif-file.txt"}},
@@ -51,6 +56,11 @@ func TestTaskBuilder(t *testing.T) {
 				NotFoundContent:   "new text when not found",
 				BackupExtension:   "bak",
 				MaxFileSize:       "100k",
+				Flags:             []string{"IGNORECASE", "MULTILINE"},
+				DeleteLines:       true,
+				IgnoreIfMissing:   true,
+				DryRun:            true,
+				ShowChanges:       true,
 
 				Creates: []string{"/tmp/creates-file.txt"},
 				OnlyIf:  []string{"/tmp/onlyif-file.txt"},
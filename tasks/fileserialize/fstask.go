@@ -0,0 +1,291 @@
+package fileserialize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/tasks/support/structured"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+const (
+	TaskType = "file.serialize"
+
+	DefaultFileMode = 0644
+)
+
+type Task struct {
+	tasks.ExecutionPolicy
+
+	TypeName string // TaskType
+	Path     string // TaskName
+
+	// values to set by the dot separated path
+	Set yaml.MapSlice
+	// values to merge recursively into the document
+	Merge yaml.MapSlice
+
+	Name            string   `taco:"name"`
+	Format          string   `taco:"format"`
+	Delete          []string `taco:"delete"`
+	BackupExtension string   `taco:"backup"`
+	Require         []string `taco:"require"`
+	Creates         []string `taco:"creates"`
+	OnlyIf          []string `taco:"onlyif"`
+	Unless          []string `taco:"unless"`
+	Shell           string   `taco:"shell"`
+
+	// was the file updated?
+	Updated bool
+}
+
+func (t *Task) GetTypeName() string {
+	return t.TypeName
+}
+
+func (t *Task) GetRequirements() []string {
+	return t.Require
+}
+
+func (t *Task) GetPath() string {
+	return t.Path
+}
+
+func (t *Task) String() string {
+	return fmt.Sprintf("task '%s' at path '%s'", t.TypeName, t.GetPath())
+}
+
+func (t *Task) GetOnlyIfCmds() []string {
+	return t.OnlyIf
+}
+
+func (t *Task) GetUnlessCmds() []string {
+	return t.Unless
+}
+
+func (t *Task) GetCreatesFilesList() []string {
+	return t.Creates
+}
+
+func (t *Task) Validate(goos string) error {
+	errs := &utils.Errors{}
+
+	errs.Add(tasks.ValidateRequired(t.Name, t.Path+"."+tasks.NameField))
+
+	if len(t.Set) == 0 && len(t.Merge) == 0 && len(t.Delete) == 0 {
+		errs.Add(fmt.Errorf(
+			"either '%s', '%s' or '%s' should be provided for the task at path '%s'",
+			tasks.SetField,
+			tasks.MergeField,
+			tasks.DeleteField,
+			t.Path,
+		))
+	}
+
+	if t.Name != "" || t.Format != "" {
+		if _, err := structured.ParseFormat(t.Format, t.Name); err != nil {
+			errs.Add(fmt.Errorf("%w at path '%s.%s'", err, t.Path, tasks.FormatField))
+		}
+	}
+
+	return errs.ToError()
+}
+
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "File not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    t.Name,
+		Comment: comment,
+	}
+}
+
+type Executor struct {
+	FsManager tasks.FsManager
+	Runner    tacoexec.Runner
+}
+
+// SupportsDryRun tells that the executor reports the changes without writing the file in a dry run
+func (fste *Executor) SupportsDryRun() bool {
+	return true
+}
+
+func (fste *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	logrus.Debugf("will trigger '%s' task", task.GetPath())
+	execRes := executionresult.ExecutionResult{
+		Changes: make(map[string]string),
+	}
+
+	fst, ok := task.(*Task)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to Task", task)
+		return execRes
+	}
+
+	execRes.Name = fst.Name
+	execRes.Comment = "File not changed"
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &tacoexec.Context{
+		Ctx:          ctx,
+		Path:         fst.Path,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		Shell:        fst.Shell,
+	}
+
+	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
+	skipReason, err := conditionals.Check(execCtx, fste.FsManager, fste.Runner, fst)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if skipReason != "" {
+		logrus.Debugf("the task '%s' will be be skipped", task.GetPath())
+		execRes.IsSkipped = true
+		execRes.SkipReason = skipReason
+		return execRes
+	}
+
+	start := time.Now()
+
+	execRes.DryRun = tasks.IsDryRun(ctx)
+	err = fste.ExecuteTask(ctx, fst, &execRes)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	execRes.Duration = time.Since(start)
+
+	logrus.Debugf("the task '%s' is finished for %v", task.GetPath(), execRes.Duration)
+	return execRes
+}
+
+func (fste *Executor) ExecuteTask(ctx context.Context, t *Task, res *executionresult.ExecutionResult) error {
+	mode := fs.FileMode(DefaultFileMode)
+	origContents := ""
+	fileExists := true
+
+	info, err := fste.FsManager.Stat(t.Name)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", t.Name)
+		}
+		mode = info.Mode().Perm()
+		origContents, err = fste.FsManager.ReadFile(t.Name)
+		if err != nil {
+			return err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		fileExists = false
+		logrus.Debugf("file '%s' doesn't exist", t.Name)
+		if len(t.Set) == 0 && len(t.Merge) == 0 {
+			return nil
+		}
+	default:
+		return err
+	}
+
+	format, err := structured.ParseFormat(t.Format, t.Name)
+	if err != nil {
+		return err
+	}
+
+	doc, err := structured.Parse(format, origContents)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s file '%s': %w", format, t.Name, err)
+	}
+
+	// explicitly set values win over merged ones, deletions are applied last
+	if len(t.Merge) > 0 {
+		err = doc.Merge(t.Merge)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, item := range t.Set {
+		err = doc.Set(fmt.Sprint(item.Key), item.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range t.Delete {
+		err = doc.Delete(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if doc.Changes.IsEmpty() {
+		logrus.Debugf("file '%s' has the desired contents", t.Name)
+		return nil
+	}
+
+	updatedContents, err := doc.String()
+	if err != nil {
+		return err
+	}
+
+	if res.DryRun {
+		res.Comment = "File would be updated"
+	} else {
+		journal := state.JournalFromContext(ctx)
+		if err = journal.Snapshot(t.Name); err != nil {
+			return err
+		}
+		defer journal.RecordOrLog(t.Path, t.Name)
+
+		if fileExists && t.BackupExtension != "" {
+			backupFilename := utils.GetBackupFilename(t.Name, t.BackupExtension)
+			err = fste.FsManager.WriteFile(backupFilename, origContents, mode)
+			if err != nil {
+				return err
+			}
+			logrus.Debugf("created backup file %s for original file %s", backupFilename, t.Name)
+		}
+
+		err = fste.FsManager.WriteFileAtomic(t.Name, updatedContents, mode)
+		if err != nil {
+			return err
+		}
+
+		logrus.Debugf("updated file '%s'", t.Name)
+
+		t.Updated = true
+		res.Comment = "File updated"
+	}
+
+	if len(doc.Changes.Added) > 0 {
+		res.Changes["added"] = strings.Join(doc.Changes.Added, ", ")
+	}
+	if len(doc.Changes.Changed) > 0 {
+		res.Changes["changed"] = strings.Join(doc.Changes.Changed, ", ")
+	}
+	if len(doc.Changes.Removed) > 0 {
+		res.Changes["removed"] = strings.Join(doc.Changes.Removed, ", ")
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package fileserialize
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestFileSerializeTaskValidation(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		InputTask   Task
+		ExpectedErr string
+	}{
+		{
+			Name: "missing name and values",
+			InputTask: Task{
+				Path: "somepath",
+			},
+			ExpectedErr: fmt.Sprintf(
+				"empty required value at path 'somepath.%s', either '%s', '%s' or '%s' should be provided for the task at path 'somepath'",
+				tasks.NameField,
+				tasks.SetField,
+				tasks.MergeField,
+				tasks.DeleteField,
+			),
+		},
+		{
+			Name: "unknown format",
+			InputTask: Task{
+				Path:   "somepath",
+				Name:   "/tmp/config.conf",
+				Delete: []string{"debug"},
+			},
+			ExpectedErr: "unknown format 'conf', supported formats are json, yaml and toml at path 'somepath.format'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.InputTask.Validate(runtime.GOOS)
+			assert.EqualError(t, err, tc.ExpectedErr)
+		})
+	}
+}
+
+func TestFileSerializeTaskExecution(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Filename         string
+		InitialContents  string
+		NoInitialFile    bool
+		Task             Task
+		ExpectedUpdated  bool
+		ExpectedChanges  map[string]string
+		ExpectedContents string
+		ExpectBackup     bool
+	}{
+		{
+			Name:            "json set merge and delete",
+			Filename:        "config.json",
+			InitialContents: "{\n  \"server\": {\"port\": 8080},\n  \"debug\": true\n}\n",
+			Task: Task{
+				Set: yaml.MapSlice{{Key: "server.tls.enabled", Value: true}},
+				Merge: yaml.MapSlice{
+					{Key: "server", Value: yaml.MapSlice{{Key: "port", Value: 8443}}},
+				},
+				Delete:          []string{"debug"},
+				BackupExtension: "bak",
+			},
+			ExpectedUpdated: true,
+			ExpectedChanges: map[string]string{
+				"added":   "server.tls.enabled",
+				"changed": "server.port",
+				"removed": "debug",
+			},
+			ExpectedContents: "{\n  \"server\": {\n    \"port\": 8443,\n    \"tls\": {\n      \"enabled\": true\n    }\n  }\n}\n",
+			ExpectBackup:     true,
+		},
+		{
+			Name:            "formatting only differences are not changed",
+			Filename:        "config.yaml",
+			InitialContents: "# app config\nserver: {port: 8080}\n",
+			Task: Task{
+				Set: yaml.MapSlice{{Key: "server.port", Value: 8080}},
+			},
+			ExpectedChanges:  map[string]string{},
+			ExpectedContents: "# app config\nserver: {port: 8080}\n",
+		},
+		{
+			Name:            "toml with explicit format",
+			Filename:        "app.conf",
+			InitialContents: "[server]\nport = 8080\n",
+			Task: Task{
+				Format: "toml",
+				Set:    yaml.MapSlice{{Key: "server.port", Value: 9090}},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"changed": "server.port"},
+			ExpectedContents: "[server]\nport = 9090\n",
+		},
+		{
+			Name:          "create missing file",
+			Filename:      "config.yml",
+			NoInitialFile: true,
+			Task: Task{
+				Set: yaml.MapSlice{{Key: "server.port", Value: 8080}},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"added": "server.port"},
+			ExpectedContents: "server:\n  port: 8080\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tc.Filename)
+			if !tc.NoInitialFile {
+				err := os.WriteFile(filePath, []byte(tc.InitialContents), 0600)
+				require.NoError(t, err)
+			}
+
+			tc.Task.Path = "serializepath"
+			// the format is resolved by the execution, it doesn't depend on the validation
+			tc.Task.Name = filePath
+
+			executor := &Executor{
+				FsManager: &utils.FsManager{},
+			}
+
+			res := executor.Execute(context.Background(), &tc.Task)
+			require.NoError(t, res.Err)
+
+			assert.Equal(t, tc.ExpectedUpdated, tc.Task.Updated)
+			assert.Equal(t, tc.ExpectedChanges, res.Changes)
+
+			actualContents, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedContents, string(actualContents))
+
+			if tc.ExpectBackup {
+				backupContents, err := os.ReadFile(filePath + ".bak")
+				require.NoError(t, err)
+				assert.Equal(t, tc.InitialContents, string(backupContents))
+			}
+		})
+	}
+}
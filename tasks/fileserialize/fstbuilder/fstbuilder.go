@@ -0,0 +1,72 @@
+package fstbuilder
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/fileserialize"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder/parser"
+)
+
+type TaskBuilder struct {
+}
+
+var fileSerializeTaskParamsFnMap = parser.TaskFieldsParserConfig{
+	tasks.SetField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*fileserialize.Task)
+			values, err := parseValues(val, path+"."+tasks.SetField)
+			if err != nil {
+				return err
+			}
+			t.Set = append(t.Set, values...)
+			return nil
+		},
+		FieldName: "Set",
+	},
+	tasks.MergeField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*fileserialize.Task)
+			values, err := parseValues(val, path+"."+tasks.MergeField)
+			if err != nil {
+				return err
+			}
+			t.Merge = append(t.Merge, values...)
+			return nil
+		},
+		FieldName: "Merge",
+	},
+}
+
+func (tb TaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &fileserialize.Task{
+		TypeName: typeName,
+		Path:     path,
+	}
+
+	errs := builder.Build(typeName, path, params, task, fileSerializeTaskParamsFnMap)
+
+	return task, errs.ToError()
+}
+
+// parseValues accepts a map or a list of maps
+func parseValues(val interface{}, path string) (values yaml.MapSlice, err error) {
+	switch typedVal := val.(type) {
+	case yaml.MapSlice:
+		return typedVal, nil
+	case []interface{}:
+		for _, item := range typedVal {
+			itemMap, ok := item.(yaml.MapSlice)
+			if !ok {
+				return nil, fmt.Errorf("invalid value '%v' at path '%s', a map is expected", item, path)
+			}
+			values = append(values, itemMap...)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("invalid value '%v' at path '%s', a map is expected", val, path)
+	}
+}
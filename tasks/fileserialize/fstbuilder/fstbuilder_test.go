@@ -0,0 +1,81 @@
+package fstbuilder
+
+import (
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/fileserialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTaskBuilder(t *testing.T) {
+	testCases := []struct {
+		name         string
+		values       []interface{}
+		expectedTask *fileserialize.Task
+		expectedErr  string
+	}{
+		{
+			name: "all fields",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/etc/app/config.json"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.FormatField, Value: "json"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SetField, Value: yaml.MapSlice{
+					{Key: "server.tls.enabled", Value: true},
+					{Key: "server.port", Value: 8443},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.MergeField, Value: []interface{}{
+					yaml.MapSlice{{Key: "logging", Value: yaml.MapSlice{{Key: "level", Value: "info"}}}},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.DeleteField, Value: "debug"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.BackupExtensionField, Value: "bak"}},
+			},
+			expectedTask: &fileserialize.Task{
+				TypeName: fileserialize.TaskType,
+				Path:     "fileSerializePath",
+				Name:     "/etc/app/config.json",
+				Format:   "json",
+				Set: yaml.MapSlice{
+					{Key: "server.tls.enabled", Value: true},
+					{Key: "server.port", Value: 8443},
+				},
+				Merge: yaml.MapSlice{
+					{Key: "logging", Value: yaml.MapSlice{{Key: "level", Value: "info"}}},
+				},
+				Delete:          []string{"debug"},
+				BackupExtension: "bak",
+			},
+		},
+		{
+			name: "invalid set value",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/etc/app/config.json"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SetField, Value: "server.port"}},
+			},
+			expectedErr: "invalid value 'server.port' at path 'fileSerializePath.set', a map is expected: set",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			taskBuilder := TaskBuilder{}
+			task, err := taskBuilder.Build(
+				fileserialize.TaskType,
+				"fileSerializePath",
+				tc.values,
+			)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			actualTask, ok := task.(*fileserialize.Task)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedTask, actualTask)
+		})
+	}
+}
@@ -13,6 +13,8 @@ type FsManager interface {
 	MoveFile(sourceFilePath, targetFilePath string) error
 	CopyLocalFile(sourceFilePath, targetFilePath string, mode os.FileMode) error
 	WriteFile(name, contents string, mode os.FileMode) error
+	// WriteFileAtomic replaces the file by a rename, see utils.WriteFileAtomic
+	WriteFileAtomic(name, contents string, mode os.FileMode) error
 	ReadFile(filePath string) (content string, err error)
 	CreateDirPathIfNeeded(targetFilePath string, mode os.FileMode) error
 	Chmod(targetFilePath string, mode os.FileMode) error
@@ -0,0 +1,274 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/names"
+	"github.com/realvnc-labs/tacoscript/tasks/support/hostsfile"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+type ActionType int
+
+const (
+	TaskTypeHostPresent = "host.present"
+	TaskTypeHostAbsent  = "host.absent"
+
+	ActionHostPresent ActionType = iota + 1
+	ActionHostAbsent
+
+	DefaultHostsFileMode = 0644
+)
+
+var ErrUnknownHostAction = errors.New("unknown action")
+
+type Task struct {
+	tasks.ExecutionPolicy
+
+	ActionType ActionType
+	TypeName   string
+	Path       string
+	Named      names.TaskNames // hostnames
+
+	IP        string   `taco:"ip"`
+	Clean     bool     `taco:"clean"`
+	HostsFile string   `taco:"hosts_file"`
+	Require   []string `taco:"require"`
+	Creates   []string `taco:"creates"`
+	OnlyIf    []string `taco:"onlyif"`
+	Unless    []string `taco:"unless"`
+
+	Shell string `taco:"shell"`
+
+	// was the hosts file updated?
+	Updated bool
+}
+
+func (t *Task) GetTypeName() string {
+	return t.TypeName
+}
+
+func (t *Task) GetRequirements() []string {
+	return t.Require
+}
+
+func (t *Task) Validate(goos string) error {
+	errs := &utils.Errors{}
+
+	if t.ActionType == 0 {
+		errs.Add(fmt.Errorf("unknown host task type: %s", t.TypeName))
+		return errs.ToError()
+	}
+
+	if t.IP != "" && net.ParseIP(t.IP) == nil {
+		errs.Add(fmt.Errorf("%w '%s' at path '%s.%s'", hostsfile.ErrInvalidIP, t.IP, t.Path, tasks.IPField))
+	}
+
+	hostnamesErr := tasks.ValidateRequiredMany(t.Named.GetNames(), t.Path+"."+tasks.NameField)
+
+	switch t.ActionType {
+	case ActionHostPresent:
+		errs.Add(tasks.ValidateRequired(t.IP, t.Path+"."+tasks.IPField))
+		errs.Add(hostnamesErr)
+	case ActionHostAbsent:
+		// either the ip or the hostnames are needed to know what should be removed
+		if t.IP == "" && hostnamesErr != nil {
+			errs.Add(hostnamesErr)
+		}
+		if t.Clean {
+			errs.Add(fmt.Errorf("'%s' field at path '%s' is not supported by %s", tasks.CleanField, t.Path, t.TypeName))
+		}
+	}
+
+	return errs.ToError()
+}
+
+func (t *Task) GetPath() string {
+	return t.Path
+}
+
+func (t *Task) String() string {
+	return fmt.Sprintf("task '%s' at path '%s'", t.TypeName, t.GetPath())
+}
+
+func (t *Task) GetOnlyIfCmds() []string {
+	return t.OnlyIf
+}
+
+func (t *Task) GetUnlessCmds() []string {
+	return t.Unless
+}
+
+func (t *Task) GetCreatesFilesList() []string {
+	return t.Creates
+}
+
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "Hosts file not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    strings.Join(t.Named.GetNames(), "; "),
+		Comment: comment,
+	}
+}
+
+type Executor struct {
+	FsManager tasks.FsManager
+	Runner    tacoexec.Runner
+}
+
+// SupportsDryRun tells that the executor reports the changes without writing the file in a dry run
+func (hte *Executor) SupportsDryRun() bool {
+	return true
+}
+
+func (hte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	logrus.Debugf("will trigger '%s' task", task.GetPath())
+	execRes := executionresult.ExecutionResult{
+		Changes: make(map[string]string),
+	}
+
+	ht, ok := task.(*Task)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to Task", task)
+		return execRes
+	}
+
+	execRes.Name = strings.Join(ht.Named.GetNames(), "; ")
+	execRes.Comment = "Hosts file not changed"
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &tacoexec.Context{
+		Ctx:          ctx,
+		Path:         ht.Path,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		Shell:        ht.Shell,
+	}
+
+	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
+	skipReason, err := conditionals.Check(execCtx, hte.FsManager, hte.Runner, ht)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if skipReason != "" {
+		logrus.Debugf("the task '%s' will be be skipped", task.GetPath())
+		execRes.IsSkipped = true
+		execRes.SkipReason = skipReason
+		return execRes
+	}
+
+	start := time.Now()
+
+	execRes.DryRun = tasks.IsDryRun(ctx)
+	err = hte.ExecuteTask(ctx, ht, &execRes)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	execRes.Duration = time.Since(start)
+
+	logrus.Debugf("the task '%s' is finished for %v", task.GetPath(), execRes.Duration)
+	return execRes
+}
+
+func (hte *Executor) ExecuteTask(ctx context.Context, t *Task, res *executionresult.ExecutionResult) (err error) {
+	hostsFilePath := t.HostsFile
+	if hostsFilePath == "" {
+		hostsFilePath = hostsfile.DefaultPath(runtime.GOOS)
+	}
+
+	mode := fs.FileMode(DefaultHostsFileMode)
+	contents := ""
+
+	info, err := hte.FsManager.Stat(hostsFilePath)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", hostsFilePath)
+		}
+		mode = info.Mode().Perm()
+		contents, err = hte.FsManager.ReadFile(hostsFilePath)
+		if err != nil {
+			return err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		logrus.Debugf("hosts file '%s' doesn't exist", hostsFilePath)
+		if t.ActionType == ActionHostAbsent {
+			return nil
+		}
+	default:
+		return err
+	}
+
+	hosts := hostsfile.Parse(contents)
+
+	var added, removed []string
+	switch t.ActionType {
+	case ActionHostPresent:
+		added, removed, err = hosts.EnsurePresent(t.IP, t.Named.GetNames(), t.Clean)
+	case ActionHostAbsent:
+		removed, err = hosts.RemoveHostnames(t.IP, t.Named.GetNames())
+	default:
+		err = ErrUnknownHostAction
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		logrus.Debugf("hosts file '%s' is in the desired state", hostsFilePath)
+		return nil
+	}
+
+	if res.DryRun {
+		res.Comment = "Hosts file would be updated"
+	} else {
+		journal := state.JournalFromContext(ctx)
+		if err = journal.Snapshot(hostsFilePath); err != nil {
+			return err
+		}
+		defer journal.RecordOrLog(t.Path, hostsFilePath)
+
+		err = hte.FsManager.WriteFileAtomic(hostsFilePath, hosts.String(), mode)
+		if err != nil {
+			return err
+		}
+
+		logrus.Debugf("updated hosts file '%s'", hostsFilePath)
+
+		t.Updated = true
+		res.Comment = "Hosts file updated"
+	}
+
+	if len(added) > 0 {
+		res.Changes["added"] = strings.Join(added, ", ")
+	}
+	if len(removed) > 0 {
+		res.Changes["removed"] = strings.Join(removed, ", ")
+	}
+
+	return nil
+}
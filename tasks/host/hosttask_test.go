@@ -0,0 +1,229 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/names"
+	"github.com/realvnc-labs/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostTaskValidation(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		InputTask   Task
+		ExpectedErr string
+	}{
+		{
+			Name: "unknown action",
+			InputTask: Task{
+				TypeName: "host.unknown",
+				Path:     "somepath",
+			},
+			ExpectedErr: "unknown host task type: host.unknown",
+		},
+		{
+			Name: "present without ip",
+			InputTask: Task{
+				ActionType: ActionHostPresent,
+				Path:       "somepath",
+				Named:      names.TaskNames{Name: "db"},
+			},
+			ExpectedErr: fmt.Sprintf("empty required value at path 'somepath.%s'", tasks.IPField),
+		},
+		{
+			Name: "present without hostnames",
+			InputTask: Task{
+				ActionType: ActionHostPresent,
+				Path:       "somepath",
+				IP:         "10.0.0.1",
+			},
+			ExpectedErr: fmt.Sprintf("empty required values at path 'somepath.%s'", tasks.NameField),
+		},
+		{
+			Name: "invalid ip",
+			InputTask: Task{
+				ActionType: ActionHostPresent,
+				Path:       "somepath",
+				IP:         "10.0.0.256",
+				Named:      names.TaskNames{Name: "db"},
+			},
+			ExpectedErr: fmt.Sprintf("invalid ip address '10.0.0.256' at path 'somepath.%s'", tasks.IPField),
+		},
+		{
+			Name: "absent without ip and hostnames",
+			InputTask: Task{
+				ActionType: ActionHostAbsent,
+				Path:       "somepath",
+			},
+			ExpectedErr: fmt.Sprintf("empty required values at path 'somepath.%s'", tasks.NameField),
+		},
+		{
+			Name: "absent with ip only",
+			InputTask: Task{
+				ActionType: ActionHostAbsent,
+				Path:       "somepath",
+				IP:         "::1",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.InputTask.Validate(runtime.GOOS)
+			if tc.ExpectedErr != "" {
+				assert.EqualError(t, err, tc.ExpectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, tc.InputTask.HostsFile)
+		})
+	}
+}
+
+func TestHostTaskExecution(t *testing.T) {
+	const initialContents = "127.0.0.1 localhost\n10.0.0.5 db\n"
+
+	testCases := []struct {
+		Name             string
+		Task             Task
+		InitialContents  *string
+		ExpectedUpdated  bool
+		ExpectedChanges  map[string]string
+		ExpectedContents string
+		ExpectFileExists bool
+	}{
+		{
+			Name: "add hostname",
+			Task: Task{
+				ActionType: ActionHostPresent,
+				IP:         "10.0.0.5",
+				Named:      names.TaskNames{Names: []string{"db", "db.local"}},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"added": "db.local"},
+			ExpectedContents: "127.0.0.1 localhost\n10.0.0.5 db db.local\n",
+			ExpectFileExists: true,
+		},
+		{
+			Name: "already present",
+			Task: Task{
+				ActionType: ActionHostPresent,
+				IP:         "10.0.0.5",
+				Named:      names.TaskNames{Name: "db"},
+			},
+			ExpectedChanges:  map[string]string{},
+			ExpectedContents: initialContents,
+			ExpectFileExists: true,
+		},
+		{
+			Name: "remove mapping",
+			Task: Task{
+				ActionType: ActionHostAbsent,
+				IP:         "10.0.0.5",
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"removed": "db"},
+			ExpectedContents: "127.0.0.1 localhost\n",
+			ExpectFileExists: true,
+		},
+		{
+			Name: "create missing hosts file",
+			Task: Task{
+				ActionType: ActionHostPresent,
+				IP:         "10.0.0.5",
+				Named:      names.TaskNames{Name: "db"},
+			},
+			InitialContents:  new(string),
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"added": "db"},
+			ExpectedContents: "10.0.0.5\tdb\n",
+			ExpectFileExists: true,
+		},
+		{
+			Name: "absent with missing hosts file",
+			Task: Task{
+				ActionType: ActionHostAbsent,
+				Named:      names.TaskNames{Name: "db"},
+			},
+			InitialContents: new(string),
+			ExpectedChanges: map[string]string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			hostsFilePath := filepath.Join(t.TempDir(), "hosts")
+			if tc.InitialContents == nil {
+				err := os.WriteFile(hostsFilePath, []byte(initialContents), 0600)
+				require.NoError(t, err)
+			}
+
+			tc.Task.Path = "hostpath"
+			tc.Task.HostsFile = hostsFilePath
+			err := tc.Task.Validate(runtime.GOOS)
+			require.NoError(t, err)
+
+			executor := &Executor{
+				FsManager: &utils.FsManager{},
+			}
+
+			res := executor.Execute(context.Background(), &tc.Task)
+			require.NoError(t, res.Err)
+
+			assert.Equal(t, tc.ExpectedUpdated, tc.Task.Updated)
+			assert.Equal(t, tc.ExpectedChanges, res.Changes)
+
+			if !tc.ExpectFileExists {
+				assert.NoFileExists(t, hostsFilePath)
+				return
+			}
+
+			actualContents, err := os.ReadFile(hostsFilePath)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedContents, string(actualContents))
+		})
+	}
+}
+
+// writeRecordingFsManager records the written files instead of writing them
+type writeRecordingFsManager struct {
+	utils.FsManager
+	written map[string]string
+}
+
+func (wrfm *writeRecordingFsManager) WriteFileAtomic(name, contents string, mode os.FileMode) error {
+	wrfm.written[name] = contents
+	return nil
+}
+
+func TestHostTaskWritesThroughFsManager(t *testing.T) {
+	hostsFilePath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsFilePath, []byte("127.0.0.1 localhost\n"), 0600))
+
+	fsManager := &writeRecordingFsManager{written: map[string]string{}}
+	task := &Task{
+		ActionType: ActionHostPresent,
+		Path:       "hostpath",
+		IP:         "10.0.0.5",
+		Named:      names.TaskNames{Name: "db"},
+		HostsFile:  hostsFilePath,
+	}
+
+	res := (&Executor{FsManager: fsManager}).Execute(context.Background(), task)
+	require.NoError(t, res.Err)
+
+	assert.Equal(t, map[string]string{hostsFilePath: "127.0.0.1 localhost\n10.0.0.5\tdb\n"}, fsManager.written)
+	actualContents, err := os.ReadFile(hostsFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n", string(actualContents))
+}
@@ -0,0 +1,52 @@
+package htbuilder
+
+import (
+	"fmt"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/host"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder/parser"
+)
+
+type TaskBuilder struct {
+}
+
+var hostTaskParamsFnMap = parser.TaskFieldsParserConfig{
+	tasks.NameField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*host.Task)
+			t.Named.Name = fmt.Sprint(val)
+			return nil
+		},
+		FieldName: "Name",
+	},
+	tasks.NamesField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			var err error
+			t := task.(*host.Task)
+			t.Named.Names, err = conv.ConvertToValues(val)
+			return err
+		},
+		FieldName: "Names",
+	},
+}
+
+func (tb TaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &host.Task{
+		TypeName: typeName,
+		Path:     path,
+	}
+
+	switch typeName {
+	case host.TaskTypeHostPresent:
+		task.ActionType = host.ActionHostPresent
+	case host.TaskTypeHostAbsent:
+		task.ActionType = host.ActionHostAbsent
+	}
+
+	errs := builder.Build(typeName, path, params, task, hostTaskParamsFnMap)
+
+	return task, errs.ToError()
+}
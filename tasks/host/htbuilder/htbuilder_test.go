@@ -0,0 +1,77 @@
+package htbuilder
+
+import (
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/host"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/names"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTaskBuilder(t *testing.T) {
+	testCases := []struct {
+		typeName     string
+		path         string
+		values       []interface{}
+		expectedTask *host.Task
+	}{
+		{
+			typeName: host.TaskTypeHostPresent,
+			path:     "hostPresentPath",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.IPField, Value: "10.0.0.5"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NamesField, Value: []interface{}{"db", "db.local"}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.CleanField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.HostsFileField, Value: "/tmp/hosts"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.OnlyIfField, Value: "test -f /tmp/hosts"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.ShellField, Value: "bash"}},
+			},
+			expectedTask: &host.Task{
+				ActionType: host.ActionHostPresent,
+				TypeName:   host.TaskTypeHostPresent,
+				Path:       "hostPresentPath",
+				Named:      names.TaskNames{Names: []string{"db", "db.local"}},
+				IP:         "10.0.0.5",
+				Clean:      true,
+				HostsFile:  "/tmp/hosts",
+				OnlyIf:     []string{"test -f /tmp/hosts"},
+				Shell:      "bash",
+			},
+		},
+		{
+			typeName: host.TaskTypeHostAbsent,
+			path:     "hostAbsentPath",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "db.local"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.RequireField, Value: []interface{}{"req one"}}},
+			},
+			expectedTask: &host.Task{
+				ActionType: host.ActionHostAbsent,
+				TypeName:   host.TaskTypeHostAbsent,
+				Path:       "hostAbsentPath",
+				Named:      names.TaskNames{Name: "db.local"},
+				Require:    []string{"req one"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.typeName, func(t *testing.T) {
+			taskBuilder := TaskBuilder{}
+			task, err := taskBuilder.Build(
+				tc.typeName,
+				tc.path,
+				tc.values,
+			)
+			require.NoError(t, err)
+
+			actualTask, ok := task.(*host.Task)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedTask, actualTask)
+		})
+	}
+}
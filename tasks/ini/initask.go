@@ -0,0 +1,367 @@
+package ini
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/tasks/support/iniconfig"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+type ActionType int
+
+const (
+	TaskTypeOptionsPresent  = "ini.options_present"
+	TaskTypeOptionsAbsent   = "ini.options_absent"
+	TaskTypeSectionsPresent = "ini.sections_present"
+
+	ActionOptionsPresent ActionType = iota + 1
+	ActionOptionsAbsent
+	ActionSectionsPresent
+
+	DefaultFileMode = 0644
+)
+
+var ErrUnknownIniAction = errors.New("unknown action")
+
+// Section contains the options of a single config section, options without a section have an empty section name.
+// For the ini.options_absent task only the option keys are used.
+type Section struct {
+	Name    string
+	Options conv.KeyValues
+}
+
+type Task struct {
+	tasks.ExecutionPolicy
+
+	ActionType ActionType
+	TypeName   string
+	Path       string
+	Sections   []Section
+
+	Name            string   `taco:"name"`
+	Separator       string   `taco:"separator"`
+	Strict          bool     `taco:"strict"`
+	BackupExtension string   `taco:"backup"`
+	Require         []string `taco:"require"`
+	Creates         []string `taco:"creates"`
+	OnlyIf          []string `taco:"onlyif"`
+	Unless          []string `taco:"unless"`
+
+	Shell string `taco:"shell"`
+
+	// was the config file updated?
+	Updated bool
+}
+
+func (t *Task) GetTypeName() string {
+	return t.TypeName
+}
+
+func (t *Task) GetRequirements() []string {
+	return t.Require
+}
+
+func (t *Task) Validate(goos string) error {
+	errs := &utils.Errors{}
+
+	if t.ActionType == 0 {
+		errs.Add(fmt.Errorf("unknown ini task type: %s", t.TypeName))
+		return errs.ToError()
+	}
+
+	errs.Add(tasks.ValidateRequired(t.Name, t.Path+"."+tasks.NameField))
+
+	if len(t.Sections) == 0 {
+		errs.Add(fmt.Errorf(
+			"either '%s' or '%s' should be provided for the task at path '%s'",
+			tasks.SectionsField,
+			tasks.OptionsField,
+			t.Path,
+		))
+	}
+
+	if t.Strict && t.ActionType != ActionOptionsPresent {
+		errs.Add(fmt.Errorf("'%s' field at path '%s' is not supported by %s", tasks.StrictField, t.Path, t.TypeName))
+	}
+
+	return errs.ToError()
+}
+
+func (t *Task) GetPath() string {
+	return t.Path
+}
+
+func (t *Task) String() string {
+	return fmt.Sprintf("task '%s' at path '%s'", t.TypeName, t.GetPath())
+}
+
+func (t *Task) GetOnlyIfCmds() []string {
+	return t.OnlyIf
+}
+
+func (t *Task) GetUnlessCmds() []string {
+	return t.Unless
+}
+
+func (t *Task) GetCreatesFilesList() []string {
+	return t.Creates
+}
+
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "File not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    t.Name,
+		Comment: comment,
+	}
+}
+
+type Executor struct {
+	FsManager tasks.FsManager
+	Runner    tacoexec.Runner
+}
+
+type changeSet struct {
+	added   []string
+	changed []string
+	removed []string
+}
+
+func (cs *changeSet) isEmpty() bool {
+	return len(cs.added) == 0 && len(cs.changed) == 0 && len(cs.removed) == 0
+}
+
+// SupportsDryRun tells that the executor reports the changes without writing the file in a dry run
+func (ite *Executor) SupportsDryRun() bool {
+	return true
+}
+
+func (ite *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	logrus.Debugf("will trigger '%s' task", task.GetPath())
+	execRes := executionresult.ExecutionResult{
+		Changes: make(map[string]string),
+	}
+
+	it, ok := task.(*Task)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to Task", task)
+		return execRes
+	}
+
+	execRes.Name = it.Name
+	execRes.Comment = "File not changed"
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &tacoexec.Context{
+		Ctx:          ctx,
+		Path:         it.Path,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		Shell:        it.Shell,
+	}
+
+	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
+	skipReason, err := conditionals.Check(execCtx, ite.FsManager, ite.Runner, it)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if skipReason != "" {
+		logrus.Debugf("the task '%s' will be be skipped", task.GetPath())
+		execRes.IsSkipped = true
+		execRes.SkipReason = skipReason
+		return execRes
+	}
+
+	start := time.Now()
+
+	execRes.DryRun = tasks.IsDryRun(ctx)
+	err = ite.ExecuteTask(ctx, it, &execRes)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	execRes.Duration = time.Since(start)
+
+	logrus.Debugf("the task '%s' is finished for %v", task.GetPath(), execRes.Duration)
+	return execRes
+}
+
+func (ite *Executor) ExecuteTask(ctx context.Context, t *Task, res *executionresult.ExecutionResult) (err error) {
+	mode := fs.FileMode(DefaultFileMode)
+	origContents := ""
+	fileExists := true
+
+	info, err := ite.FsManager.Stat(t.Name)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", t.Name)
+		}
+		mode = info.Mode().Perm()
+		origContents, err = ite.FsManager.ReadFile(t.Name)
+		if err != nil {
+			return err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		fileExists = false
+		logrus.Debugf("config file '%s' doesn't exist", t.Name)
+		if t.ActionType == ActionOptionsAbsent {
+			return nil
+		}
+	default:
+		return err
+	}
+
+	doc := iniconfig.Parse(origContents, t.Separator)
+
+	changes := &changeSet{}
+	switch t.ActionType {
+	case ActionOptionsPresent:
+		applyOptionsPresent(t, doc, changes)
+	case ActionOptionsAbsent:
+		applyOptionsAbsent(t, doc, changes)
+	case ActionSectionsPresent:
+		applySectionsPresent(t, doc, changes)
+	default:
+		return ErrUnknownIniAction
+	}
+
+	if changes.isEmpty() {
+		logrus.Debugf("config file '%s' is in the desired state", t.Name)
+		return nil
+	}
+
+	if res.DryRun {
+		res.Comment = "File would be updated"
+	} else {
+		journal := state.JournalFromContext(ctx)
+		if err = journal.Snapshot(t.Name); err != nil {
+			return err
+		}
+		defer journal.RecordOrLog(t.Path, t.Name)
+
+		if fileExists && t.BackupExtension != "" {
+			backupFilename := utils.GetBackupFilename(t.Name, t.BackupExtension)
+			err = ite.FsManager.WriteFile(backupFilename, origContents, mode)
+			if err != nil {
+				return err
+			}
+			logrus.Debugf("created backup file %s for original file %s", backupFilename, t.Name)
+		}
+
+		err = ite.FsManager.WriteFileAtomic(t.Name, doc.String(), mode)
+		if err != nil {
+			return err
+		}
+
+		logrus.Debugf("updated config file '%s'", t.Name)
+
+		t.Updated = true
+		res.Comment = "File updated"
+	}
+
+	if len(changes.added) > 0 {
+		res.Changes["added"] = strings.Join(changes.added, ", ")
+	}
+	if len(changes.changed) > 0 {
+		res.Changes["changed"] = strings.Join(changes.changed, ", ")
+	}
+	if len(changes.removed) > 0 {
+		res.Changes["removed"] = strings.Join(changes.removed, ", ")
+	}
+
+	return nil
+}
+
+func applyOptionsPresent(t *Task, doc *iniconfig.Document, changes *changeSet) {
+	for _, section := range t.Sections {
+		for _, option := range section.Options {
+			added, changed := doc.Set(section.Name, option.Key, option.Value)
+			if added {
+				changes.added = append(changes.added, optionPath(section.Name, option.Key))
+			}
+			if changed {
+				changes.changed = append(changes.changed, optionPath(section.Name, option.Key))
+			}
+		}
+
+		if !t.Strict {
+			continue
+		}
+
+		for _, key := range doc.Keys(section.Name) {
+			if hasOption(section.Options, key) {
+				continue
+			}
+			if doc.Remove(section.Name, key) {
+				changes.removed = append(changes.removed, optionPath(section.Name, key))
+			}
+		}
+	}
+}
+
+func applyOptionsAbsent(t *Task, doc *iniconfig.Document, changes *changeSet) {
+	for _, section := range t.Sections {
+		for _, option := range section.Options {
+			if doc.Remove(section.Name, option.Key) {
+				changes.removed = append(changes.removed, optionPath(section.Name, option.Key))
+			}
+		}
+	}
+}
+
+func applySectionsPresent(t *Task, doc *iniconfig.Document, changes *changeSet) {
+	for _, section := range t.Sections {
+		if doc.AddSection(section.Name) {
+			changes.added = append(changes.added, "["+section.Name+"]")
+		}
+
+		// options of a present section are only added when missing, existing values are kept
+		for _, option := range section.Options {
+			if _, found := doc.Get(section.Name, option.Key); found {
+				continue
+			}
+			doc.Set(section.Name, option.Key, option.Value)
+			changes.added = append(changes.added, optionPath(section.Name, option.Key))
+		}
+	}
+}
+
+func optionPath(section, key string) string {
+	if section == "" {
+		return key
+	}
+
+	return section + "." + key
+}
+
+func hasOption(options conv.KeyValues, key string) bool {
+	for _, option := range options {
+		if option.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
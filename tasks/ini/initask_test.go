@@ -0,0 +1,258 @@
+package ini
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/state"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIniTaskValidation(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		InputTask   Task
+		ExpectedErr string
+	}{
+		{
+			Name: "unknown action",
+			InputTask: Task{
+				TypeName: "ini.unknown",
+			},
+			ExpectedErr: "unknown ini task type: ini.unknown",
+		},
+		{
+			Name: "missing name and sections",
+			InputTask: Task{
+				ActionType: ActionOptionsPresent,
+				Path:       "somepath",
+			},
+			ExpectedErr: fmt.Sprintf(
+				"empty required value at path 'somepath.%s', either '%s' or '%s' should be provided for the task at path 'somepath'",
+				tasks.NameField,
+				tasks.SectionsField,
+				tasks.OptionsField,
+			),
+		},
+		{
+			Name: "strict for absent options",
+			InputTask: Task{
+				ActionType: ActionOptionsAbsent,
+				Path:       "somepath",
+				Name:       "/tmp/some.ini",
+				Strict:     true,
+				Sections:   []Section{{Name: "main", Options: conv.KeyValues{{Key: "one"}}}},
+			},
+			ExpectedErr: fmt.Sprintf("'%s' field at path 'somepath' is not supported by ", tasks.StrictField),
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			err := tc.InputTask.Validate(runtime.GOOS)
+			assert.EqualError(t, err, tc.ExpectedErr)
+		})
+	}
+}
+
+func TestIniTaskExecution(t *testing.T) {
+	const initialContents = "# app config\nname=app\n\n[server]\nport = 8080\nhost = localhost\n"
+
+	testCases := []struct {
+		Name             string
+		Task             Task
+		NoInitialFile    bool
+		DryRun           bool
+		ExpectedUpdated  bool
+		ExpectedChanges  map[string]string
+		ExpectedContents string
+		ExpectBackup     bool
+	}{
+		{
+			Name: "options present",
+			Task: Task{
+				ActionType: ActionOptionsPresent,
+				Sections: []Section{
+					{Options: conv.KeyValues{{Key: "name", Value: "app"}}},
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "9090"}, {Key: "tls", Value: "true"}}},
+				},
+				BackupExtension: "bak",
+			},
+			ExpectedUpdated: true,
+			ExpectedChanges: map[string]string{
+				"added":   "server.tls",
+				"changed": "server.port",
+			},
+			ExpectedContents: "# app config\nname=app\n\n[server]\nport = 9090\nhost = localhost\ntls=true\n",
+			ExpectBackup:     true,
+		},
+		{
+			Name: "strict options present",
+			Task: Task{
+				ActionType: ActionOptionsPresent,
+				Strict:     true,
+				Sections: []Section{
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "8080"}}},
+				},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"removed": "server.host"},
+			ExpectedContents: "# app config\nname=app\n\n[server]\nport = 8080\n",
+		},
+		{
+			Name: "options absent",
+			Task: Task{
+				ActionType: ActionOptionsAbsent,
+				Sections: []Section{
+					{Options: conv.KeyValues{{Key: "name"}}},
+					{Name: "server", Options: conv.KeyValues{{Key: "unknown"}}},
+				},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"removed": "name"},
+			ExpectedContents: "# app config\n\n[server]\nport = 8080\nhost = localhost\n",
+		},
+		{
+			Name: "sections present keeps existing values",
+			Task: Task{
+				ActionType: ActionSectionsPresent,
+				Sections: []Section{
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "9090"}}},
+					{Name: "client"},
+				},
+			},
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"added": "[client]"},
+			ExpectedContents: initialContents + "[client]\n",
+		},
+		{
+			Name: "nothing to change",
+			Task: Task{
+				ActionType: ActionOptionsPresent,
+				Sections: []Section{
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "8080"}}},
+				},
+			},
+			ExpectedChanges:  map[string]string{},
+			ExpectedContents: initialContents,
+		},
+		{
+			Name: "create missing file",
+			Task: Task{
+				ActionType: ActionOptionsPresent,
+				Separator:  " = ",
+				Sections: []Section{
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "8080"}}},
+				},
+			},
+			NoInitialFile:    true,
+			ExpectedUpdated:  true,
+			ExpectedChanges:  map[string]string{"added": "server.port"},
+			ExpectedContents: "[server]\nport = 8080\n",
+		},
+		{
+			Name: "dry run",
+			Task: Task{
+				ActionType: ActionOptionsPresent,
+				Sections: []Section{
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "9090"}}},
+				},
+				BackupExtension: "bak",
+			},
+			DryRun:           true,
+			ExpectedChanges:  map[string]string{"changed": "server.port"},
+			ExpectedContents: initialContents,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.Name, func(t *testing.T) {
+			configFilePath := filepath.Join(t.TempDir(), "app.ini")
+			if !tc.NoInitialFile {
+				err := os.WriteFile(configFilePath, []byte(initialContents), 0600)
+				require.NoError(t, err)
+			}
+
+			tc.Task.Path = "inipath"
+			tc.Task.Name = configFilePath
+			err := tc.Task.Validate(runtime.GOOS)
+			require.NoError(t, err)
+
+			executor := &Executor{
+				FsManager: &utils.FsManager{},
+			}
+
+			ctx := context.Background()
+			if tc.DryRun {
+				ctx = tasks.WithDryRun(ctx)
+			}
+
+			res := executor.Execute(ctx, &tc.Task)
+			require.NoError(t, res.Err)
+			assert.Equal(t, tc.DryRun, res.DryRun)
+
+			assert.Equal(t, tc.ExpectedUpdated, tc.Task.Updated)
+			assert.Equal(t, tc.ExpectedChanges, res.Changes)
+
+			actualContents, err := os.ReadFile(configFilePath)
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedContents, string(actualContents))
+
+			if tc.ExpectBackup {
+				backupContents, err := os.ReadFile(configFilePath + ".bak")
+				require.NoError(t, err)
+				assert.Equal(t, initialContents, string(backupContents))
+			} else {
+				assert.NoFileExists(t, configFilePath+".bak")
+			}
+		})
+	}
+}
+
+func TestIniTaskRollback(t *testing.T) {
+	const initialContents = "[server]\nport = 8080\n"
+
+	dir := t.TempDir()
+	configFilePath := filepath.Join(dir, "app.ini")
+	require.NoError(t, os.WriteFile(configFilePath, []byte(initialContents), 0600))
+
+	history := state.NewHistory(filepath.Join(dir, "state"), 0)
+	journal := history.Journal(state.NewRunID(time.Now()))
+
+	task := &Task{
+		ActionType: ActionOptionsPresent,
+		Path:       "inipath",
+		Name:       configFilePath,
+		Sections:   []Section{{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "9090"}}}},
+	}
+	require.NoError(t, task.Validate(runtime.GOOS))
+
+	executor := &Executor{FsManager: &utils.FsManager{}}
+	res := executor.Execute(state.WithJournal(context.Background(), journal), task)
+	require.NoError(t, res.Err)
+	require.True(t, task.Updated)
+
+	loaded, err := state.LoadJournal(journal.Dir)
+	require.NoError(t, err)
+	require.Len(t, loaded.Entries, 1)
+	assert.Equal(t, "inipath", loaded.Entries[0].TaskPath)
+
+	rolledBack, err := loaded.Rollback(false)
+	require.NoError(t, err)
+	assert.Equal(t, []state.RolledBackFile{{Path: configFilePath, Action: state.RollbackRestored}}, rolledBack)
+
+	actualContents, err := os.ReadFile(configFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, initialContents, string(actualContents))
+}
@@ -0,0 +1,124 @@
+package initbuilder
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/ini"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder/parser"
+)
+
+type TaskBuilder struct {
+}
+
+var iniTaskParamsFnMap = parser.TaskFieldsParserConfig{
+	tasks.SectionsField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*ini.Task)
+			sections, err := parseSections(val, path+"."+tasks.SectionsField)
+			if err != nil {
+				return err
+			}
+			t.Sections = append(t.Sections, sections...)
+			return nil
+		},
+		FieldName: "Sections",
+	},
+	tasks.OptionsField: parser.TaskField{
+		ParseFn: func(task tasks.CoreTask, path string, val interface{}) error {
+			t := task.(*ini.Task)
+			options, err := parseOptions(val, path+"."+tasks.OptionsField)
+			if err != nil {
+				return err
+			}
+			// options without a section are placed before any section
+			t.Sections = append([]ini.Section{{Options: options}}, t.Sections...)
+			return nil
+		},
+		FieldName: "Sections",
+	},
+}
+
+func (tb TaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &ini.Task{
+		TypeName: typeName,
+		Path:     path,
+	}
+
+	switch typeName {
+	case ini.TaskTypeOptionsPresent:
+		task.ActionType = ini.ActionOptionsPresent
+	case ini.TaskTypeOptionsAbsent:
+		task.ActionType = ini.ActionOptionsAbsent
+	case ini.TaskTypeSectionsPresent:
+		task.ActionType = ini.ActionSectionsPresent
+	}
+
+	errs := builder.Build(typeName, path, params, task, iniTaskParamsFnMap)
+
+	return task, errs.ToError()
+}
+
+// parseSections accepts a map of section names to options, a list of such maps or a list of section names
+func parseSections(val interface{}, path string) (sections []ini.Section, err error) {
+	switch typedVal := val.(type) {
+	case yaml.MapSlice:
+		for _, item := range typedVal {
+			sectionName := fmt.Sprint(item.Key)
+			options, err := parseOptions(item.Value, path+"."+sectionName)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, ini.Section{
+				Name:    sectionName,
+				Options: options,
+			})
+		}
+	case []interface{}:
+		for _, item := range typedVal {
+			itemSections, err := parseSections(item, path)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, itemSections...)
+		}
+	case nil:
+		return nil, fmt.Errorf("empty value at path '%s'", path)
+	default:
+		sections = append(sections, ini.Section{
+			Name: fmt.Sprint(val),
+		})
+	}
+
+	return sections, nil
+}
+
+// parseOptions accepts a map of option keys to values, a list of such maps or a list of option keys
+func parseOptions(val interface{}, path string) (options conv.KeyValues, err error) {
+	switch typedVal := val.(type) {
+	case nil:
+		return options, nil
+	case yaml.MapSlice:
+		return conv.ConvertMapToKeyValues(typedVal, path)
+	case []interface{}:
+		for _, item := range typedVal {
+			if itemMap, ok := item.(yaml.MapSlice); ok {
+				itemOptions, err := conv.ConvertMapToKeyValues(itemMap, path)
+				if err != nil {
+					return nil, err
+				}
+				options = append(options, itemOptions...)
+				continue
+			}
+			options = append(options, conv.KeyValue{Key: fmt.Sprint(item)})
+		}
+	default:
+		options = append(options, conv.KeyValue{Key: fmt.Sprint(val)})
+	}
+
+	return options, nil
+}
@@ -0,0 +1,114 @@
+package initbuilder
+
+import (
+	"testing"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/ini"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTaskBuilder(t *testing.T) {
+	testCases := []struct {
+		typeName     string
+		path         string
+		values       []interface{}
+		expectedTask *ini.Task
+	}{
+		{
+			typeName: ini.TaskTypeOptionsPresent,
+			path:     "iniOptionsPresentPath",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/etc/app.ini"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SectionsField, Value: yaml.MapSlice{
+					{Key: "server", Value: yaml.MapSlice{
+						{Key: "port", Value: 8080},
+						{Key: "host", Value: "localhost"},
+					}},
+					{Key: "client", Value: []interface{}{
+						yaml.MapSlice{{Key: "timeout", Value: 10}},
+					}},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.OptionsField, Value: yaml.MapSlice{
+					{Key: "debug", Value: false},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SeparatorField, Value: " = "}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.StrictField, Value: true}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.BackupExtensionField, Value: "bak"}},
+			},
+			expectedTask: &ini.Task{
+				ActionType: ini.ActionOptionsPresent,
+				TypeName:   ini.TaskTypeOptionsPresent,
+				Path:       "iniOptionsPresentPath",
+				Name:       "/etc/app.ini",
+				Sections: []ini.Section{
+					{Options: conv.KeyValues{{Key: "debug", Value: "false"}}},
+					{Name: "server", Options: conv.KeyValues{{Key: "port", Value: "8080"}, {Key: "host", Value: "localhost"}}},
+					{Name: "client", Options: conv.KeyValues{{Key: "timeout", Value: "10"}}},
+				},
+				Separator:       " = ",
+				Strict:          true,
+				BackupExtension: "bak",
+			},
+		},
+		{
+			typeName: ini.TaskTypeOptionsAbsent,
+			path:     "iniOptionsAbsentPath",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/etc/app.ini"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SectionsField, Value: yaml.MapSlice{
+					{Key: "server", Value: []interface{}{"port", "host"}},
+				}}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.OptionsField, Value: "debug"}},
+			},
+			expectedTask: &ini.Task{
+				ActionType: ini.ActionOptionsAbsent,
+				TypeName:   ini.TaskTypeOptionsAbsent,
+				Path:       "iniOptionsAbsentPath",
+				Name:       "/etc/app.ini",
+				Sections: []ini.Section{
+					{Options: conv.KeyValues{{Key: "debug"}}},
+					{Name: "server", Options: conv.KeyValues{{Key: "port"}, {Key: "host"}}},
+				},
+			},
+		},
+		{
+			typeName: ini.TaskTypeSectionsPresent,
+			path:     "iniSectionsPresentPath",
+			values: []interface{}{
+				yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "/etc/app.ini"}},
+				yaml.MapSlice{yaml.MapItem{Key: tasks.SectionsField, Value: []interface{}{"server", "client"}}},
+			},
+			expectedTask: &ini.Task{
+				ActionType: ini.ActionSectionsPresent,
+				TypeName:   ini.TaskTypeSectionsPresent,
+				Path:       "iniSectionsPresentPath",
+				Name:       "/etc/app.ini",
+				Sections: []ini.Section{
+					{Name: "server"},
+					{Name: "client"},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.typeName, func(t *testing.T) {
+			taskBuilder := TaskBuilder{}
+			task, err := taskBuilder.Build(
+				tc.typeName,
+				tc.path,
+				tc.values,
+			)
+			require.NoError(t, err)
+
+			actualTask, ok := task.(*ini.Task)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectedTask, actualTask)
+		})
+	}
+}
@@ -31,6 +31,8 @@ const (
 )
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	ActionType PkgActionType
 	TypeName   string
 	Path       string
@@ -39,6 +41,8 @@ type Task struct {
 	Shell         string   `taco:"shell"`
 	Version       string   `taco:"version"`
 	ShouldRefresh bool     `taco:"refresh"`
+	Become        bool     `taco:"become"`
+	BecomeMethod  string   `taco:"become_method"`
 	Require       []string `taco:"require"`
 	Creates       []string `taco:"creates"`
 	OnlyIf        []string `taco:"onlyif"`
@@ -70,6 +74,8 @@ func (pt *Task) Validate(goos string) error {
 		errs.Add(fmt.Errorf("unknown pkg task type: %s", pt.TypeName))
 	}
 
+	errs.Add(tasks.ValidateBecome(pt.Become, pt.BecomeMethod, pt.Path, goos))
+
 	return errs.ToError()
 }
 
@@ -104,10 +110,32 @@ type PackageManager interface {
 	ExecuteTask(ctx context.Context, t *Task) (res *ExecutionResult, err error)
 }
 
+func (pt *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !pt.Updated {
+		comment = "Package not updated " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    pt.Named.Name,
+		Comment: comment,
+	}
+}
+
 type Executor struct {
 	PackageManager PackageManager
 	Runner         tacoexec.Runner
-	FsManager      *utils.FsManager
+	FsManager      tasks.FsManager
+}
+
+// SupportsDryRun tells that the package manager reports the packages to install or remove without changing them in a dry run
+func (pte *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// SupportsTimeout tells that the package manager command is killed when the context is done
+func (pte *Executor) SupportsTimeout() bool {
+	return true
 }
 
 func (pte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
@@ -127,6 +155,8 @@ func (pte *Executor) Execute(ctx context.Context, task tasks.CoreTask) execution
 		Ctx:          ctx,
 		StdoutWriter: &stdoutBuf,
 		StderrWriter: &stderrBuf,
+		Become:       pkgTask.Become,
+		BecomeMethod: pkgTask.BecomeMethod,
 	}
 
 	logrus.Debugf("will check if the task '%s' should be executed", task.GetPath())
@@ -145,6 +175,7 @@ func (pte *Executor) Execute(ctx context.Context, task tasks.CoreTask) execution
 
 	start := time.Now()
 
+	execRes.DryRun = tasks.IsDryRun(ctx)
 	pkgExecResult, err := pte.PackageManager.ExecuteTask(ctx, pkgTask)
 	execRes.Err = err
 	if pkgExecResult != nil {
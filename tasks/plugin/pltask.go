@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+// ValidateTimeout limits the run time of the validate verb of a plugin
+const ValidateTimeout = time.Minute
+
+// Task is a task of a type which is implemented by a plugin executable
+type Task struct {
+	tasks.ExecutionPolicy
+
+	TypeName string
+	Path     string
+	Plugin   Plugin
+	// all parameters of the task in the script, they are passed to the plugin
+	Params map[string]interface{}
+
+	Name    string   `taco:"name"`
+	DryRun  bool     `taco:"dry_run"`
+	Shell   string   `taco:"shell"`
+	Creates []string `taco:"creates"`
+	Require []string `taco:"require"`
+	OnlyIf  []string `taco:"onlyif"`
+	Unless  []string `taco:"unless"`
+
+	// true if the plugin reported that it changed anything
+	Updated bool
+}
+
+func (t *Task) GetTypeName() string {
+	return t.TypeName
+}
+
+func (t *Task) GetRequirements() []string {
+	return t.Require
+}
+
+// Validate lets the plugin check the task parameters with the validate verb
+func (t *Task) Validate(goos string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ValidateTimeout)
+	defer cancel()
+
+	resp, stderr, err := t.Plugin.Call(ctx, VerbValidate, t.request(nil))
+	if err != nil {
+		return fmt.Errorf("cannot validate task at path '%s': %w%s", t.Path, err, stderrSuffix(stderr))
+	}
+
+	if resp.Error != "" {
+		return fmt.Errorf("%s at path '%s'", resp.Error, t.Path)
+	}
+
+	return nil
+}
+
+func (t *Task) GetPath() string {
+	return t.Path
+}
+
+func (t *Task) GetOnlyIfCmds() []string {
+	return t.OnlyIf
+}
+
+func (t *Task) GetUnlessCmds() []string {
+	return t.Unless
+}
+
+func (t *Task) GetCreatesFilesList() []string {
+	return t.Creates
+}
+
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && comment == "" {
+		comment = "Nothing changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    t.Name,
+		Comment: strings.TrimSpace(comment),
+	}
+}
+
+func (t *Task) request(facts utils.TemplateVarsMap) *Request {
+	return &Request{
+		TaskType: t.TypeName,
+		Path:     t.Path,
+		Params:   t.Params,
+		DryRun:   t.DryRun,
+		Facts:    facts,
+	}
+}
+
+type TemplateVariablesProvider interface {
+	GetTemplateVariables() (utils.TemplateVarsMap, error)
+}
+
+// Executor runs the tasks of all plugins, the plugin executable is taken from the task
+type Executor struct {
+	Runner                    tacoexec.Runner
+	FsManager                 tasks.FsManager
+	TemplateVariablesProvider TemplateVariablesProvider
+}
+
+// SupportsDryRun tells that the plugins get the dry run in the dry_run field of the request
+func (pe *Executor) SupportsDryRun() bool {
+	return true
+}
+
+// SupportsTimeout tells that the plugin call is cancelled when the context is done
+func (pe *Executor) SupportsTimeout() bool {
+	return true
+}
+
+func (pe *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
+	execRes := executionresult.ExecutionResult{}
+
+	t, ok := task.(*Task)
+	if !ok {
+		execRes.Err = fmt.Errorf("cannot convert task '%v' to Task", task)
+		return execRes
+	}
+	execRes.Name = t.Name
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	execCtx := &tacoexec.Context{
+		Ctx:          ctx,
+		Path:         t.Path,
+		StdoutWriter: &stdoutBuf,
+		StderrWriter: &stderrBuf,
+		Shell:        t.Shell,
+	}
+
+	skipReason, err := conditionals.Check(execCtx, pe.FsManager, pe.Runner, t)
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	if skipReason != "" {
+		execRes.IsSkipped = true
+		execRes.SkipReason = skipReason
+		return execRes
+	}
+
+	facts, err := pe.TemplateVariablesProvider.GetTemplateVariables()
+	if err != nil {
+		execRes.Err = err
+		return execRes
+	}
+
+	start := time.Now()
+
+	req := t.request(facts)
+	// the dry run of the whole script is passed to the plugin as the dry_run of the task
+	req.DryRun = req.DryRun || tasks.IsDryRun(ctx)
+	execRes.DryRun = req.DryRun
+
+	resp, stderr, err := t.Plugin.Call(ctx, VerbRun, req)
+	execRes.Duration = time.Since(start)
+	execRes.StdErr = stderr
+	if stderr != "" {
+		logrus.Debugf("stderr of plugin '%s' at path '%s': %s", t.Plugin.Executable, t.Path, stderr)
+	}
+
+	if err != nil {
+		execRes.Err = fmt.Errorf("%w%s", err, stderrSuffix(stderr))
+		return execRes
+	}
+
+	if resp.Error != "" {
+		execRes.Err = errors.New(resp.Error)
+	}
+	execRes.Comment = resp.Comment
+	execRes.Changes = stringifyChanges(resp.Changes)
+	t.Updated = resp.Changed
+
+	return execRes
+}
+
+// stringifyChanges converts the change values to strings, values which are not strings are JSON encoded
+func stringifyChanges(changes map[string]interface{}) map[string]string {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(changes))
+	for key, val := range changes {
+		if strVal, ok := val.(string); ok {
+			res[key] = strVal
+			continue
+		}
+
+		jsonVal, err := json.Marshal(val)
+		if err != nil {
+			res[key] = fmt.Sprint(val)
+			continue
+		}
+		res[key] = string(jsonVal)
+	}
+
+	return res
+}
+
+func stderrSuffix(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+
+	return ", stderr: " + stderr
+}
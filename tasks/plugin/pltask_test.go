@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/utils"
+)
+
+type templateVariablesProviderMock struct{}
+
+func (tvpm templateVariablesProviderMock) GetTemplateVariables() (utils.TemplateVarsMap, error) {
+	return utils.TemplateVarsMap{utils.OSKernel: "linux"}, nil
+}
+
+func TestTaskExecution(t *testing.T) {
+	skipOnWindows(t)
+
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+	p := writePlugin(t, dir, "app.deployed", fmt.Sprintf(
+		`echo "$REQUEST" > %s; echo '{"changed": true, "comment": "App deployed", "changes": {"version": "1.2", "replicas": 3}}'`,
+		requestFile,
+	))
+
+	task := &Task{
+		TypeName: "app.deployed",
+		Path:     "deploy-app.app.deployed[1]",
+		Plugin:   p,
+		Name:     "shop",
+		DryRun:   true,
+		Params: map[string]interface{}{
+			"name":    "shop",
+			"dry_run": true,
+			"options": map[string]interface{}{"replicas": 3},
+		},
+	}
+
+	executor := &Executor{
+		Runner:                    &tacoexec.RunnerMock{},
+		FsManager:                 &utils.FsManager{},
+		TemplateVariablesProvider: templateVariablesProviderMock{},
+	}
+
+	res := executor.Execute(context.Background(), task)
+
+	require.NoError(t, res.Err)
+	assert.Equal(t, "shop", res.Name)
+	assert.Equal(t, "App deployed", res.Comment)
+	assert.Equal(t, map[string]string{"version": "1.2", "replicas": "3"}, res.Changes)
+	assert.True(t, task.Updated)
+
+	requestBody, err := os.ReadFile(requestFile)
+	require.NoError(t, err)
+
+	request := Request{}
+	require.NoError(t, json.Unmarshal(requestBody, &request))
+	assert.Equal(t, Request{
+		TaskType: "app.deployed",
+		Path:     "deploy-app.app.deployed[1]",
+		Params: map[string]interface{}{
+			"name":    "shop",
+			"dry_run": true,
+			"options": map[string]interface{}{"replicas": float64(3)},
+		},
+		DryRun: true,
+		Facts:  map[string]string{utils.OSKernel: "linux"},
+	}, request)
+}
+
+func TestTaskExecutionFailed(t *testing.T) {
+	skipOnWindows(t)
+
+	p := writePlugin(t, t.TempDir(), "app.deployed", `echo "connection refused" >&2; echo '{"error": "cannot deploy"}'; exit 1`)
+
+	executor := &Executor{
+		Runner:                    &tacoexec.RunnerMock{},
+		FsManager:                 &utils.FsManager{},
+		TemplateVariablesProvider: templateVariablesProviderMock{},
+	}
+
+	task := &Task{TypeName: "app.deployed", Plugin: p, Name: "shop"}
+	res := executor.Execute(context.Background(), task)
+
+	assert.EqualError(t, res.Err, "cannot deploy")
+	assert.Equal(t, "connection refused\n", res.StdErr)
+	assert.False(t, task.Updated)
+}
+
+func TestTaskExecutionSkipped(t *testing.T) {
+	runner := &tacoexec.RunnerMock{
+		ErrToReturn: tacoexec.RunError{Err: fmt.Errorf("exit status 1"), ExitCode: 1},
+	}
+
+	executor := &Executor{
+		Runner:                    runner,
+		FsManager:                 &utils.FsManager{},
+		TemplateVariablesProvider: templateVariablesProviderMock{},
+	}
+
+	res := executor.Execute(context.Background(), &Task{
+		TypeName: "app.deployed",
+		Plugin:   Plugin{Executable: filepath.Join(t.TempDir(), "missing")},
+		OnlyIf:   []string{"false"},
+	})
+
+	assert.NoError(t, res.Err)
+	assert.True(t, res.IsSkipped)
+}
+
+func TestTaskValidation(t *testing.T) {
+	skipOnWindows(t)
+
+	dir := t.TempDir()
+	validPlugin := writePlugin(t, dir, "app.deployed", `echo '{}'`)
+	invalidPlugin := writePlugin(t, dir, "app.removed", `test "$1" = validate && echo '{"error": "missing version"}'`)
+
+	task := &Task{Path: "somepath", Plugin: validPlugin}
+	assert.NoError(t, task.Validate(runtime.GOOS))
+
+	task = &Task{Path: "somepath", Plugin: invalidPlugin}
+	assert.EqualError(t, task.Validate(runtime.GOOS), "missing version at path 'somepath'")
+
+	task = &Task{Path: "somepath", Plugin: Plugin{Executable: filepath.Join(dir, "missing")}}
+	assert.Error(t, task.Validate(runtime.GOOS))
+}
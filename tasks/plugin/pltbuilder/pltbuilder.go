@@ -0,0 +1,60 @@
+package pltbuilder
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/plugin"
+	"github.com/realvnc-labs/tacoscript/tasks/shared/builder"
+)
+
+// TaskBuilder builds the tasks of a plugin, all parameters of the task are kept for the plugin
+type TaskBuilder struct {
+	Plugin plugin.Plugin
+}
+
+func (tb TaskBuilder) Build(typeName, path string, params interface{}) (tasks.CoreTask, error) {
+	task := &plugin.Task{
+		TypeName: typeName,
+		Path:     path,
+		Plugin:   tb.Plugin,
+		Params:   map[string]interface{}{},
+	}
+
+	errs := builder.Build(typeName, path, params, task, nil)
+
+	for _, inputItem := range params.([]interface{}) {
+		row := inputItem.(yaml.MapSlice)[0]
+		task.Params[fmt.Sprint(row.Key)] = toJSONValue(row.Value)
+	}
+
+	return task, errs.ToError()
+}
+
+// toJSONValue converts the yaml maps to maps with string keys, which can be encoded as JSON
+func toJSONValue(value interface{}) interface{} {
+	switch typedVal := value.(type) {
+	case yaml.MapSlice:
+		res := make(map[string]interface{}, len(typedVal))
+		for _, item := range typedVal {
+			res[fmt.Sprint(item.Key)] = toJSONValue(item.Value)
+		}
+		return res
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(typedVal))
+		for key, val := range typedVal {
+			res[fmt.Sprint(key)] = toJSONValue(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(typedVal))
+		for _, item := range typedVal {
+			res = append(res, toJSONValue(item))
+		}
+		return res
+	default:
+		return value
+	}
+}
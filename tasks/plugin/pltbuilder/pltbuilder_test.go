@@ -0,0 +1,64 @@
+package pltbuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/plugin"
+	"gopkg.in/yaml.v2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskBuilder(t *testing.T) {
+	p := plugin.Plugin{Executable: "/usr/local/bin/taco-plugin-app.deployed"}
+
+	ctx := []interface{}{
+		yaml.MapSlice{yaml.MapItem{Key: tasks.NameField, Value: "shop"}},
+		yaml.MapSlice{yaml.MapItem{Key: "version", Value: "1.2"}},
+		yaml.MapSlice{yaml.MapItem{Key: "options", Value: yaml.MapSlice{
+			yaml.MapItem{Key: "replicas", Value: 3},
+			yaml.MapItem{Key: "regions", Value: []interface{}{"eu", yaml.MapSlice{yaml.MapItem{Key: "us", Value: true}}}},
+		}}},
+		yaml.MapSlice{yaml.MapItem{Key: tasks.DryRunField, Value: true}},
+		yaml.MapSlice{yaml.MapItem{Key: tasks.RequireField, Value: "install-runtime"}},
+		yaml.MapSlice{yaml.MapItem{Key: tasks.UnlessField, Value: "test -e /opt/shop"}},
+		yaml.MapSlice{yaml.MapItem{Key: tasks.TimeoutField, Value: "1m"}},
+	}
+
+	task, err := TaskBuilder{Plugin: p}.Build("app.deployed", "somePath", ctx)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &plugin.Task{
+		ExecutionPolicy: tasks.ExecutionPolicy{Timeout: time.Minute},
+		TypeName:        "app.deployed",
+		Path:            "somePath",
+		Plugin:          p,
+		Params: map[string]interface{}{
+			"name":    "shop",
+			"version": "1.2",
+			"options": map[string]interface{}{
+				"replicas": 3,
+				"regions":  []interface{}{"eu", map[string]interface{}{"us": true}},
+			},
+			"dry_run": true,
+			"require": "install-runtime",
+			"unless":  "test -e /opt/shop",
+			"timeout": "1m",
+		},
+		Name:    "shop",
+		DryRun:  true,
+		Require: []string{"install-runtime"},
+		Unless:  []string{"test -e /opt/shop"},
+	}, task)
+}
+
+func TestTaskBuilderError(t *testing.T) {
+	ctx := []interface{}{
+		yaml.MapSlice{yaml.MapItem{Key: tasks.DryRunField, Value: "maybe"}},
+	}
+
+	_, err := TaskBuilder{}.Build("app.deployed", "somePath", ctx)
+	assert.EqualError(t, err, "failed to parse bool value: dry_run")
+}
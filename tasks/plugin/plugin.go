@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ExecutablePrefix is the file name prefix of the plugin executables, the rest of the name is the task type
+	ExecutablePrefix = "taco-plugin-"
+
+	// PathEnv is the environment variable with the directories which are searched for plugins,
+	// the PATH is searched if it's empty
+	PathEnv = "TACO_PLUGIN_PATH"
+
+	// VerbRun applies the task
+	VerbRun = "run"
+	// VerbValidate checks the task parameters
+	VerbValidate = "validate"
+)
+
+// Request is written as JSON to the stdin of the plugin
+type Request struct {
+	TaskType string                 `json:"task_type"`
+	Path     string                 `json:"path"`
+	Params   map[string]interface{} `json:"params"`
+	DryRun   bool                   `json:"dry_run"`
+	// the template variables of the host, they are passed to the run verb only
+	Facts map[string]string `json:"facts,omitempty"`
+}
+
+// Response is read as JSON from the stdout of the plugin
+type Response struct {
+	Changed bool                   `json:"changed"`
+	Comment string                 `json:"comment"`
+	Changes map[string]interface{} `json:"changes"`
+	// the task failed or, for the validate verb, the parameters are invalid
+	Error string `json:"error"`
+}
+
+// Plugin is an executable implementing a task type
+type Plugin struct {
+	Executable string
+}
+
+// Call runs the plugin with the verb as its argument, the plugin is killed if the context is cancelled.
+// The stderr of the plugin is returned for logging.
+func (p Plugin) Call(ctx context.Context, verb string, req *Request) (resp Response, stderr string, err error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return resp, "", err
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Executable, verb) //nolint:gosec // the plugins are trusted executables
+	cmd.Stdin = bytes.NewReader(reqBody)
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	runErr := cmd.Run()
+	stderr = stderrBuf.String()
+
+	decodeErr := json.Unmarshal(stdoutBuf.Bytes(), &resp)
+	if decodeErr == nil {
+		// a plugin can exit with a non-zero code together with an error response
+		if runErr != nil && resp.Error == "" {
+			resp.Error = runErr.Error()
+		}
+		return resp, stderr, nil
+	}
+
+	if runErr != nil {
+		return resp, stderr, fmt.Errorf("plugin '%s' failed: %w", p.Executable, runErr)
+	}
+
+	return resp, stderr, fmt.Errorf("invalid response of plugin '%s': %w", p.Executable, decodeErr)
+}
+
+// Dirs gives the directories which are searched for plugins, pluginPath is a list of directories separated
+// by os.PathListSeparator, TACO_PLUGIN_PATH and PATH are used if it's empty
+func Dirs(pluginPath string) []string {
+	if pluginPath == "" {
+		pluginPath = os.Getenv(PathEnv)
+	}
+	if pluginPath == "" {
+		pluginPath = os.Getenv("PATH")
+	}
+
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(pluginPath) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// Discover finds the plugin executables in the directories by their task type,
+// the first plugin found wins if several directories contain the same plugin
+func Discover(dirs []string) map[string]Plugin {
+	plugins := map[string]Plugin{}
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				logrus.Debugf("cannot read plugin directory '%s': %v", dir, err)
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			typeName, ok := taskTypeOf(entry.Name())
+			if !ok {
+				continue
+			}
+
+			if _, found := plugins[typeName]; found {
+				continue
+			}
+
+			executable := filepath.Join(dir, entry.Name())
+			if !isExecutable(executable) {
+				continue
+			}
+
+			plugins[typeName] = Plugin{Executable: executable}
+		}
+	}
+
+	return plugins
+}
+
+func taskTypeOf(fileName string) (typeName string, ok bool) {
+	if !strings.HasPrefix(fileName, ExecutablePrefix) {
+		return "", false
+	}
+
+	typeName = strings.TrimPrefix(fileName, ExecutablePrefix)
+	if runtime.GOOS == "windows" {
+		if !strings.EqualFold(filepath.Ext(typeName), ".exe") {
+			return "", false
+		}
+		typeName = typeName[:len(typeName)-len(".exe")]
+	}
+
+	return typeName, typeName != ""
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePlugin creates a shell script plugin, the script reads the request from stdin into the REQUEST variable
+func writePlugin(t *testing.T, dir, typeName, body string) Plugin {
+	t.Helper()
+
+	executable := filepath.Join(dir, ExecutablePrefix+typeName)
+	script := "#!/bin/sh\nREQUEST=$(cat)\n" + body + "\n"
+	err := os.WriteFile(executable, []byte(script), 0700) //nolint:gosec // the plugin must be executable
+	require.NoError(t, err)
+
+	return Plugin{Executable: executable}
+}
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugins are shell scripts")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	skipOnWindows(t)
+
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	pluginA := writePlugin(t, dir1, "app.deployed", "")
+	writePlugin(t, dir2, "app.deployed", "")
+	pluginC := writePlugin(t, dir2, "app.removed", "")
+
+	err := os.WriteFile(filepath.Join(dir1, ExecutablePrefix+"not.executable"), []byte(""), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir1, "other-tool"), []byte(""), 0600)
+	require.NoError(t, err)
+
+	plugins := Discover([]string{dir1, filepath.Join(dir1, "missing"), dir2})
+
+	assert.Equal(t, map[string]Plugin{
+		"app.deployed": pluginA,
+		"app.removed":  pluginC,
+	}, plugins)
+}
+
+func TestDirs(t *testing.T) {
+	t.Setenv(PathEnv, "")
+	t.Setenv("PATH", "/usr/bin")
+
+	sep := string(os.PathListSeparator)
+	assert.Equal(t, []string{"/opt/plugins", "/srv/plugins"}, Dirs("/opt/plugins"+sep+sep+"/srv/plugins"))
+	assert.Equal(t, []string{"/usr/bin"}, Dirs(""))
+
+	t.Setenv(PathEnv, "/etc/taco/plugins")
+	assert.Equal(t, []string{"/etc/taco/plugins"}, Dirs(""))
+}
+
+func TestCall(t *testing.T) {
+	skipOnWindows(t)
+
+	testCases := []struct {
+		name             string
+		body             string
+		expectedResponse Response
+		expectedErr      string
+		expectedStderr   string
+	}{
+		{
+			name: "successful run",
+			body: `echo "$1" >&2; echo '{"changed": true, "comment": "deployed", "changes": {"version": "1.2"}}'`,
+			expectedResponse: Response{
+				Changed: true,
+				Comment: "deployed",
+				Changes: map[string]interface{}{"version": "1.2"},
+			},
+			expectedStderr: "run\n",
+		},
+		{
+			name:             "failed run with error response",
+			body:             `echo '{"error": "app not found"}'; exit 2`,
+			expectedResponse: Response{Error: "app not found"},
+		},
+		{
+			name:             "failed run without error message",
+			body:             `echo '{"comment": "partially deployed"}'; exit 2`,
+			expectedResponse: Response{Comment: "partially deployed", Error: "exit status 2"},
+		},
+		{
+			name:        "failed run without response",
+			body:        `exit 3`,
+			expectedErr: "failed: exit status 3",
+		},
+		{
+			name:        "invalid response",
+			body:        `echo 'deployed'`,
+			expectedErr: "invalid response of plugin",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			p := writePlugin(t, t.TempDir(), "app.deployed", tc.body)
+
+			resp, stderr, err := p.Call(context.Background(), VerbRun, &Request{TaskType: "app.deployed"})
+
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.True(t, strings.Contains(err.Error(), tc.expectedErr), err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResponse, resp)
+			assert.Equal(t, tc.expectedStderr, stderr)
+		})
+	}
+}
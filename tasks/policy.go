@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retry defines how often a failed task is repeated
+type Retry struct {
+	// how often the task is run at most, including the first run
+	Attempts int
+	// delay between the runs
+	Interval time.Duration
+	// maximum random delay which is added to the interval, so many hosts don't retry at the same moment
+	Splay time.Duration
+	// the task is repeated until its command exits with this code, nil if the exit code doesn't matter
+	UntilExitCode *int
+}
+
+// ExecutionPolicy holds the settings which are shared by all task types and applied around the task execution,
+// see ExecutorRouter.GetExecutor
+type ExecutionPolicy struct {
+	// the task is cancelled if a single run takes longer than the timeout, zero means no timeout
+	Timeout time.Duration
+	Retry   Retry
+
+	// the failure of the task stops the script execution
+	AbortOnError bool
+	// the failure of the task never stops the script execution, even if the script or the run is failhard
+	ContinueOnError bool
+
+	// the task handles secrets, its env values and the string values of its changes are masked in all output
+	Sensitive bool
+}
+
+// GetExecutionPolicy gives access to the policy of the tasks which embed it
+func (ep *ExecutionPolicy) GetExecutionPolicy() *ExecutionPolicy {
+	return ep
+}
+
+// TaskWithExecutionPolicy is implemented by the tasks which embed ExecutionPolicy
+type TaskWithExecutionPolicy interface {
+	GetExecutionPolicy() *ExecutionPolicy
+}
+
+// AbortsOnError decides if the failure of the task stops the script execution, failHard is true
+// if the script or the run should stop after any failed task
+func (ep *ExecutionPolicy) AbortsOnError(failHard bool) bool {
+	if ep.ContinueOnError {
+		return false
+	}
+
+	return failHard || ep.AbortOnError
+}
+
+// Validate checks the policy values of the task at the path
+func (ep *ExecutionPolicy) Validate(path string) error {
+	if ep.Timeout < 0 {
+		return fmt.Errorf("negative timeout at path '%s.%s'", path, TimeoutField)
+	}
+
+	if ep.Retry.Attempts < 0 || ep.Retry.Interval < 0 || ep.Retry.Splay < 0 {
+		return fmt.Errorf("negative retry value at path '%s.%s'", path, RetryField)
+	}
+
+	if ep.AbortOnError && ep.ContinueOnError {
+		return fmt.Errorf("'%s' and '%s' cannot be combined at path '%s'", AbortOnErrorField, ContinueOnErrorField, path)
+	}
+
+	return nil
+}
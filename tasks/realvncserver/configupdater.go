@@ -5,6 +5,7 @@ package realvncserver
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -16,7 +17,7 @@ import (
 
 	tacoexec "github.com/realvnc-labs/tacoscript/exec"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/fieldstatus"
-	"github.com/realvnc-labs/tacoscript/tasks/support/realvnc"
+	"github.com/realvnc-labs/tacoscript/tasks/support/iniconfig"
 	"github.com/realvnc-labs/tacoscript/utils"
 )
 
@@ -28,19 +29,25 @@ const (
 	DefaultConfigFilePermissions = 0644
 )
 
-func (rvste *Executor) applyConfigChanges(rvst *Task) (addedCount int, updatedCount int, err error) {
-	configValues, outputBuffer, err := newConfigValuesWithOutputBuffer(rvst)
+// journaledConfigFile gives the config file which is recorded in the run journal for the rollback
+func journaledConfigFile(rvst *Task) string {
+	return rvst.ConfigFile
+}
+
+// applyConfigChanges updates the config file, a dry run only counts the changes
+func (rvste *Executor) applyConfigChanges(rvst *Task, dryRun bool) (addedCount int, updatedCount int, err error) {
+	doc, err := readConfigDocument(rvst)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	addedCount, updatedCount, err = rvste.makeChanges(rvst, configValues)
+	addedCount, updatedCount, err = rvste.makeChanges(rvst, doc)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	if addedCount > 0 || updatedCount > 0 {
-		err = commitChanges(rvst, outputBuffer)
+	if (addedCount > 0 || updatedCount > 0) && !dryRun {
+		err = commitChanges(rvst, []byte(doc.String()))
 		if err != nil {
 			return 0, 0, err
 		}
@@ -49,146 +56,34 @@ func (rvste *Executor) applyConfigChanges(rvst *Task) (addedCount int, updatedCo
 	return addedCount, updatedCount, nil
 }
 
-func newConfigValuesWithOutputBuffer(rvst *Task) (
-	configValuesFile *realvnc.ConfigValues, outputBuffer *bytes.Buffer, err error) {
+// readConfigDocument parses the key=value config file, a missing config file gives an empty document
+func readConfigDocument(rvst *Task) (doc *iniconfig.Document, err error) {
 	configFilename := rvst.ConfigFile
 	if configFilename == "" {
-		return nil, nil, errors.New(ErrConfigFileMustBeSpecifiedMsg)
+		return nil, errors.New(ErrConfigFileMustBeSpecifiedMsg)
 	}
 
 	logrus.Debugf("reading config values from %s", configFilename)
-	configValuesFile, err = realvnc.NewConfigValuesFromFile(configFilename)
-	if err != nil {
-		// we can continue ok if no existing config file
-		if !errors.Is(err, os.ErrNotExist) {
-			return nil, nil, err
-		}
+	configBytes, err := os.ReadFile(configFilename)
+	// we can continue ok if no existing config file
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
 	}
 
-	outputBuffer = &bytes.Buffer{}
-	configValuesFile.SetOutputWriter(outputBuffer)
-
-	return configValuesFile, outputBuffer, nil
+	return iniconfig.Parse(string(configBytes), iniconfig.DefaultSeparator), nil
 }
 
-func (rvste *Executor) makeChanges(rvst *Task, configValues *realvnc.ConfigValues) (
-	addedCount int, updatedCount int, err error) {
-	updatedCount, err = rvste.updateExistingValues(rvst, configValues)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	addedCount, err = rvste.addNewValues(rvst, configValues)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	return addedCount, updatedCount, nil
-}
-
-func (rvste *Executor) updateExistingValues(rvst *Task, configValues *realvnc.ConfigValues) (
-	updatedCount int, err error) {
-	updatedCount = 0
-	lineNum := 0
-
-	// if no scanner then we aren't reading an existing config file so no values to update
-	if !configValues.HasScanner() {
-		return 0, nil
-	}
-
+// makeChanges sets or removes the config values with new values, the config file has no sections
+func (rvste *Executor) makeChanges(rvst *Task, doc *iniconfig.Document) (addedCount int, updatedCount int, err error) {
 	logrus.Debugf("checking for config values to update")
 
-	for configValues.Scan() {
-		updated := false
-		inputLine := configValues.Text()
-		lineNum++
-
-		skipLine, existingConfigValue, err := realvnc.ParseConfigKeyValueLine(inputLine)
-		if err != nil {
-			return 0, fmt.Errorf("failed to parse config file line %d: %v", lineNum, err)
-		}
-
-		// if the line doesn't match as a key value pair then just write the line untouched
-		if skipLine {
-			err = configValues.WriteLine(inputLine)
-			if err != nil {
-				return 0, fmt.Errorf("failed to write config file line %d: %v", lineNum, err)
-			}
-			continue
-		}
-
-		fieldName := existingConfigValue.Name
-
-		fieldStatus, found := rvst.fieldTracker.GetFieldStatus(fieldName)
-		if err != nil {
-			return 0, fmt.Errorf("error while finding field %s: %v", fieldName, err)
-		}
-
-		if !found {
-			// if the key value pair isn't found then just write the line untouched
-			err = configValues.WriteLine(inputLine)
-			if err != nil {
-				return 0, fmt.Errorf("failed to write config file value %s at line %d: %v", fieldName, lineNum, err)
-			}
-			continue
-		}
-
-		if !fieldStatus.HasNewValue {
-			// if the key value pair isn't being updated then just write the line untouched
-			err = configValues.WriteLine(inputLine)
-			if err != nil {
-				return 0, fmt.Errorf("failed to write config file value %s at line %d: %v", fieldName, lineNum, err)
-			}
-			continue
-		}
-
-		// if we're removing then we're not write any value so we just fall through and update the
-		// change status and updatedCount
-
-		if !fieldStatus.Clear {
-			changeValue, err := rvst.getChangeValue(fieldName)
-			if err != nil {
-				return 0, err
-			}
-
-			// write the new value
-			err = configValues.WriteValue(changeValue)
-			if err != nil {
-				return 0, fmt.Errorf("failed to write config value %s at line %d: %v", fieldName, lineNum, err)
-			}
-
-			if existingConfigValue.Value != changeValue.Value {
-				updated = true
-				logrus.Debugf(`updated %s with %s`, changeValue.Name, changeValue.Value)
-			}
-		} else {
-			updated = true
-			logrus.Debugf(`removed %s`, fieldName)
-		}
-
-		err = rvst.fieldTracker.SetChangeApplied(fieldName)
-		if err != nil {
-			return 0, fmt.Errorf("failed to update change status %s: %v", fieldName, err)
-		}
-
-		if updated {
-			updatedCount++
-		}
-	}
-
-	return updatedCount, nil
-}
-
-func (rvste *Executor) addNewValues(rvst *Task, configValues *realvnc.ConfigValues) (
-	addedCount int, err error) {
-	addedCount = 0
-
-	logrus.Debugf("checking for new config values")
-
 	err = rvst.fieldTracker.WithNewValues(func(fieldName string, fs fieldstatus.FieldStatus) (err error) {
-		// ignore fields where the change has been applied already (aka updating/cleared fields)
-		if fs.ChangeApplied || fs.Clear {
-			return nil
+		if fs.Clear {
+			if doc.Remove("", fieldName) {
+				updatedCount++
+				logrus.Debugf(`removed %s`, fieldName)
+			}
+			return rvst.fieldTracker.SetChangeApplied(fieldName)
 		}
 
 		// get the current field value as a string
@@ -197,46 +92,27 @@ func (rvste *Executor) addNewValues(rvst *Task, configValues *realvnc.ConfigValu
 			return err
 		}
 
-		// make a new config value to be added
-		newValue := realvnc.ConfigValue{
-			Name:  fieldName,
-			Value: val,
-		}
-
-		err = configValues.WriteValue(newValue)
-		if err != nil {
-			return fmt.Errorf("failed to added config value %s: %v", fieldName, err)
+		added, changed := doc.Set("", fieldName, val)
+		switch {
+		case added:
+			addedCount++
+			logrus.Debugf(`added %s with %s`, fieldName, val)
+		case changed:
+			updatedCount++
+			logrus.Debugf(`updated %s with %s`, fieldName, val)
 		}
 
-		logrus.Debugf(`added %s with %s`, newValue.Name, newValue.Value)
-
-		addedCount++
-		return nil
+		return rvst.fieldTracker.SetChangeApplied(fieldName)
 	})
 
 	if err != nil {
-		return 0, err
-	}
-	return addedCount, nil
-}
-
-func (t *Task) getChangeValue(fieldName string) (changeValue realvnc.ConfigValue, err error) {
-	// get the current field value as a string
-	val, err := t.getFieldValueAsString(fieldName)
-	if err != nil {
-		return changeValue, err
-	}
-
-	// make the new config value for the change
-	changeValue = realvnc.ConfigValue{
-		Name:  fieldName,
-		Value: val,
+		return 0, 0, err
 	}
 
-	return changeValue, nil
+	return addedCount, updatedCount, nil
 }
 
-func commitChanges(rvst *Task, outputBuffer *bytes.Buffer) (err error) {
+func commitChanges(rvst *Task, contents []byte) (err error) {
 	configFilename := rvst.ConfigFile
 	existingConfig := true
 
@@ -273,7 +149,7 @@ func commitChanges(rvst *Task, outputBuffer *bytes.Buffer) (err error) {
 		perms = info.Mode().Perm()
 	}
 
-	err = os.WriteFile(configFilename, outputBuffer.Bytes(), perms)
+	err = os.WriteFile(configFilename, contents, perms)
 	if err != nil {
 		return err
 	}
@@ -301,7 +177,7 @@ func (rvste *Executor) ReloadConfig(rvst *Task) (err error) {
 	cmd.Stderr = &errBuf
 
 	cmdRunner := tacoexec.OSApi{}
-	err = cmdRunner.Run(cmd)
+	err = cmdRunner.Run(context.Background(), cmd)
 	if err != nil {
 		logrus.Debugf("error during settings reload: %s", err)
 		logrus.Debugf("stderr = %s", errBuf.String())
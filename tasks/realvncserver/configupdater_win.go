@@ -5,6 +5,7 @@ package realvncserver
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -27,7 +28,13 @@ var (
 	TestBaseKey = `HKCU:\Software\RealVNCTest\vncserver`
 )
 
-func (rvste *Executor) applyConfigChanges(rvst *Task) (addedCount int, updatedCount int, err error) {
+// journaledConfigFile gives an empty path as the config is kept in the registry which is not recorded in the run journal
+func journaledConfigFile(rvst *Task) string {
+	return ""
+}
+
+// applyConfigChanges updates the registry values, a dry run only counts the changes
+func (rvste *Executor) applyConfigChanges(rvst *Task, dryRun bool) (addedCount int, updatedCount int, err error) {
 	baseKey := getBaseKeyForServerMode(rvst.ServerMode)
 
 	err = rvst.fieldTracker.WithNewValues(func(fieldName string, fs fieldstatus.FieldStatus) (err error) {
@@ -39,7 +46,14 @@ func (rvste *Executor) applyConfigChanges(rvst *Task) (addedCount int, updatedCo
 
 		desc := ""
 
-		if fs.Clear {
+		if dryRun {
+			added, updated, err := countValueChange(baseKey, regPath, regValue, fs.Clear)
+			if err != nil {
+				return err
+			}
+			addedCount += added
+			updatedCount += updated
+		} else if fs.Clear {
 			_, desc, err = winregistry.RemoveValue(baseKey, regPath)
 			if err != nil {
 				return err
@@ -77,6 +91,28 @@ func (rvste *Executor) applyConfigChanges(rvst *Task) (addedCount int, updatedCo
 	return addedCount, updatedCount, nil
 }
 
+// countValueChange compares the registry value with the desired one and counts the change the update would make
+func countValueChange(baseKey, regPath, regValue string, clear bool) (added, updated int, err error) {
+	found, val, err := winregistry.GetValue(baseKey, regPath, winregistry.REG_SZ)
+	if err != nil && !found {
+		return 0, 0, err
+	}
+
+	switch {
+	case clear && found:
+		return 0, 1, nil
+	case clear:
+		return 0, 0, nil
+	case !found:
+		return 1, 0, nil
+	case err != nil || val != regValue:
+		// a value of a different type is replaced
+		return 0, 1, nil
+	}
+
+	return 0, 0, nil
+}
+
 func (rvste *Executor) ReloadConfig(rvst *Task) (err error) {
 	var cmd *exec.Cmd
 
@@ -90,7 +126,7 @@ func (rvste *Executor) ReloadConfig(rvst *Task) (err error) {
 	cmd.Stderr = &errBuf
 
 	cmdRunner := tacoexec.OSApi{}
-	err = cmdRunner.Run(cmd)
+	err = cmdRunner.Run(context.Background(), cmd)
 	if err != nil {
 		logrus.Debugf(`command output = %s`, outBuf.String())
 		logrus.Debugf(`err output = %s`, errBuf.String())
@@ -11,6 +11,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/state"
 	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/conditionals"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/executionresult"
@@ -30,6 +31,8 @@ const (
 )
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	TypeName string // TaskType
 	Path     string // TaskName
 
@@ -152,6 +155,17 @@ type RvsConfigReloader interface {
 	Reload(rvst *Task) (err error)
 }
 
+func (t *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !t.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "Config not changed " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Comment: comment,
+	}
+}
+
 type Executor struct {
 	FsManager tasks.FsManager
 	Runner    tacoexec.Runner
@@ -159,6 +173,11 @@ type Executor struct {
 	Reloader RvsConfigReloader
 }
 
+// SupportsDryRun tells that the executor counts the config changes without applying them in a dry run
+func (rvste *Executor) SupportsDryRun() bool {
+	return true
+}
+
 func (rvste *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
 	start := time.Now()
 
@@ -198,7 +217,30 @@ func (rvste *Executor) Execute(ctx context.Context, task tasks.CoreTask) executi
 		return execRes
 	}
 
-	addedCount, updatedCount, err := rvste.applyConfigChanges(rvst)
+	execRes.DryRun = tasks.IsDryRun(ctx)
+	if execRes.DryRun {
+		addedCount, updatedCount, err := rvste.applyConfigChanges(rvst, true)
+		if err != nil {
+			execRes.Err = err
+			return execRes
+		}
+		if addedCount > 0 || updatedCount > 0 {
+			execRes.Comment = "Config would be updated"
+			execRes.Changes["count"] = fmt.Sprintf("%d config value change(s) would be applied", addedCount+updatedCount)
+		}
+		return execRes
+	}
+
+	if configFile := journaledConfigFile(rvst); configFile != "" {
+		journal := state.JournalFromContext(ctx)
+		if err = journal.Snapshot(configFile); err != nil {
+			execRes.Err = err
+			return execRes
+		}
+		defer journal.RecordOrLog(task.GetPath(), configFile)
+	}
+
+	addedCount, updatedCount, err := rvste.applyConfigChanges(rvst, false)
 	if err != nil {
 		execRes.Err = err
 		return execRes
@@ -0,0 +1,61 @@
+package tasks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Builder creates a task from the parameters of the task in the script, see the builder package for the helpers
+// to parse the parameters
+type Builder interface {
+	Build(typeName, path string, params interface{}) (CoreTask, error)
+}
+
+// Registration is the builder and executor of a registered task type
+type Registration struct {
+	Builder  Builder
+	Executor Executor
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Registration{}
+)
+
+// Register adds a task type which is not built into tacoscript, so it can be used in the scripts run by
+// script.RunScript. It's typically called in the init function of the package implementing the task type.
+// Register panics if the type name is empty or already registered, or if the builder or executor is nil.
+func Register(typeName string, builder Builder, executor Executor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if typeName == "" {
+		panic("tasks: Register with an empty task type")
+	}
+
+	if builder == nil || executor == nil {
+		panic(fmt.Sprintf("tasks: Register of task type '%s' without a builder or executor", typeName))
+	}
+
+	if _, ok := registry[typeName]; ok {
+		panic(fmt.Sprintf("tasks: Register called twice for task type '%s'", typeName))
+	}
+
+	registry[typeName] = Registration{
+		Builder:  builder,
+		Executor: executor,
+	}
+}
+
+// RegisteredTaskTypes gives the registered task types by their type name
+func RegisteredTaskTypes() map[string]Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	registrations := make(map[string]Registration, len(registry))
+	for typeName, registration := range registry {
+		registrations[typeName] = registration
+	}
+
+	return registrations
+}
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type builderMock struct{}
+
+func (bm builderMock) Build(typeName, path string, params interface{}) (CoreTask, error) {
+	return &policyTaskMock{Path: path}, nil
+}
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		registry = map[string]Registration{}
+	}()
+
+	executor := &executorMock{}
+	Register("custom.task", builderMock{}, executor)
+
+	registrations := RegisteredTaskTypes()
+	assert.Len(t, registrations, 1)
+	assert.Equal(t, Registration{Builder: builderMock{}, Executor: executor}, registrations["custom.task"])
+
+	assert.PanicsWithValue(t, "tasks: Register called twice for task type 'custom.task'", func() {
+		Register("custom.task", builderMock{}, executor)
+	})
+	assert.PanicsWithValue(t, "tasks: Register with an empty task type", func() {
+		Register("", builderMock{}, executor)
+	})
+	assert.PanicsWithValue(t, "tasks: Register of task type 'other.task' without a builder or executor", func() {
+		Register("other.task", builderMock{}, nil)
+	})
+
+	// the returned map is a copy
+	delete(registrations, "custom.task")
+	assert.Len(t, RegisteredTaskTypes(), 1)
+}
@@ -76,12 +76,23 @@ func Build(
 
 	outputTaskValues := reflect.Indirect(reflect.ValueOf(outputTask))
 
+	policyTask, hasPolicy := outputTask.(tasks.TaskWithExecutionPolicy)
+
 	for _, inputItem := range inputFields.([]interface{}) {
 		row := inputItem.(yaml.MapSlice)[0]
 
 		inputKey := row.Key.(string)
 		inputVal := row.Value
 
+		// the fields of the execution policy are shared by all task types
+		if hasPolicy {
+			isPolicyField, err := parsePolicyField(policyTask.GetExecutionPolicy(), path, inputKey, inputVal)
+			if isPolicyField {
+				errs.Add(errWithField(err, inputKey))
+				continue
+			}
+		}
+
 		fieldName := mapper.GetFieldName(inputKey)
 
 		if fieldName != "" {
@@ -141,6 +152,10 @@ func Build(
 		}
 	}
 
+	if hasPolicy {
+		errs.Add(policyTask.GetExecutionPolicy().Validate(path))
+	}
+
 	return errs
 }
 
@@ -0,0 +1,66 @@
+package builder
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/conv"
+	"github.com/realvnc-labs/tacoscript/tasks"
+)
+
+// parsePolicyField sets the shared retry, timeout, error handling and sensitive fields of the task execution policy,
+// false is returned if the key isn't a policy field
+func parsePolicyField(policy *tasks.ExecutionPolicy, path, key string, val interface{}) (isPolicyField bool, err error) {
+	switch key {
+	case tasks.TimeoutField:
+		policy.Timeout, err = conv.ConvertToDuration(val)
+	case tasks.RetryField:
+		policy.Retry, err = parseRetry(val, path+"."+tasks.RetryField)
+	case tasks.AbortOnErrorField:
+		policy.AbortOnError, err = conv.ConvertToBool(val)
+	case tasks.ContinueOnErrorField:
+		policy.ContinueOnError, err = conv.ConvertToBool(val)
+	case tasks.SensitiveField:
+		policy.Sensitive, err = conv.ConvertToBool(val)
+	default:
+		return false, nil
+	}
+
+	return true, err
+}
+
+// parseRetry accepts the number of attempts or a map with the attempts, interval, splay and until values
+func parseRetry(val interface{}, path string) (retry tasks.Retry, err error) {
+	retryMap, ok := val.(yaml.MapSlice)
+	if !ok {
+		retry.Attempts, err = conv.ConvertToInt(val)
+		if err != nil {
+			return retry, fmt.Errorf("invalid attempts value '%v' at path '%s': %w", val, path, err)
+		}
+		return retry, nil
+	}
+
+	for _, item := range retryMap {
+		key := fmt.Sprint(item.Key)
+		switch key {
+		case tasks.AttemptsField:
+			retry.Attempts, err = conv.ConvertToInt(item.Value)
+		case tasks.IntervalField:
+			retry.Interval, err = conv.ConvertToDuration(item.Value)
+		case tasks.SplayField:
+			retry.Splay, err = conv.ConvertToDuration(item.Value)
+		case tasks.UntilField:
+			var untilExitCode int
+			untilExitCode, err = conv.ConvertToInt(item.Value)
+			retry.UntilExitCode = &untilExitCode
+		default:
+			return retry, fmt.Errorf("unknown key '%s' at path '%s'", key, path)
+		}
+		if err != nil {
+			return retry, fmt.Errorf("invalid %s value '%v' at path '%s': %w", key, item.Value, path, err)
+		}
+	}
+
+	return retry, nil
+}
@@ -0,0 +1,116 @@
+package builder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/realvnc-labs/tacoscript/tasks"
+	"github.com/realvnc-labs/tacoscript/tasks/filemanaged"
+)
+
+func TestBuildExecutionPolicy(t *testing.T) {
+	untilExitCode := 2
+	zeroExitCode := 0
+
+	testCases := []struct {
+		name           string
+		inputFields    []interface{}
+		expectedPolicy tasks.ExecutionPolicy
+		expectedErr    string
+	}{
+		{
+			name: "retry map",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.TimeoutField, Value: "30s"}},
+				yaml.MapSlice{{Key: tasks.RetryField, Value: yaml.MapSlice{
+					{Key: tasks.AttemptsField, Value: 3},
+					{Key: tasks.IntervalField, Value: "10s"},
+					{Key: tasks.SplayField, Value: "5s"},
+					{Key: tasks.UntilField, Value: 2},
+				}}},
+			},
+			expectedPolicy: tasks.ExecutionPolicy{
+				Timeout: 30 * time.Second,
+				Retry: tasks.Retry{
+					Attempts:      3,
+					Interval:      10 * time.Second,
+					Splay:         5 * time.Second,
+					UntilExitCode: &untilExitCode,
+				},
+			},
+		},
+		{
+			name: "retry attempts",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.RetryField, Value: 5}},
+			},
+			expectedPolicy: tasks.ExecutionPolicy{
+				Retry: tasks.Retry{Attempts: 5},
+			},
+		},
+		{
+			name: "retry until zero",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.RetryField, Value: yaml.MapSlice{{Key: tasks.UntilField, Value: 0}}}},
+			},
+			expectedPolicy: tasks.ExecutionPolicy{
+				Retry: tasks.Retry{UntilExitCode: &zeroExitCode},
+			},
+		},
+		{
+			name: "error handling",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.ContinueOnErrorField, Value: true}},
+			},
+			expectedPolicy: tasks.ExecutionPolicy{ContinueOnError: true},
+		},
+		{
+			name: "sensitive",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.SensitiveField, Value: "true"}},
+			},
+			expectedPolicy: tasks.ExecutionPolicy{Sensitive: true},
+		},
+		{
+			name: "conflicting error handling",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.AbortOnErrorField, Value: "true"}},
+				yaml.MapSlice{{Key: tasks.ContinueOnErrorField, Value: true}},
+			},
+			expectedErr: "'abort_on_error' and 'continue_on_error' cannot be combined at path 'somepath'",
+		},
+		{
+			name: "unknown retry key",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.RetryField, Value: yaml.MapSlice{{Key: "backoff", Value: 2}}}},
+			},
+			expectedErr: "unknown key 'backoff' at path 'somepath.retry': retry",
+		},
+		{
+			name: "negative timeout",
+			inputFields: []interface{}{
+				yaml.MapSlice{{Key: tasks.TimeoutField, Value: "-1s"}},
+			},
+			expectedErr: "negative timeout at path 'somepath.timeout'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			task := &filemanaged.Task{}
+
+			errs := Build(filemanaged.TaskType, "somepath", tc.inputFields, task, nil)
+
+			if tc.expectedErr == "" {
+				assert.NoError(t, errs.ToError())
+				assert.Equal(t, tc.expectedPolicy, task.ExecutionPolicy)
+			} else {
+				assert.EqualError(t, errs.ToError(), tc.expectedErr)
+			}
+		})
+	}
+}
@@ -83,7 +83,14 @@ func checkMissingFileCondition(fsManager tasks.FsManager, task tasks.CoreTask) (
 func runCommands(ctx *tacoexec.Context, runner tacoexec.Runner, cmds []string) (err error) {
 	newCtx := ctx.Copy()
 	newCtx.Cmds = cmds
-	return runner.Run(&newCtx)
+	err = runner.Run(&newCtx)
+
+	// a condition interrupted by the cancellation or the timeout of the run is neither true nor false
+	if ctx.Ctx != nil && ctx.Ctx.Err() != nil {
+		return ctx.Ctx.Err()
+	}
+
+	return err
 }
 
 func checkUnless(ctx *tacoexec.Context, runner tacoexec.Runner, task tasks.CoreTask) (isExpectationSuccess bool, err error) {
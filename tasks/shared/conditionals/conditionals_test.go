@@ -0,0 +1,66 @@
+package conditionals
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/realvnc-labs/tacoscript/apptest"
+	tacoexec "github.com/realvnc-labs/tacoscript/exec"
+)
+
+type conditionalTask struct {
+	onlyIf []string
+	unless []string
+}
+
+func (ct conditionalTask) GetTypeName() string           { return "test.conditional" }
+func (ct conditionalTask) Validate(goos string) error    { return nil }
+func (ct conditionalTask) GetPath() string               { return "conditionalpath" }
+func (ct conditionalTask) GetRequirements() []string     { return nil }
+func (ct conditionalTask) GetCreatesFilesList() []string { return nil }
+func (ct conditionalTask) GetOnlyIfCmds() []string       { return ct.onlyIf }
+func (ct conditionalTask) GetUnlessCmds() []string       { return ct.unless }
+
+func TestCheckInterruptedConditions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		task     conditionalTask
+		deadline bool
+	}{
+		{
+			name: "cancelled onlyif",
+			task: conditionalTask{onlyIf: []string{"sleep 10"}},
+		},
+		{
+			name:     "timed out unless",
+			task:     conditionalTask{unless: []string{"sleep 10"}},
+			deadline: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			var ctx context.Context
+			var cancel context.CancelFunc
+			expectedErr := context.Canceled
+			if tc.deadline {
+				ctx, cancel = context.WithTimeout(context.Background(), 0)
+				expectedErr = context.DeadlineExceeded
+			} else {
+				ctx, cancel = context.WithCancel(context.Background())
+				cancel()
+			}
+			defer cancel()
+
+			// the runner fails like a command killed by the done context
+			runner := &tacoexec.RunnerMock{ErrToReturn: tacoexec.RunError{Err: ctx.Err(), ExitCode: -1}}
+
+			skipReason, err := Check(&tacoexec.Context{Ctx: ctx}, &apptest.FsManagerMock{}, runner, tc.task)
+			assert.ErrorIs(t, err, expectedErr)
+			assert.Empty(t, skipReason)
+		})
+	}
+}
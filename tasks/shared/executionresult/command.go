@@ -0,0 +1,41 @@
+package executionresult
+
+import (
+	"strings"
+
+	"github.com/realvnc-labs/tacoscript/exec"
+)
+
+// CommandChanges gives the pid, exit code and output of a run command, which are reported as the changes of the
+// command tasks
+func (tr *ExecutionResult) CommandChanges(shell string) map[string]interface{} {
+	changes := map[string]interface{}{
+		"pid": tr.Pid,
+	}
+
+	if runErr, ok := tr.Err.(exec.RunError); ok {
+		changes["retcode"] = runErr.ExitCode
+	} else if tr.ExitCode != 0 {
+		changes["retcode"] = tr.ExitCode
+	}
+
+	stdout := strings.TrimSpace(strings.ReplaceAll(tr.StdOut, "\r\n", "\n"))
+	if exec.IsPowerShell(shell) {
+		stdout = powershellUnquote(stdout)
+	}
+
+	changes["stderr"] = strings.TrimSpace(strings.ReplaceAll(tr.StdErr, "\r\n", "\n"))
+	changes["stdout"] = stdout
+
+	return changes
+}
+
+// stdout from multiline powershell scripts often includes trailing spaces on each line.
+// when encoded as yaml, the result does not look pretty. this function strips trailing whitespace
+func powershellUnquote(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -13,9 +13,32 @@ type ExecutionResult struct {
 	IsSkipped  bool
 	SkipReason string
 	Pid        int
-	Name       string
-	Comment    string
-	Changes    map[string]string
+	// exit code of the command, a non-zero exit code can be successful, e.g. if it's in the success_retcodes list
+	ExitCode int
+	Name     string
+	Comment  string
+	Changes  map[string]string
+	// the results of all runs of a task with retries
+	Attempts []AttemptResult
+	// the task ran as a dry run, the changes are the ones the task would make
+	DryRun bool
+}
+
+// AttemptResult is the outcome of a single run of a repeated task
+type AttemptResult struct {
+	Attempt  int
+	Err      error
+	ExitCode int
+	Duration time.Duration
+}
+
+func NewAttemptResult(attempt int, res *ExecutionResult) AttemptResult {
+	return AttemptResult{
+		Attempt:  attempt,
+		Err:      res.Err,
+		ExitCode: res.ExitCode,
+		Duration: res.Duration,
+	}
 }
 
 func (tr *ExecutionResult) String() string {
@@ -0,0 +1,313 @@
+package hostsfile
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultUnixHostsFile    = "/etc/hosts"
+	DefaultWindowsHostsFile = `C:\Windows\System32\drivers\etc\hosts`
+)
+
+var (
+	ErrInvalidIP = errors.New("invalid ip address")
+)
+
+// Entry is a single line of the hosts file. Comments and blank lines are kept as entries without an IP
+// so that the original content and ordering can be written back untouched.
+type Entry struct {
+	Raw       string
+	IP        string
+	Hostnames []string
+	Comment   string
+
+	// separator between the ip and the first hostname, preserved when the entry is rewritten
+	separator string
+	modified  bool
+}
+
+func (e *Entry) IsMapping() bool {
+	return e.IP != ""
+}
+
+func (e *Entry) HasHostname(hostname string) bool {
+	for _, h := range e.Hostnames {
+		if strings.EqualFold(h, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIP tells if the entry maps the ip, the addresses are compared parsed, so ::1 and 0:0:0:0:0:0:0:1 are equal
+func (e *Entry) HasIP(ip string) bool {
+	entryIP := net.ParseIP(e.IP)
+	return entryIP != nil && entryIP.Equal(net.ParseIP(ip))
+}
+
+// sameFamily tells if the entry maps an address of the same family, IPv4 or IPv6, as the ip
+func (e *Entry) sameFamily(ip string) bool {
+	entryIP, otherIP := net.ParseIP(e.IP), net.ParseIP(ip)
+	if entryIP == nil || otherIP == nil {
+		return false
+	}
+
+	return (entryIP.To4() == nil) == (otherIP.To4() == nil)
+}
+
+func (e *Entry) String() string {
+	if !e.modified {
+		return e.Raw
+	}
+
+	line := e.IP + e.separator + strings.Join(e.Hostnames, " ")
+	if e.Comment != "" {
+		line += " " + e.Comment
+	}
+	return line
+}
+
+type HostsFile struct {
+	Entries []*Entry
+
+	newLine        string
+	hasTrailingEOL bool
+}
+
+func DefaultPath(goos string) string {
+	if goos != "windows" {
+		return DefaultUnixHostsFile
+	}
+
+	systemRoot := os.Getenv("SystemRoot")
+	if systemRoot == "" {
+		return DefaultWindowsHostsFile
+	}
+
+	return filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts")
+}
+
+func Parse(contents string) *HostsFile {
+	hf := &HostsFile{
+		newLine:        "\n",
+		hasTrailingEOL: true,
+	}
+
+	if contents == "" {
+		return hf
+	}
+
+	if strings.Contains(contents, "\r\n") {
+		hf.newLine = "\r\n"
+	}
+
+	hf.hasTrailingEOL = strings.HasSuffix(contents, "\n")
+
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	for _, line := range lines {
+		hf.Entries = append(hf.Entries, parseLine(strings.TrimSuffix(line, "\r")))
+	}
+
+	return hf
+}
+
+func parseLine(line string) *Entry {
+	entry := &Entry{
+		Raw:       line,
+		separator: "\t",
+	}
+
+	body := line
+	if commentPos := strings.Index(line, "#"); commentPos >= 0 {
+		body = line[:commentPos]
+		entry.Comment = line[commentPos:]
+	}
+
+	fields := strings.Fields(body)
+	if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+		// comment, blank or unrecognised line which will be kept as it is
+		entry.Comment = ""
+		return entry
+	}
+
+	entry.IP = fields[0]
+	entry.Hostnames = fields[1:]
+
+	afterIP := strings.TrimLeft(body, " \t")[len(entry.IP):]
+	sepLen := len(afterIP) - len(strings.TrimLeft(afterIP, " \t"))
+	if sepLen > 0 {
+		entry.separator = afterIP[:sepLen]
+	}
+
+	return entry
+}
+
+func (hf *HostsFile) String() string {
+	if len(hf.Entries) == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(hf.Entries))
+	for _, entry := range hf.Entries {
+		lines = append(lines, entry.String())
+	}
+
+	res := strings.Join(lines, hf.newLine)
+	if hf.hasTrailingEOL {
+		res += hf.newLine
+	}
+
+	return res
+}
+
+// GetHostnames returns all hostnames mapped to the ip in the order they appear in the file
+func (hf *HostsFile) GetHostnames(ip string) []string {
+	hostnames := []string{}
+	for _, entry := range hf.Entries {
+		if entry.HasIP(ip) {
+			hostnames = append(hostnames, entry.Hostnames...)
+		}
+	}
+	return hostnames
+}
+
+// EnsurePresent makes sure that all hostnames are mapped to the ip. Missing hostnames are added to the first
+// existing entry of the ip or to a new entry at the end of the file. The hostnames are removed from the entries
+// of other ips of the same address family, as the first mapping of a hostname wins the name resolution, a mapping
+// to an IPv4 and an IPv6 address is kept. If clean is true any other hostnames mapped to the ip are removed,
+// so the ip has exactly the given hostnames.
+func (hf *HostsFile) EnsurePresent(ip string, hostnames []string, clean bool) (added, removed []string, err error) {
+	if net.ParseIP(ip) == nil {
+		return nil, nil, fmt.Errorf("%w: '%s'", ErrInvalidIP, ip)
+	}
+
+	removed = hf.removeFromEntries(
+		func(entry *Entry) bool {
+			return entry.sameFamily(ip) && !entry.HasIP(ip)
+		},
+		func(hostname string) bool {
+			return containsFold(hostnames, hostname)
+		},
+	)
+
+	if clean {
+		removed = append(removed, hf.removeFromIP(ip, func(hostname string) bool {
+			return !containsFold(hostnames, hostname)
+		})...)
+	}
+
+	var firstEntry *Entry
+	for _, entry := range hf.Entries {
+		if entry.HasIP(ip) {
+			firstEntry = entry
+			break
+		}
+	}
+
+	existing := hf.GetHostnames(ip)
+	for _, hostname := range hostnames {
+		if containsFold(existing, hostname) || containsFold(added, hostname) {
+			continue
+		}
+		added = append(added, hostname)
+	}
+
+	if len(added) == 0 {
+		return added, removed, nil
+	}
+
+	if firstEntry == nil {
+		hf.Entries = append(hf.Entries, &Entry{
+			IP:        ip,
+			Hostnames: added,
+			separator: "\t",
+			modified:  true,
+		})
+		return added, removed, nil
+	}
+
+	firstEntry.Hostnames = append(firstEntry.Hostnames, added...)
+	firstEntry.modified = true
+
+	return added, removed, nil
+}
+
+// RemoveHostnames removes the hostnames from the entries of the ip. If ip is empty, the hostnames are removed
+// from all entries, if hostnames are empty, all entries of the ip are removed.
+func (hf *HostsFile) RemoveHostnames(ip string, hostnames []string) (removed []string, err error) {
+	if ip != "" && net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrInvalidIP, ip)
+	}
+
+	if len(hostnames) == 0 {
+		return hf.removeFromIP(ip, func(hostname string) bool {
+			return true
+		}), nil
+	}
+
+	return hf.removeFromIP(ip, func(hostname string) bool {
+		return containsFold(hostnames, hostname)
+	}), nil
+}
+
+// removeFromIP removes the hostnames from the entries of the ip, or from all entries if the ip is empty
+func (hf *HostsFile) removeFromIP(ip string, shouldRemove func(hostname string) bool) (removed []string) {
+	return hf.removeFromEntries(
+		func(entry *Entry) bool {
+			return ip == "" || entry.HasIP(ip)
+		},
+		shouldRemove,
+	)
+}
+
+// removeFromEntries removes the hostnames from the matching mapping entries, an entry without hostnames is dropped
+func (hf *HostsFile) removeFromEntries(matches func(entry *Entry) bool, shouldRemove func(hostname string) bool) (removed []string) {
+	entries := make([]*Entry, 0, len(hf.Entries))
+	for _, entry := range hf.Entries {
+		if !entry.IsMapping() || !matches(entry) {
+			entries = append(entries, entry)
+			continue
+		}
+
+		keptHostnames := make([]string, 0, len(entry.Hostnames))
+		for _, hostname := range entry.Hostnames {
+			if shouldRemove(hostname) {
+				removed = append(removed, hostname)
+				continue
+			}
+			keptHostnames = append(keptHostnames, hostname)
+		}
+
+		if len(keptHostnames) == len(entry.Hostnames) {
+			entries = append(entries, entry)
+			continue
+		}
+
+		// entries without hostnames are dropped completely
+		if len(keptHostnames) == 0 {
+			continue
+		}
+
+		entry.Hostnames = keptHostnames
+		entry.modified = true
+		entries = append(entries, entry)
+	}
+
+	hf.Entries = entries
+
+	return removed
+}
+
+func containsFold(items []string, item string) bool {
+	for _, i := range items {
+		if strings.EqualFold(i, item) {
+			return true
+		}
+	}
+	return false
+}
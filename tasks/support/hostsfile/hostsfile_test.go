@@ -0,0 +1,226 @@
+package hostsfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHostsFile = `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	localhost ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local db   # database
+not a mapping line
+`
+
+func TestShouldKeepUnchangedContents(t *testing.T) {
+	hosts := Parse(testHostsFile)
+
+	assert.Equal(t, testHostsFile, hosts.String())
+	assert.Equal(t, []string{"db.local", "db"}, hosts.GetHostnames("10.0.0.5"))
+	assert.Equal(t, []string{"localhost", "ip6-localhost", "ip6-loopback"}, hosts.GetHostnames("::1"))
+}
+
+func TestShouldKeepWindowsLineEndings(t *testing.T) {
+	contents := "# hosts\r\n127.0.0.1 localhost\r\n"
+	hosts := Parse(contents)
+
+	_, _, err := hosts.EnsurePresent("127.0.0.1", []string{"myhost"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "# hosts\r\n127.0.0.1 localhost myhost\r\n", hosts.String())
+}
+
+func TestEnsurePresent(t *testing.T) {
+	testCases := []struct {
+		name             string
+		ip               string
+		hostnames        []string
+		clean            bool
+		expectedAdded    []string
+		expectedRemoved  []string
+		expectedContents string
+		expectedErr      string
+	}{
+		{
+			name:          "add to existing ip",
+			ip:            "10.0.0.5",
+			hostnames:     []string{"db", "postgres"},
+			expectedAdded: []string{"postgres"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	localhost ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local db postgres # database
+not a mapping line
+`,
+		},
+		{
+			name:          "add new ip",
+			ip:            "10.0.0.6",
+			hostnames:     []string{"cache"},
+			expectedAdded: []string{"cache"},
+			expectedContents: testHostsFile + `10.0.0.6	cache
+`,
+		},
+		{
+			name:             "nothing to do",
+			ip:               "10.0.0.5",
+			hostnames:        []string{"DB.local"},
+			expectedContents: testHostsFile,
+		},
+		{
+			name:            "exact hostnames",
+			ip:              "10.0.0.5",
+			hostnames:       []string{"db", "postgres"},
+			clean:           true,
+			expectedAdded:   []string{"postgres"},
+			expectedRemoved: []string{"db.local"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	localhost ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db postgres # database
+not a mapping line
+`,
+		},
+		{
+			name:            "move hostname from another ip",
+			ip:              "10.0.0.7",
+			hostnames:       []string{"db"},
+			expectedAdded:   []string{"db"},
+			expectedRemoved: []string{"db"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	localhost ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local # database
+not a mapping line
+10.0.0.7	db
+`,
+		},
+		{
+			name:             "keep the mapping of the other address family",
+			ip:               "127.0.0.1",
+			hostnames:        []string{"localhost"},
+			expectedContents: testHostsFile,
+		},
+		{
+			name:             "equal ipv6 address",
+			ip:               "0:0:0:0:0:0:0:1",
+			hostnames:        []string{"ip6-localhost"},
+			expectedContents: testHostsFile,
+		},
+		{
+			name:        "invalid ip",
+			ip:          "10.0.0",
+			hostnames:   []string{"db"},
+			expectedErr: "invalid ip address: '10.0.0'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			hosts := Parse(testHostsFile)
+
+			added, removed, err := hosts.EnsurePresent(tc.ip, tc.hostnames, tc.clean)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedAdded, added)
+			assert.Equal(t, tc.expectedRemoved, removed)
+			assert.Equal(t, tc.expectedContents, hosts.String())
+		})
+	}
+}
+
+func TestRemoveHostnames(t *testing.T) {
+	testCases := []struct {
+		name             string
+		ip               string
+		hostnames        []string
+		expectedRemoved  []string
+		expectedContents string
+	}{
+		{
+			name:            "remove hostname from all ips",
+			hostnames:       []string{"localhost"},
+			expectedRemoved: []string{"localhost", "localhost"},
+			expectedContents: `# static table lookup for hostnames
+::1	ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local db   # database
+not a mapping line
+`,
+		},
+		{
+			name:            "remove hostname from one ip",
+			ip:              "::1",
+			hostnames:       []string{"localhost"},
+			expectedRemoved: []string{"localhost"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local db   # database
+not a mapping line
+`,
+		},
+		{
+			name:            "remove hostname from equal ipv6 address",
+			ip:              "0:0:0:0:0:0:0:1",
+			hostnames:       []string{"localhost"},
+			expectedRemoved: []string{"localhost"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	ip6-localhost ip6-loopback
+
+# local services
+10.0.0.5    db.local db   # database
+not a mapping line
+`,
+		},
+		{
+			name:            "remove all hostnames of ip",
+			ip:              "10.0.0.5",
+			expectedRemoved: []string{"db.local", "db"},
+			expectedContents: `# static table lookup for hostnames
+127.0.0.1	localhost
+::1	localhost ip6-localhost ip6-loopback
+
+# local services
+not a mapping line
+`,
+		},
+		{
+			name:             "nothing to remove",
+			hostnames:        []string{"unknown"},
+			expectedContents: testHostsFile,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			hosts := Parse(testHostsFile)
+
+			removed, err := hosts.RemoveHostnames(tc.ip, tc.hostnames)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedRemoved, removed)
+			assert.Equal(t, tc.expectedContents, hosts.String())
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package iniconfig
+
+import (
+	"strings"
+)
+
+const DefaultSeparator = "="
+
+type lineKind int
+
+const (
+	lineOther lineKind = iota
+	lineSection
+	lineOption
+)
+
+// Line is a single line of the config file. Lines which are not changed are written back as they were read,
+// so comments, blank lines, formatting and malformed lines like "=value" are preserved.
+type Line struct {
+	Raw     string
+	Section string
+	Key     string
+	Value   string
+
+	kind lineKind
+	// everything before the option value, e.g. "key = ", kept to preserve the formatting of updated options
+	valuePrefix string
+	modified    bool
+}
+
+func (l *Line) String() string {
+	if !l.modified {
+		return l.Raw
+	}
+
+	switch l.kind {
+	case lineSection:
+		return "[" + l.Section + "]"
+	case lineOption:
+		return l.valuePrefix + l.Value
+	default:
+		return l.Raw
+	}
+}
+
+// Document is a parsed INI or flat key=value config file. Options before the first section header or in files
+// without sections belong to the section with an empty name.
+type Document struct {
+	lines     []*Line
+	separator string
+
+	newLine        string
+	hasTrailingEOL bool
+}
+
+func Parse(contents, separator string) *Document {
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+
+	doc := &Document{
+		separator:      separator,
+		newLine:        "\n",
+		hasTrailingEOL: true,
+	}
+
+	if contents == "" {
+		return doc
+	}
+
+	if strings.Contains(contents, "\r\n") {
+		doc.newLine = "\r\n"
+	}
+	doc.hasTrailingEOL = strings.HasSuffix(contents, "\n")
+
+	currentSection := ""
+	rawLines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	for _, rawLine := range rawLines {
+		line := doc.parseLine(strings.TrimSuffix(rawLine, "\r"), currentSection)
+		currentSection = line.Section
+		doc.lines = append(doc.lines, line)
+	}
+
+	return doc
+}
+
+func (d *Document) parseLine(rawLine, currentSection string) *Line {
+	line := &Line{
+		Raw:     rawLine,
+		Section: currentSection,
+	}
+
+	trimmed := strings.TrimSpace(rawLine)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+		return line
+	}
+
+	if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+		line.kind = lineSection
+		line.Section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+		return line
+	}
+
+	sep := strings.TrimSpace(d.separator)
+	var sepPos, sepLen int
+	if sep == "" {
+		// whitespace separated options like "key value"
+		trimmedLeft := strings.TrimLeft(rawLine, " \t")
+		sepPos = strings.IndexAny(trimmedLeft, " \t")
+		if sepPos >= 0 {
+			sepPos += len(rawLine) - len(trimmedLeft)
+		}
+		sepLen = 1
+	} else {
+		sepPos = strings.Index(rawLine, sep)
+		sepLen = len(sep)
+	}
+
+	// lines without an option name like "=value" are kept as they are
+	if sepPos < 0 || strings.TrimSpace(rawLine[:sepPos]) == "" {
+		return line
+	}
+
+	key := strings.TrimSpace(rawLine[:sepPos])
+
+	afterSep := rawLine[sepPos+sepLen:]
+	value := strings.TrimSpace(afterSep)
+
+	line.kind = lineOption
+	line.Key = key
+	line.Value = value
+	line.valuePrefix = rawLine[:sepPos+sepLen] + afterSep[:len(afterSep)-len(strings.TrimLeft(afterSep, " \t"))]
+
+	return line
+}
+
+func (d *Document) String() string {
+	if len(d.lines) == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(d.lines))
+	for _, line := range d.lines {
+		lines = append(lines, line.String())
+	}
+
+	res := strings.Join(lines, d.newLine)
+	if d.hasTrailingEOL {
+		res += d.newLine
+	}
+
+	return res
+}
+
+func (d *Document) Get(section, key string) (value string, found bool) {
+	line := d.findOption(section, key)
+	if line == nil {
+		return "", false
+	}
+
+	return line.Value, true
+}
+
+// Keys returns all option names of the section in the order they appear
+func (d *Document) Keys(section string) []string {
+	keys := []string{}
+	for _, line := range d.lines {
+		if line.kind == lineOption && line.Section == section {
+			keys = append(keys, line.Key)
+		}
+	}
+
+	return keys
+}
+
+func (d *Document) HasSection(section string) bool {
+	if section == "" {
+		return true
+	}
+
+	for _, line := range d.lines {
+		if line.kind == lineSection && line.Section == section {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddSection adds an empty section to the end of the document if it doesn't exist yet
+func (d *Document) AddSection(section string) (added bool) {
+	if d.HasSection(section) {
+		return false
+	}
+
+	d.lines = append(d.lines, &Line{
+		Section:  section,
+		kind:     lineSection,
+		modified: true,
+	})
+
+	return true
+}
+
+// Set sets the option value, all existing options with the key are updated, so a duplicated key can't keep
+// an old value, otherwise a new option is added to the end of the section. Missing sections are created.
+func (d *Document) Set(section, key, value string) (added, changed bool) {
+	found := false
+	for _, line := range d.lines {
+		if line.kind != lineOption || line.Section != section || line.Key != key {
+			continue
+		}
+		found = true
+		if line.Value != value {
+			line.Value = value
+			line.modified = true
+			changed = true
+		}
+	}
+	if found {
+		return false, changed
+	}
+
+	d.AddSection(section)
+
+	newLine := &Line{
+		Section:     section,
+		Key:         key,
+		Value:       value,
+		kind:        lineOption,
+		valuePrefix: key + d.separator,
+		modified:    true,
+	}
+
+	pos := d.insertPosition(section)
+	d.lines = append(d.lines[:pos], append([]*Line{newLine}, d.lines[pos:]...)...)
+
+	return true, false
+}
+
+// Remove removes all options with the key from the section
+func (d *Document) Remove(section, key string) (removed bool) {
+	lines := make([]*Line, 0, len(d.lines))
+	for _, line := range d.lines {
+		if line.kind == lineOption && line.Section == section && line.Key == key {
+			removed = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+	d.lines = lines
+
+	return removed
+}
+
+func (d *Document) findOption(section, key string) *Line {
+	var found *Line
+	for _, line := range d.lines {
+		if line.kind == lineOption && line.Section == section && line.Key == key {
+			found = line
+		}
+	}
+
+	return found
+}
+
+// insertPosition gives the index after the last option or header of the section
+func (d *Document) insertPosition(section string) int {
+	pos := -1
+	for i, line := range d.lines {
+		if line.Section != section {
+			continue
+		}
+		if line.kind == lineOption || line.kind == lineSection {
+			pos = i
+		}
+	}
+
+	if pos >= 0 {
+		return pos + 1
+	}
+
+	if section != "" {
+		return len(d.lines)
+	}
+
+	// options without a section should go before the first section header
+	for i, line := range d.lines {
+		if line.kind == lineSection {
+			return i
+		}
+	}
+
+	return len(d.lines)
+}
@@ -0,0 +1,257 @@
+package iniconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `; global options
+Debug = false
+
+[server]
+# listening port
+port = 8080
+host=localhost
+
+[client]
+timeout: 10
+`
+
+func TestShouldKeepUnchangedContents(t *testing.T) {
+	doc := Parse(testConfig, "")
+
+	assert.Equal(t, testConfig, doc.String())
+
+	value, found := doc.Get("server", "port")
+	assert.True(t, found)
+	assert.Equal(t, "8080", value)
+
+	value, found = doc.Get("", "Debug")
+	assert.True(t, found)
+	assert.Equal(t, "false", value)
+
+	_, found = doc.Get("client", "timeout")
+	assert.False(t, found)
+
+	assert.Equal(t, []string{"port", "host"}, doc.Keys("server"))
+}
+
+func TestShouldKeepMalformedLines(t *testing.T) {
+	const config = "[server]\n= value\nport = 8080\n"
+
+	doc := Parse(config, "")
+
+	assert.Equal(t, []string{"port"}, doc.Keys("server"))
+
+	_, changed := doc.Set("server", "port", "9090")
+	assert.True(t, changed)
+	assert.Equal(t, "[server]\n= value\nport = 9090\n", doc.String())
+}
+
+func TestDuplicatedKeys(t *testing.T) {
+	const config = "[server]\nport = 8080\nhost = localhost\nport=8081\n"
+
+	doc := Parse(config, "")
+
+	value, found := doc.Get("server", "port")
+	assert.True(t, found)
+	assert.Equal(t, "8081", value)
+
+	added, changed := doc.Set("server", "port", "8081")
+	assert.False(t, added)
+	assert.True(t, changed, "the first occurrence has another value")
+	assert.Equal(t, "[server]\nport = 8081\nhost = localhost\nport=8081\n", doc.String())
+
+	_, changed = doc.Set("server", "port", "8081")
+	assert.False(t, changed)
+
+	assert.True(t, doc.Remove("server", "port"))
+	assert.Equal(t, "[server]\nhost = localhost\n", doc.String())
+}
+
+func TestFlatKeyValueFile(t *testing.T) {
+	const config = "#This is a comment\nDesktop=Build machine\nEncryption=BadValue\nRsaPrivateKeyFile = $HOME/secure/vnc=1"
+
+	doc := Parse(config, DefaultSeparator)
+
+	value, found := doc.Get("", "Desktop")
+	assert.True(t, found)
+	assert.Equal(t, "Build machine", value)
+
+	value, found = doc.Get("", "RsaPrivateKeyFile")
+	assert.True(t, found)
+	assert.Equal(t, "$HOME/secure/vnc=1", value)
+
+	added, changed := doc.Set("", "Encryption", "AlwaysOn")
+	assert.False(t, added)
+	assert.True(t, changed)
+
+	added, _ = doc.Set("", "BlankScreen", "true")
+	assert.True(t, added)
+	assert.True(t, doc.Remove("", "Desktop"))
+
+	assert.Equal(
+		t,
+		"#This is a comment\nEncryption=AlwaysOn\nRsaPrivateKeyFile = $HOME/secure/vnc=1\nBlankScreen=true",
+		doc.String(),
+	)
+}
+
+func TestSet(t *testing.T) {
+	testCases := []struct {
+		name             string
+		section          string
+		key              string
+		value            string
+		expectedAdded    bool
+		expectedChanged  bool
+		expectedContents string
+	}{
+		{
+			name:             "unchanged value",
+			section:          "server",
+			key:              "port",
+			value:            "8080",
+			expectedContents: testConfig,
+		},
+		{
+			name:            "changed value keeps formatting",
+			section:         "server",
+			key:             "port",
+			value:           "9090",
+			expectedChanged: true,
+			expectedContents: `; global options
+Debug = false
+
+[server]
+# listening port
+port = 9090
+host=localhost
+
+[client]
+timeout: 10
+`,
+		},
+		{
+			name:          "new option in existing section",
+			section:       "server",
+			key:           "tls",
+			value:         "true",
+			expectedAdded: true,
+			expectedContents: `; global options
+Debug = false
+
+[server]
+# listening port
+port = 8080
+host=localhost
+tls=true
+
+[client]
+timeout: 10
+`,
+		},
+		{
+			name:          "new global option",
+			section:       "",
+			key:           "Verbose",
+			value:         "1",
+			expectedAdded: true,
+			expectedContents: `; global options
+Debug = false
+Verbose=1
+
+[server]
+# listening port
+port = 8080
+host=localhost
+
+[client]
+timeout: 10
+`,
+		},
+		{
+			name:          "new section",
+			section:       "logging",
+			key:           "level",
+			value:         "info",
+			expectedAdded: true,
+			expectedContents: testConfig + `[logging]
+level=info
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			doc := Parse(testConfig, "")
+
+			added, changed := doc.Set(tc.section, tc.key, tc.value)
+			assert.Equal(t, tc.expectedAdded, added)
+			assert.Equal(t, tc.expectedChanged, changed)
+			assert.Equal(t, tc.expectedContents, doc.String())
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	doc := Parse(testConfig, "")
+
+	assert.True(t, doc.Remove("server", "host"))
+	assert.False(t, doc.Remove("server", "unknown"))
+	assert.False(t, doc.Remove("", "host"))
+
+	assert.Equal(t, `; global options
+Debug = false
+
+[server]
+# listening port
+port = 8080
+
+[client]
+timeout: 10
+`, doc.String())
+}
+
+func TestCustomSeparators(t *testing.T) {
+	testCases := []struct {
+		name             string
+		contents         string
+		separator        string
+		expectedContents string
+	}{
+		{
+			name:             "colon separator",
+			contents:         "[client]\ntimeout: 10\n",
+			separator:        ": ",
+			expectedContents: "[client]\ntimeout: 20\nretries: 3\n",
+		},
+		{
+			name:             "whitespace separator",
+			contents:         "timeout   10\n",
+			separator:        " ",
+			expectedContents: "timeout   20\nretries 3\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			doc := Parse(tc.contents, tc.separator)
+
+			section := ""
+			if doc.HasSection("client") {
+				section = "client"
+			}
+
+			_, changed := doc.Set(section, "timeout", "20")
+			assert.True(t, changed)
+			added, _ := doc.Set(section, "retries", "3")
+			assert.True(t, added)
+
+			assert.Equal(t, tc.expectedContents, doc.String())
+		})
+	}
+}
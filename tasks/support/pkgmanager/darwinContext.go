@@ -37,5 +37,11 @@ func (ecb OsPackageManagerCmdProvider) GetManagementCmds(t *pkgtask.Task) (*Mana
 		UninstallCmds: []string{fmt.Sprintf("brew uninstall %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("brew upgrade %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "brew list --formula --versions",
+		InstalledCmd: func(pkg string) string {
+			return fmt.Sprintf("brew list --formula --versions %s", pkg)
+		},
+		UpgradableCmd: func(pkg string) string {
+			return fmt.Sprintf("brew outdated --formula --quiet %s | grep -qx %s", pkg, pkg)
+		},
 	}, nil
 }
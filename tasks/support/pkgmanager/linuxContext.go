@@ -65,6 +65,10 @@ func (ecb AptCmdsProvider) GetManagementCmds(t *pkgtask.Task) (*ManagementCmds,
 		UninstallCmds: []string{fmt.Sprintf("apt remove -y %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("apt upgrade -y %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "dpkg -l",
+		InstalledCmd:  dpkgInstalledCmd,
+		UpgradableCmd: func(pkg string) string {
+			return fmt.Sprintf("apt list --upgradable %s 2>/dev/null | grep -q '^%s/'", pkg, pkg)
+		},
 	}, nil
 }
 
@@ -81,6 +85,10 @@ func (ecb AptGetCmdsProvider) GetManagementCmds(t *pkgtask.Task) (*ManagementCmd
 		UninstallCmds: []string{fmt.Sprintf("apt-get remove -y %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("apt-get upgrade -y %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "dpkg -l",
+		InstalledCmd:  dpkgInstalledCmd,
+		UpgradableCmd: func(pkg string) string {
+			return fmt.Sprintf("apt-get -s --only-upgrade install %s 2>/dev/null | grep -q '^Inst %s '", pkg, pkg)
+		},
 	}, nil
 }
 
@@ -97,6 +105,10 @@ func (ecb YumCmdsProvider) GetManagementCmds(t *pkgtask.Task) (*ManagementCmds,
 		UninstallCmds: []string{fmt.Sprintf("yum remove -y %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("yum upgrade -y %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "rpm -qa",
+		InstalledCmd:  rpmInstalledCmd,
+		UpgradableCmd: func(pkg string) string {
+			return rpmUpgradableCmd("yum", pkg)
+		},
 	}, nil
 }
 
@@ -113,9 +125,27 @@ func (ecb DnfCmdsProvider) GetManagementCmds(t *pkgtask.Task) (*ManagementCmds,
 		UninstallCmds: []string{fmt.Sprintf("dnf remove -y %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("dnf upgrade -y %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "rpm -qa",
+		InstalledCmd:  rpmInstalledCmd,
+		UpgradableCmd: func(pkg string) string {
+			return rpmUpgradableCmd("dnf", pkg)
+		},
 	}, nil
 }
 
+func dpkgInstalledCmd(pkg string) string {
+	return fmt.Sprintf(`dpkg-query -W -f='${Status}' %s 2>/dev/null | grep -q "ok installed"`, pkg)
+}
+
+func rpmInstalledCmd(pkg string) string {
+	return fmt.Sprintf("rpm -q %s", pkg)
+}
+
+// rpmUpgradableCmd uses the check-update command of yum and dnf, which exits with 100 if upgrades are available
+// and doesn't refresh the expired metadata with the cache only option
+func rpmUpgradableCmd(manager, pkg string) string {
+	return fmt.Sprintf("%s check-update -q --cacheonly %s >/dev/null; test $? -eq 100", manager, pkg)
+}
+
 func buildInstallCmds(rawCmds []string, version string) []string {
 	rawInstallCmds := make([]string, 0, len(rawCmds))
 	if version == "" {
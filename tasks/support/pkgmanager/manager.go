@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/pkgtask"
 	"github.com/realvnc-labs/tacoscript/utils"
 
@@ -22,6 +23,11 @@ type ManagementCmds struct {
 	UpgradeCmds   []string
 	ListCmd       string
 	FilterFunc    func(ctx context.Context, rawPackages []string) []string
+	// InstalledCmd gives the command which succeeds if the package is installed, it's used in a dry run
+	InstalledCmd func(pkg string) string
+	// UpgradableCmd gives the command which succeeds if an upgrade of the installed package is available,
+	// it's used in a dry run of pkg.uptodate which isn't supported without it
+	UpgradableCmd func(pkg string) string
 }
 
 type ManagementCmdsProvider interface {
@@ -80,6 +86,10 @@ func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *pkgtask.Task) (
 		)
 	}
 
+	if tasks.IsDryRun(ctx) {
+		return pm.dryRun(ctx, t, managementCmds)
+	}
+
 	err = pm.updatePkgManagerIfNeeded(ctx, t, managementCmds)
 	if err != nil {
 		return nil, err
@@ -102,6 +112,73 @@ func (pm PackageTaskManager) ExecuteTask(ctx context.Context, t *pkgtask.Task) (
 	return res, nil
 }
 
+// dryRun reports the packages which the task would install, upgrade or remove without refreshing the package manager,
+// so the upgrades are detected with the package lists of the last refresh
+func (pm PackageTaskManager) dryRun(
+	ctx context.Context,
+	t *pkgtask.Task,
+	managementCmds *ManagementCmds,
+) (res *pkgtask.ExecutionResult, err error) {
+	res = &pkgtask.ExecutionResult{
+		Changes: map[string]string{},
+	}
+
+	if managementCmds.InstalledCmd == nil || (t.ActionType == pkgtask.ActionUpdate && managementCmds.UpgradableCmd == nil) {
+		return nil, fmt.Errorf("%w: %s", tasks.ErrDryRunNotSupported, t.TypeName)
+	}
+
+	var changeKey string
+	switch t.ActionType {
+	case pkgtask.ActionInstall:
+		changeKey = "would add [%d]"
+		res.Comment = "The following packages would be installed: "
+	case pkgtask.ActionUpdate:
+		changeKey = "would update [%d]"
+		res.Comment = "The following packages would be updated: "
+	case pkgtask.ActionUninstall:
+		changeKey = "would remove [%d]"
+		res.Comment = "The following packages would be uninstalled: "
+	default:
+		return nil, fmt.Errorf("unknown action type '%v' for task %s", t.ActionType, t.TypeName)
+	}
+
+	for _, pkg := range t.Named.GetNames() {
+		if pm.wouldChange(ctx, t, managementCmds, pkg) {
+			res.Changes[fmt.Sprintf(changeKey, len(res.Changes))] = pkg
+		}
+	}
+
+	if len(res.Changes) == 0 {
+		res.Comment = fmt.Sprintf("The following packages are in the desired state: %s", pm.getAffectedPackagesStr(t))
+		return res, nil
+	}
+
+	packages := make([]string, 0, len(res.Changes))
+	for i := 0; i < len(res.Changes); i++ {
+		packages = append(packages, res.Changes[fmt.Sprintf(changeKey, i)])
+	}
+	res.Comment += strings.Join(packages, ", ")
+
+	return res, nil
+}
+
+// wouldChange tells if the task would change the package, a missing package would be installed by pkg.uptodate as well
+func (pm PackageTaskManager) wouldChange(ctx context.Context, t *pkgtask.Task, managementCmds *ManagementCmds, pkg string) bool {
+	installed := pm.run(ctx, t, &pkgtask.ExecutionResult{}, managementCmds.InstalledCmd(pkg)) == nil
+
+	switch t.ActionType {
+	case pkgtask.ActionUninstall:
+		return installed
+	case pkgtask.ActionUpdate:
+		if !installed {
+			return true
+		}
+		return pm.run(ctx, t, &pkgtask.ExecutionResult{}, managementCmds.UpgradableCmd(pkg)) == nil
+	default:
+		return !installed
+	}
+}
+
 func (pm PackageTaskManager) executePackageMethod(
 	ctx context.Context,
 	t *pkgtask.Task,
@@ -275,6 +352,8 @@ func (pm PackageTaskManager) run(
 		Path:         t.Path,
 		Cmds:         rawCmds,
 		Shell:        t.Shell,
+		Become:       t.Become,
+		BecomeMethod: t.BecomeMethod,
 	}
 
 	err = pm.Runner.Run(execCtx)
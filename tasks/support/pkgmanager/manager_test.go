@@ -9,9 +9,11 @@ import (
 	"testing"
 
 	"github.com/realvnc-labs/tacoscript/exec"
+	"github.com/realvnc-labs/tacoscript/tasks"
 	"github.com/realvnc-labs/tacoscript/tasks/pkgtask"
 	"github.com/realvnc-labs/tacoscript/tasks/shared/names"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type MockedOsPackageManagerCmdProvider struct {
@@ -201,3 +203,119 @@ func TestTaskExecution(t *testing.T) {
 		})
 	}
 }
+
+// dryRunCmdsProvider adds the dry run commands to the mocked commands
+type dryRunCmdsProvider struct {
+	MockedOsPackageManagerCmdProvider
+	NoUpgradableCmd bool
+}
+
+func (drcp dryRunCmdsProvider) GetManagementCmds(t *pkgtask.Task) (*ManagementCmds, error) {
+	cmds, err := drcp.MockedOsPackageManagerCmdProvider.GetManagementCmds(t)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds.InstalledCmd = func(pkg string) string {
+		return "mpmb installed " + pkg
+	}
+	if !drcp.NoUpgradableCmd {
+		cmds.UpgradableCmd = func(pkg string) string {
+			return "mpmb upgradable " + pkg
+		}
+	}
+
+	return cmds, nil
+}
+
+// cmdResultsRunner fails the commands which are not in the succeeding commands
+type cmdResultsRunner struct {
+	succeedingCmds map[string]bool
+	givenCmds      []string
+}
+
+func (crr *cmdResultsRunner) Run(execContext *exec.Context) error {
+	cmd := strings.Join(execContext.Cmds, "\n")
+	crr.givenCmds = append(crr.givenCmds, cmd)
+	if crr.succeedingCmds[cmd] {
+		return nil
+	}
+
+	return exec.RunError{Err: errors.New("exit status 1"), ExitCode: 1}
+}
+
+func TestTaskDryRun(t *testing.T) {
+	testCases := []struct {
+		name            string
+		actionType      pkgtask.PkgActionType
+		noUpgradableCmd bool
+		expectedChanges map[string]string
+		expectedComment string
+		expectedErr     error
+	}{
+		{
+			name:            "install missing package",
+			actionType:      pkgtask.ActionInstall,
+			expectedChanges: map[string]string{"would add [0]": "curl"},
+			expectedComment: "The following packages would be installed: curl",
+		},
+		{
+			name:            "remove installed packages",
+			actionType:      pkgtask.ActionUninstall,
+			expectedChanges: map[string]string{"would remove [0]": "vim", "would remove [1]": "git"},
+			expectedComment: "The following packages would be uninstalled: vim, git",
+		},
+		{
+			name:       "upgrade outdated and missing packages",
+			actionType: pkgtask.ActionUpdate,
+			expectedChanges: map[string]string{
+				"would update [0]": "vim",
+				"would update [1]": "curl",
+			},
+			expectedComment: "The following packages would be updated: vim, curl",
+		},
+		{
+			name:            "upgrades cannot be queried",
+			actionType:      pkgtask.ActionUpdate,
+			noUpgradableCmd: true,
+			expectedErr:     tasks.ErrDryRunNotSupported,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &cmdResultsRunner{succeedingCmds: map[string]bool{
+				"mpmb --version":       true,
+				"mpmb installed vim":   true,
+				"mpmb installed git":   true,
+				"mpmb upgradable vim":  true,
+				"mpmb upgradable curl": true,
+			}}
+			mngr := PackageTaskManager{
+				Runner: runner,
+				ManagementCmdsProviderBuildFunc: func() ([]ManagementCmdsProvider, error) {
+					return []ManagementCmdsProvider{dryRunCmdsProvider{NoUpgradableCmd: tc.noUpgradableCmd}}, nil
+				},
+			}
+
+			task := &pkgtask.Task{
+				TypeName:   "pkg.test",
+				ActionType: tc.actionType,
+				Named:      names.TaskNames{Names: []string{"vim", "git", "curl"}},
+			}
+			res, err := mngr.ExecuteTask(tasks.WithDryRun(context.Background()), task)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedChanges, res.Changes)
+			assert.Equal(t, tc.expectedComment, res.Comment)
+			for _, cmd := range runner.givenCmds {
+				assert.NotContains(t, []string{"mpmb upgrade", "mpmb list"}, cmd, "a dry run doesn't refresh or change anything")
+			}
+		})
+	}
+}
@@ -34,6 +34,12 @@ func (ecb OsPackageManagerCmdProvider) GetManagementCmds(t *pkgtask.Task) (*Mana
 		UninstallCmds: []string{fmt.Sprintf("choco uninstall -y %s", strings.Join(rawCmds, " "))},
 		UpgradeCmds:   []string{fmt.Sprintf("choco upgrade -y %s", strings.Join(rawCmds, " "))},
 		ListCmd:       "choco list --local-only",
+		InstalledCmd: func(pkg string) string {
+			return fmt.Sprintf(`choco list --local-only --exact --limit-output %s | findstr /b /i "%s|"`, pkg, pkg)
+		},
+		UpgradableCmd: func(pkg string) string {
+			return fmt.Sprintf(`choco outdated --limit-output | findstr /b /i "%s|"`, pkg)
+		},
 		FilterFunc: func(ctx context.Context, rawPackages []string) []string {
 			res := make([]string, 0, len(rawPackages))
 			for _, rawPackage := range rawPackages {
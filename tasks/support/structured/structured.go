@@ -0,0 +1,665 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+
+	defaultJSONIndent = "  "
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrEmptyPath     = errors.New("empty path")
+)
+
+// ParseFormat gives the document format by its name or, if the name is empty, by the extension of the filename
+func ParseFormat(name, filename string) (Format, error) {
+	if name == "" {
+		name = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("%w '%s', supported formats are json, yaml and toml", ErrUnknownFormat, name)
+	}
+}
+
+// ChangeSet contains the paths of all values which were touched by the document modifications
+type ChangeSet struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (cs *ChangeSet) IsEmpty() bool {
+	return len(cs.Added) == 0 && len(cs.Changed) == 0 && len(cs.Removed) == 0
+}
+
+// Document is a parsed JSON, YAML or TOML document. Maps are kept as yaml.MapSlice to preserve the order of the keys
+// where the format allows it, sequences are kept as []interface{}.
+type Document struct {
+	Root    interface{}
+	Changes ChangeSet
+
+	format     Format
+	jsonIndent string
+}
+
+func Parse(format Format, contents string) (doc *Document, err error) {
+	doc = &Document{
+		format:     format,
+		jsonIndent: defaultJSONIndent,
+	}
+
+	if strings.TrimSpace(contents) == "" {
+		doc.Root = yaml.MapSlice{}
+		return doc, nil
+	}
+
+	switch format {
+	case FormatJSON:
+		doc.Root, err = parseJSON(contents)
+		doc.jsonIndent = detectIndent(contents)
+	case FormatYAML:
+		var root yaml.MapSlice
+		err = yaml.Unmarshal([]byte(contents), &root)
+		if err != nil {
+			// documents with a sequence or a scalar as root
+			var anyRoot interface{}
+			if yaml.Unmarshal([]byte(contents), &anyRoot) != nil {
+				return nil, err
+			}
+			doc.Root, err = normalize(anyRoot), nil
+		} else {
+			doc.Root = root
+		}
+	case FormatTOML:
+		root := map[string]interface{}{}
+		_, err = toml.Decode(contents, &root)
+		doc.Root = normalize(root)
+	default:
+		err = fmt.Errorf("%w '%s'", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (d *Document) String() (string, error) {
+	switch d.format {
+	case FormatJSON:
+		buf := &bytes.Buffer{}
+		err := writeJSON(buf, d.Root, d.jsonIndent, 0)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString("\n")
+		return buf.String(), nil
+	case FormatYAML:
+		res, err := yaml.Marshal(d.Root)
+		if err != nil {
+			return "", err
+		}
+		return string(res), nil
+	case FormatTOML:
+		root, ok := toStringMap(d.Root).(map[string]interface{})
+		if !ok {
+			return "", errors.New("the root of a toml document should be a table")
+		}
+		buf := &bytes.Buffer{}
+		enc := toml.NewEncoder(buf)
+		enc.Indent = ""
+		err := enc.Encode(root)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		return "", fmt.Errorf("%w '%s'", ErrUnknownFormat, d.format)
+	}
+}
+
+// Set sets the value at the dot separated path, missing maps on the path are created
+func (d *Document) Set(path string, value interface{}) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	root, err := d.setValue(d.Root, keys, 0, normalize(value))
+	if err != nil {
+		return err
+	}
+	d.Root = root
+
+	return nil
+}
+
+// Delete removes the value at the dot separated path, missing paths are ignored
+func (d *Document) Delete(path string) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	root, err := d.deleteValue(d.Root, keys, 0)
+	if err != nil {
+		return err
+	}
+	d.Root = root
+
+	return nil
+}
+
+// Merge merges the values into the document recursively, maps are merged key by key, all other values are replaced
+func (d *Document) Merge(values yaml.MapSlice) error {
+	root, err := d.mergeValue(d.Root, normalize(values), nil)
+	if err != nil {
+		return err
+	}
+	d.Root = root
+
+	return nil
+}
+
+func (d *Document) setValue(node interface{}, keys []string, pos int, value interface{}) (interface{}, error) {
+	path := keys[:pos+1]
+	key := keys[pos]
+	isLast := pos == len(keys)-1
+
+	switch typedNode := node.(type) {
+	case yaml.MapSlice:
+		for i := range typedNode {
+			if fmt.Sprint(typedNode[i].Key) != key {
+				continue
+			}
+			if isLast {
+				if !valuesEqual(typedNode[i].Value, value) {
+					typedNode[i].Value = value
+					d.Changes.Changed = append(d.Changes.Changed, joinPath(path))
+				}
+				return typedNode, nil
+			}
+			child, err := d.setValue(typedNode[i].Value, keys, pos+1, value)
+			if err != nil {
+				return nil, err
+			}
+			typedNode[i].Value = child
+			return typedNode, nil
+		}
+
+		if isLast {
+			d.Changes.Added = append(d.Changes.Added, joinPath(path))
+			return append(typedNode, yaml.MapItem{Key: key, Value: value}), nil
+		}
+
+		child, err := d.setValue(yaml.MapSlice{}, keys, pos+1, value)
+		if err != nil {
+			return nil, err
+		}
+		return append(typedNode, yaml.MapItem{Key: key, Value: child}), nil
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > len(typedNode) {
+			return nil, fmt.Errorf("invalid index '%s' at path '%s'", key, joinPath(path))
+		}
+		if index == len(typedNode) {
+			var child interface{} = value
+			if !isLast {
+				child, err = d.setValue(yaml.MapSlice{}, keys, pos+1, value)
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				d.Changes.Added = append(d.Changes.Added, joinPath(path))
+			}
+			return append(typedNode, child), nil
+		}
+		if isLast {
+			if !valuesEqual(typedNode[index], value) {
+				typedNode[index] = value
+				d.Changes.Changed = append(d.Changes.Changed, joinPath(path))
+			}
+			return typedNode, nil
+		}
+		child, err := d.setValue(typedNode[index], keys, pos+1, value)
+		if err != nil {
+			return nil, err
+		}
+		typedNode[index] = child
+		return typedNode, nil
+	case nil:
+		return d.setValue(yaml.MapSlice{}, keys, pos, value)
+	default:
+		return nil, fmt.Errorf("cannot set value at path '%s', the value at '%s' is not a map or a list", joinPath(keys), joinPath(keys[:pos]))
+	}
+}
+
+func (d *Document) deleteValue(node interface{}, keys []string, pos int) (interface{}, error) {
+	path := keys[:pos+1]
+	key := keys[pos]
+	isLast := pos == len(keys)-1
+
+	switch typedNode := node.(type) {
+	case yaml.MapSlice:
+		for i := range typedNode {
+			if fmt.Sprint(typedNode[i].Key) != key {
+				continue
+			}
+			if isLast {
+				d.Changes.Removed = append(d.Changes.Removed, joinPath(path))
+				return append(typedNode[:i], typedNode[i+1:]...), nil
+			}
+			child, err := d.deleteValue(typedNode[i].Value, keys, pos+1)
+			if err != nil {
+				return nil, err
+			}
+			typedNode[i].Value = child
+			return typedNode, nil
+		}
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(typedNode) {
+			return typedNode, nil
+		}
+		if isLast {
+			d.Changes.Removed = append(d.Changes.Removed, joinPath(path))
+			return append(typedNode[:index], typedNode[index+1:]...), nil
+		}
+		child, err := d.deleteValue(typedNode[index], keys, pos+1)
+		if err != nil {
+			return nil, err
+		}
+		typedNode[index] = child
+		return typedNode, nil
+	}
+
+	return node, nil
+}
+
+func (d *Document) mergeValue(node, value interface{}, path []string) (interface{}, error) {
+	valueMap, isValueMap := value.(yaml.MapSlice)
+	nodeMap, isNodeMap := node.(yaml.MapSlice)
+	if node == nil {
+		nodeMap, isNodeMap = yaml.MapSlice{}, true
+	}
+
+	if !isValueMap || !isNodeMap {
+		if len(path) == 0 {
+			return nil, errors.New("cannot merge values into a document which is not a map")
+		}
+		if !valuesEqual(node, value) {
+			d.Changes.Changed = append(d.Changes.Changed, joinPath(path))
+		}
+		return value, nil
+	}
+
+	for _, item := range valueMap {
+		key := fmt.Sprint(item.Key)
+		itemPath := append(append([]string{}, path...), key)
+
+		found := false
+		for i := range nodeMap {
+			if fmt.Sprint(nodeMap[i].Key) != key {
+				continue
+			}
+			found = true
+			child, err := d.mergeValue(nodeMap[i].Value, item.Value, itemPath)
+			if err != nil {
+				return nil, err
+			}
+			nodeMap[i].Value = child
+			break
+		}
+
+		if !found {
+			d.Changes.Added = append(d.Changes.Added, joinPath(itemPath))
+			nodeMap = append(nodeMap, yaml.MapItem{Key: key, Value: item.Value})
+		}
+	}
+
+	return nodeMap, nil
+}
+
+// splitPath splits the path by dots, a dot which is part of a key can be escaped with a backslash
+func splitPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, ErrEmptyPath
+	}
+
+	keys := []string{}
+	current := strings.Builder{}
+	escaped := false
+	for _, r := range path {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '.':
+			keys = append(keys, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	keys = append(keys, current.String())
+
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("%w segment in '%s'", ErrEmptyPath, path)
+		}
+	}
+
+	return keys, nil
+}
+
+func joinPath(keys []string) string {
+	escapedKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		escapedKeys = append(escapedKeys, strings.ReplaceAll(key, ".", `\.`))
+	}
+
+	return strings.Join(escapedKeys, ".")
+}
+
+// normalize converts all maps to yaml.MapSlice with string keys, keys of unordered maps are sorted
+func normalize(value interface{}) interface{} {
+	switch typedVal := value.(type) {
+	case yaml.MapSlice:
+		res := make(yaml.MapSlice, 0, len(typedVal))
+		for _, item := range typedVal {
+			res = append(res, yaml.MapItem{Key: fmt.Sprint(item.Key), Value: normalize(item.Value)})
+		}
+		return res
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedVal))
+		for key := range typedVal {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		res := make(yaml.MapSlice, 0, len(typedVal))
+		for _, key := range keys {
+			res = append(res, yaml.MapItem{Key: key, Value: normalize(typedVal[key])})
+		}
+		return res
+	case map[interface{}]interface{}:
+		stringMap := make(map[string]interface{}, len(typedVal))
+		for key, val := range typedVal {
+			stringMap[fmt.Sprint(key)] = val
+		}
+		return normalize(stringMap)
+	case []map[string]interface{}:
+		res := make([]interface{}, 0, len(typedVal))
+		for _, item := range typedVal {
+			res = append(res, normalize(item))
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(typedVal))
+		for _, item := range typedVal {
+			res = append(res, normalize(item))
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+func toStringMap(value interface{}) interface{} {
+	switch typedVal := value.(type) {
+	case yaml.MapSlice:
+		res := make(map[string]interface{}, len(typedVal))
+		for _, item := range typedVal {
+			res[fmt.Sprint(item.Key)] = toStringMap(item.Value)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(typedVal))
+		for _, item := range typedVal {
+			res = append(res, toStringMap(item))
+		}
+		return res
+	case json.Number:
+		if intVal, err := typedVal.Int64(); err == nil {
+			return intVal
+		}
+		floatVal, _ := typedVal.Float64()
+		return floatVal
+	default:
+		return value
+	}
+}
+
+// valuesEqual compares values semantically, so the order of map keys and the numeric types are ignored
+func valuesEqual(left, right interface{}) bool {
+	leftMap, isLeftMap := left.(yaml.MapSlice)
+	rightMap, isRightMap := right.(yaml.MapSlice)
+	if isLeftMap || isRightMap {
+		if !isLeftMap || !isRightMap || len(leftMap) != len(rightMap) {
+			return false
+		}
+		for _, leftItem := range leftMap {
+			found := false
+			for _, rightItem := range rightMap {
+				if fmt.Sprint(leftItem.Key) == fmt.Sprint(rightItem.Key) {
+					found = valuesEqual(leftItem.Value, rightItem.Value)
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	leftList, isLeftList := left.([]interface{})
+	rightList, isRightList := right.([]interface{})
+	if isLeftList || isRightList {
+		if !isLeftList || !isRightList || len(leftList) != len(rightList) {
+			return false
+		}
+		for i := range leftList {
+			if !valuesEqual(leftList[i], rightList[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	leftNumber, isLeftNumber := toFloat(left)
+	rightNumber, isRightNumber := toFloat(right)
+	if isLeftNumber && isRightNumber {
+		return leftNumber == rightNumber
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch typedVal := value.(type) {
+	case json.Number:
+		res, err := typedVal.Float64()
+		return res, err == nil
+	case int:
+		return float64(typedVal), true
+	case int64:
+		return float64(typedVal), true
+	case uint64:
+		return float64(typedVal), true
+	case float64:
+		return typedVal, true
+	default:
+		return 0, false
+	}
+}
+
+func parseJSON(contents string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(contents))
+	dec.UseNumber()
+
+	root, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid json: unexpected data after the top-level value")
+	}
+
+	return root, nil
+}
+
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, isDelim := token.(json.Delim)
+	if !isDelim {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		res := yaml.MapSlice{}
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, yaml.MapItem{Key: fmt.Sprint(keyToken), Value: value})
+		}
+		_, err = dec.Token()
+		return res, err
+	case '[':
+		res := []interface{}{}
+		for dec.More() {
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, value)
+		}
+		_, err = dec.Token()
+		return res, err
+	default:
+		return nil, fmt.Errorf("invalid json: unexpected delimiter '%s'", delim)
+	}
+}
+
+// detectIndent gives the indentation of the first indented line so that rewritten files keep their formatting
+func detectIndent(contents string) string {
+	for _, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+
+	return defaultJSONIndent
+}
+
+func writeJSON(buf *bytes.Buffer, value interface{}, indent string, level int) error {
+	switch typedVal := value.(type) {
+	case yaml.MapSlice:
+		if len(typedVal) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i, item := range typedVal {
+			buf.WriteString(strings.Repeat(indent, level+1))
+			err := writeJSONScalar(buf, fmt.Sprint(item.Key))
+			if err != nil {
+				return err
+			}
+			buf.WriteString(": ")
+			err = writeJSON(buf, item.Value, indent, level+1)
+			if err != nil {
+				return err
+			}
+			if i < len(typedVal)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(strings.Repeat(indent, level) + "}")
+	case []interface{}:
+		if len(typedVal) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range typedVal {
+			buf.WriteString(strings.Repeat(indent, level+1))
+			err := writeJSON(buf, item, indent, level+1)
+			if err != nil {
+				return err
+			}
+			if i < len(typedVal)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(strings.Repeat(indent, level) + "]")
+	default:
+		return writeJSONScalar(buf, value)
+	}
+
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, value interface{}) error {
+	if number, ok := value.(json.Number); ok {
+		buf.WriteString(number.String())
+		return nil
+	}
+
+	scalarBuf := &bytes.Buffer{}
+	enc := json.NewEncoder(scalarBuf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(value)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(bytes.TrimSuffix(scalarBuf.Bytes(), []byte("\n")))
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package structured
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		name           string
+		formatName     string
+		filename       string
+		expectedFormat Format
+		expectedErr    string
+	}{
+		{name: "json extension", filename: "/etc/app/config.json", expectedFormat: FormatJSON},
+		{name: "yml extension", filename: "config.YML", expectedFormat: FormatYAML},
+		{name: "toml extension", filename: "config.toml", expectedFormat: FormatTOML},
+		{name: "explicit format", formatName: "yaml", filename: "config.conf", expectedFormat: FormatYAML},
+		{
+			name:        "unknown extension",
+			filename:    "config.conf",
+			expectedErr: "unknown format 'conf', supported formats are json, yaml and toml",
+		},
+	}
+
+	for _, testCase := range testCases {
+		tc := testCase
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := ParseFormat(tc.formatName, tc.filename)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFormat, format)
+		})
+	}
+}
+
+func TestJSONDocument(t *testing.T) {
+	const contents = `{
+    "name": "app",
+    "server": {
+        "port": 8080,
+        "ratio": 1.50,
+        "hosts": ["a", "b"]
+    },
+    "debug": true
+}
+`
+
+	doc, err := Parse(FormatJSON, contents)
+	require.NoError(t, err)
+
+	// values with the same meaning are not changes
+	require.NoError(t, doc.Set("server.port", 8080))
+	require.NoError(t, doc.Set("server.ratio", 1.5))
+	assert.True(t, doc.Changes.IsEmpty())
+
+	require.NoError(t, doc.Set("server.tls.enabled", true))
+	require.NoError(t, doc.Set("server.hosts.1", "c"))
+	require.NoError(t, doc.Set("server.hosts.2", "<d>"))
+	require.NoError(t, doc.Delete("debug"))
+	require.NoError(t, doc.Delete("unknown.path"))
+
+	assert.Equal(t, ChangeSet{
+		Added:   []string{"server.tls.enabled", "server.hosts.2"},
+		Changed: []string{"server.hosts.1"},
+		Removed: []string{"debug"},
+	}, doc.Changes)
+
+	actual, err := doc.String()
+	require.NoError(t, err)
+	assert.Equal(t, `{
+    "name": "app",
+    "server": {
+        "port": 8080,
+        "ratio": 1.50,
+        "hosts": [
+            "a",
+            "c",
+            "<d>"
+        ],
+        "tls": {
+            "enabled": true
+        }
+    }
+}
+`, actual)
+}
+
+func TestYAMLDocumentMerge(t *testing.T) {
+	const contents = `server:
+  port: 8080
+  host: localhost
+users:
+- alice
+`
+
+	doc, err := Parse(FormatYAML, contents)
+	require.NoError(t, err)
+
+	err = doc.Merge(yaml.MapSlice{
+		{Key: "server", Value: yaml.MapSlice{
+			{Key: "port", Value: 9090},
+			{Key: "host", Value: "localhost"},
+			{Key: "tls", Value: yaml.MapSlice{{Key: "enabled", Value: true}}},
+		}},
+		{Key: "users", Value: []interface{}{"alice", "bob"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ChangeSet{
+		Added:   []string{"server.tls"},
+		Changed: []string{"server.port", "users"},
+	}, doc.Changes)
+
+	actual, err := doc.String()
+	require.NoError(t, err)
+	assert.Equal(t, `server:
+  port: 9090
+  host: localhost
+  tls:
+    enabled: true
+users:
+- alice
+- bob
+`, actual)
+}
+
+func TestTOMLDocument(t *testing.T) {
+	const contents = `title = "app"
+
+[server]
+port = 8080
+`
+
+	doc, err := Parse(FormatTOML, contents)
+	require.NoError(t, err)
+
+	require.NoError(t, doc.Set("server.port", 8080))
+	assert.True(t, doc.Changes.IsEmpty())
+
+	require.NoError(t, doc.Set("server.host", "localhost"))
+	require.NoError(t, doc.Delete("title"))
+
+	actual, err := doc.String()
+	require.NoError(t, err)
+	assert.Equal(t, `[server]
+host = "localhost"
+port = 8080
+`, actual)
+}
+
+func TestEscapedPaths(t *testing.T) {
+	doc, err := Parse(FormatJSON, `{"example.com": {"port": 80}}`)
+	require.NoError(t, err)
+
+	require.NoError(t, doc.Set(`example\.com.port`, 443))
+	assert.Equal(t, []string{`example\.com.port`}, doc.Changes.Changed)
+
+	err = doc.Set("example..port", 1)
+	assert.ErrorIs(t, err, ErrEmptyPath)
+}
+
+func TestSetInvalidPath(t *testing.T) {
+	doc, err := Parse(FormatYAML, "name: app\nlist: []\n")
+	require.NoError(t, err)
+
+	err = doc.Set("name.first", "x")
+	assert.EqualError(t, err, "cannot set value at path 'name.first', the value at 'name' is not a map or a list")
+
+	err = doc.Set("list.5", "x")
+	assert.EqualError(t, err, "invalid index '5' at path 'list.5'")
+}
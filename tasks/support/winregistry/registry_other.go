@@ -25,6 +25,10 @@ func RemoveKey(regPath string) (updated bool, desc string, err error) {
 	return false, "", ErrFnNotImplemented
 }
 
+func KeyExists(regPath string) (exists bool, err error) {
+	return false, ErrFnNotImplemented
+}
+
 func HasValidRootKey(rootKey string) (err error) {
 	return ErrFnNotImplemented
 }
@@ -161,6 +161,24 @@ func RemoveKey(regPath string) (updated bool, desc string, err error) {
 	return true, "key removed", nil
 }
 
+func KeyExists(regPath string) (exists bool, err error) {
+	key, keyPath, err := getRootKey(regPath)
+	if err != nil {
+		return false, err
+	}
+
+	k, err := registry.OpenKey(key, keyPath, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	k.Close()
+
+	return true, nil
+}
+
 func HasValidRootKey(rootKey string) (err error) {
 	_, _, err = getRootKey(rootKey)
 	return err
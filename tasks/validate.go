@@ -3,6 +3,8 @@ package tasks
 import (
 	"fmt"
 	"strings"
+
+	"github.com/realvnc-labs/tacoscript/exec"
 )
 
 func ValidateRequired(val, path string) error {
@@ -22,3 +24,16 @@ func ValidateRequiredMany(vals []string, path string) error {
 
 	return fmt.Errorf("empty required values at path '%s'", path)
 }
+
+// ValidateBecome checks the privilege escalation settings of a task
+func ValidateBecome(become bool, becomeMethod, path, goos string) error {
+	if err := exec.ValidateBecomeMethod(becomeMethod); err != nil {
+		return fmt.Errorf("%w at path '%s.%s'", err, path, BecomeMethodField)
+	}
+
+	if become && goos == "windows" {
+		return fmt.Errorf("privilege escalation is not supported on Windows at path '%s.%s'", path, BecomeField)
+	}
+
+	return nil
+}
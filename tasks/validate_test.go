@@ -38,3 +38,41 @@ func TestValidateRequired(t *testing.T) {
 		assert.EqualError(t, actualErr, testCase.ExpectedErrMsg)
 	}
 }
+
+func TestValidateBecome(t *testing.T) {
+	testCases := []struct {
+		become         bool
+		becomeMethod   string
+		goos           string
+		ExpectedErrMsg string
+	}{
+		{
+			become: true,
+			goos:   "linux",
+		},
+		{
+			become:       true,
+			becomeMethod: "doas",
+			goos:         "darwin",
+		},
+		{
+			becomeMethod:   "runas",
+			goos:           "linux",
+			ExpectedErrMsg: "unknown become method 'runas', supported methods are 'sudo', 'su' and 'doas' at path 'task1.become_method'",
+		},
+		{
+			become:         true,
+			goos:           "windows",
+			ExpectedErrMsg: "privilege escalation is not supported on Windows at path 'task1.become'",
+		},
+	}
+
+	for _, tc := range testCases {
+		err := ValidateBecome(tc.become, tc.becomeMethod, "task1", tc.goos)
+		if tc.ExpectedErrMsg == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.ExpectedErrMsg)
+		}
+	}
+}
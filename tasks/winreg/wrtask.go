@@ -34,6 +34,8 @@ const (
 var ErrUnknownWinRegAction = errors.New("unknown action")
 
 type Task struct {
+	tasks.ExecutionPolicy
+
 	ActionType ActionType
 	TypeName   string
 	Path       string
@@ -122,9 +124,26 @@ func (wrt *Task) GetCreatesFilesList() []string {
 	return wrt.Creates
 }
 
+func (wrt *Task) DescribeResult(res *executionresult.ExecutionResult) tasks.ResultDescription {
+	comment := res.Comment
+	if res.Err == nil && !wrt.Updated && (!res.DryRun || len(res.Changes) == 0) {
+		comment = "Windows registry not updated " + res.SkipReason
+	}
+
+	return tasks.ResultDescription{
+		Name:    wrt.RegPath + `\` + wrt.Name,
+		Comment: comment,
+	}
+}
+
 type Executor struct {
 	Runner    tacoexec.Runner
-	FsManager *utils.FsManager
+	FsManager tasks.FsManager
+}
+
+// SupportsDryRun tells that the executor compares the registry with the desired state without updating it in a dry run
+func (wrte *Executor) SupportsDryRun() bool {
+	return true
 }
 
 func (wrte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executionresult.ExecutionResult {
@@ -174,7 +193,11 @@ func (wrte *Executor) Execute(ctx context.Context, task tasks.CoreTask) executio
 
 	start := time.Now()
 
-	err = wrte.ExecuteTask(ctx, wrt, &execRes)
+	if tasks.IsDryRun(ctx) {
+		err = wrte.dryRun(wrt, &execRes)
+	} else {
+		err = wrte.ExecuteTask(ctx, wrt, &execRes)
+	}
 	if err != nil {
 		execRes.Err = err
 		return execRes
@@ -225,3 +248,48 @@ func (wrte *Executor) ExecuteTask(ctx context.Context, t *Task, res *executionre
 
 	return nil
 }
+
+// dryRun reports the registry change which the task would make
+func (wrte *Executor) dryRun(t *Task, res *executionresult.ExecutionResult) error {
+	res.DryRun = true
+
+	var desc string
+	switch t.ActionType {
+	case ActionWinRegPresent:
+		found, val, err := winregistry.GetValue(t.RegPath, t.Name, winregistry.REG_SZ)
+		switch {
+		case !found && err != nil:
+			return err
+		case !found:
+			desc = "would add new value"
+		case err != nil || val != t.Val:
+			// a value of a different type is replaced
+			desc = "would update existing value"
+		}
+	case ActionWinRegAbsent:
+		found, _, err := winregistry.GetValue(t.RegPath, t.Name, winregistry.REG_SZ)
+		if err != nil && !found {
+			return err
+		}
+		if found {
+			desc = "would remove value"
+		}
+	case ActionWinRegAbsentKey:
+		exists, err := winregistry.KeyExists(t.RegPath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			desc = "would remove key"
+		}
+	default:
+		return ErrUnknownWinRegAction
+	}
+
+	if desc != "" {
+		res.Comment = "registry would be updated"
+		res.Changes["registry"] = desc
+	}
+
+	return nil
+}
@@ -2,8 +2,10 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/kylelemons/godebug/diff"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 func Diff(expectedStr, actualStr string) string {
@@ -20,3 +22,24 @@ Diff:
 %s
 `, Truncate(expectedStr), Truncate(actualStr), contentDiff)
 }
+
+// UnifiedDiff gives the changes between the original and the updated contents in the unified diff format
+func UnifiedDiff(filename, origContents, updatedContents string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(origContents),
+		B:        splitLines(updatedContents),
+		FromFile: filename,
+		ToFile:   filename,
+		Context:  3,
+	})
+}
+
+// splitLines keeps the line endings, unlike difflib.SplitLines no line is added for empty contents
+func splitLines(contents string) []string {
+	lines := strings.SplitAfter(contents, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -40,6 +40,10 @@ func (fmm *FsManager) WriteFile(name, contents string, mode os.FileMode) error {
 	return os.WriteFile(name, []byte(contents), mode)
 }
 
+func (fmm *FsManager) WriteFileAtomic(name, contents string, mode os.FileMode) error {
+	return WriteFileAtomic(name, []byte(contents), mode)
+}
+
 func (fmm *FsManager) ReadFile(filePath string) (content string, err error) {
 	contentsByte, err := os.ReadFile(filePath)
 
@@ -232,3 +236,46 @@ func DownloadFile(ctx context.Context, targetLocation string, sourceURL *url.URL
 
 	return nil
 }
+
+// WriteFileAtomic writes data to a temp file in the target directory and renames it to the target name,
+// so readers never see a partially written file. The temp file gets the owner of an existing target file,
+// otherwise the rename would change the owner to the current user.
+func WriteFileAtomic(name string, data []byte, mode os.FileMode) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".taco-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpFile.Name()
+
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if _, err = tmpFile.Write(data); err != nil {
+		CloseResourceSecure(tmpName, tmpFile)
+		return err
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		CloseResourceSecure(tmpName, tmpFile)
+		return err
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmpName, mode); err != nil {
+		return err
+	}
+
+	if err = keepOwner(name, tmpName); err != nil {
+		return err
+	}
+
+	logrus.Debugf("will move temp file '%s' to '%s'", tmpName, name)
+
+	return os.Rename(tmpName, name)
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomicKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+
+	const ownerID = 65534
+
+	filePath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(filePath, []byte("old"), 0644))
+	require.NoError(t, os.Chown(filePath, ownerID, ownerID))
+
+	require.NoError(t, WriteFileAtomic(filePath, []byte("new"), 0644))
+
+	contents, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(contents))
+
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	assert.Equal(t, uint32(ownerID), stat.Uid)
+	assert.Equal(t, uint32(ownerID), stat.Gid)
+}
@@ -4,10 +4,12 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"os/user"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 func ParseLocationOS(rawLocation string) string {
@@ -21,30 +23,78 @@ func ParseLocationOS(rawLocation string) string {
 }
 
 func Chown(targetFilePath, userName, groupName string) error {
-	usrID, groupID := -1, -1
+	usrID, groupID, err := lookupOwnerIDs(userName, groupName)
+	if err != nil {
+		return err
+	}
+
+	return os.Chown(targetFilePath, usrID, groupID)
+}
+
+// IsOwnedBy tells if the file is owned by the user and group, an empty name matches any owner
+func IsOwnedBy(targetFilePath, userName, groupName string) (bool, error) {
+	usrID, groupID, err := lookupOwnerIDs(userName, groupName)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(targetFilePath)
+	if err != nil {
+		return false, err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, fmt.Errorf("cannot get the owner of '%s'", targetFilePath)
+	}
+
+	return (usrID < 0 || int(stat.Uid) == usrID) && (groupID < 0 || int(stat.Gid) == groupID), nil
+}
+
+// keepOwner changes the owner of the new file to the owner of the existing file, nothing is done if the file doesn't exist
+func keepOwner(existingFilePath, newFilePath string) error {
+	info, err := os.Stat(existingFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot get the owner of '%s'", existingFilePath)
+	}
+
+	return os.Chown(newFilePath, int(stat.Uid), int(stat.Gid))
+}
+
+// lookupOwnerIDs gives the ids of the user and group names, the id of an empty name is -1
+func lookupOwnerIDs(userName, groupName string) (usrID, groupID int, err error) {
+	usrID, groupID = -1, -1
 
 	if userName != "" {
 		sysUser, err := user.Lookup(userName)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 		usrID, err = strconv.Atoi(sysUser.Uid)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 	}
 
 	if groupName != "" {
 		sysGroup, err := user.LookupGroup(groupName)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 
 		groupID, err = strconv.Atoi(sysGroup.Gid)
 		if err != nil {
-			return err
+			return -1, -1, err
 		}
 	}
 
-	return os.Chown(targetFilePath, usrID, groupID)
+	return usrID, groupID, nil
 }
@@ -23,3 +23,12 @@ func ParseLocationOS(rawLocation string) string {
 func Chown(targetFilePath, userName, groupName string) error {
 	return fmt.Errorf("no chown support under windows")
 }
+
+// keepOwner does nothing on Windows, the owner of a file isn't a uid and gid
+func keepOwner(existingFilePath, newFilePath string) error {
+	return nil
+}
+
+func IsOwnedBy(targetFilePath, userName, groupName string) (bool, error) {
+	return false, fmt.Errorf("no chown support under windows")
+}
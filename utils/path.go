@@ -12,6 +12,8 @@ type Location struct {
 	URL         *url.URL
 	LocalPath   string
 	RawLocation string
+	// the contents of a local file which signature was verified, the executors use them instead of reading the file again
+	VerifiedContents []byte `json:"-"`
 }
 
 func ParseLocation(rawLocation string) Location {
@@ -51,3 +53,20 @@ func ParseLocation(rawLocation string) Location {
 		RawLocation: rawLocation,
 	}
 }
+
+// ResolveRelative gives the location of a relative local path in the directory of the base URL,
+// the other locations are returned unchanged
+func (l Location) ResolveRelative(base *url.URL) Location {
+	if base == nil || l.IsURL || l.LocalPath == "" || filepath.IsAbs(l.LocalPath) ||
+		strings.HasPrefix(l.LocalPath, string(os.PathSeparator)) {
+		return l
+	}
+
+	u := base.ResolveReference(&url.URL{Path: filepath.ToSlash(l.LocalPath)})
+
+	return Location{
+		IsURL:       true,
+		URL:         u,
+		RawLocation: u.String(),
+	}
+}